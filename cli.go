@@ -0,0 +1,435 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/signal"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/linzhengen/azure-document-intelligence-mcp/config"
+	"github.com/linzhengen/azure-document-intelligence-mcp/internal/domain/analysis"
+	"github.com/linzhengen/azure-document-intelligence-mcp/internal/infrastructure/fetch"
+	historyinfra "github.com/linzhengen/azure-document-intelligence-mcp/internal/infrastructure/history"
+	"github.com/linzhengen/azure-document-intelligence-mcp/internal/infrastructure/metrics"
+	"github.com/linzhengen/azure-document-intelligence-mcp/internal/redact"
+	"github.com/linzhengen/azure-document-intelligence-mcp/internal/usecase"
+)
+
+// Output formats of the commands, the first being the default.
+var commandFormats = map[string][]string{
+	"analyze":     {"json", "markdown"},
+	"classify":    {"json", "csv"},
+	"tables":      {"csv", "markdown", "json"},
+	"models list": {"text", "json"},
+}
+
+// Default models of the document commands.
+var commandModels = map[string]string{
+	"analyze":  "prebuilt-layout",
+	"classify": "prebuilt-invoice",
+	"tables":   "prebuilt-layout",
+}
+
+// documentFlags are the flags of the commands analyzing a document.
+type documentFlags struct {
+	model       string
+	format      string
+	output      string
+	contentType string
+	fetchMode   string
+	pages       string
+	region      string
+	simplify    bool
+	redactPII   bool
+	table       int
+}
+
+// newCommandFlags returns the flags of command, which include the global
+// flags so that they can also follow the command.
+func newCommandFlags(command string) *flag.FlagSet {
+	fs := flag.NewFlagSet(command, flag.ContinueOnError)
+	flag.VisitAll(func(f *flag.Flag) {
+		fs.Var(f.Value, f.Name, f.Usage)
+	})
+	return fs
+}
+
+// parseCommand parses the flags of a command, setting the global ones on the
+// global flag set, and returns its arguments.
+func parseCommand(fs *flag.FlagSet, args []string) ([]string, error) {
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	var err error
+	fs.Visit(func(f *flag.Flag) {
+		if global := flag.Lookup(f.Name); global != nil && err == nil {
+			err = flag.Set(f.Name, f.Value.String())
+		}
+	})
+	return fs.Args(), err
+}
+
+// checkFormat refuses formats the command doesn't write.
+func checkFormat(command, format string) error {
+	if !slices.Contains(commandFormats[command], format) {
+		return fmt.Errorf("unsupported format %q: must be one of %s", format, strings.Join(commandFormats[command], ", "))
+	}
+	return nil
+}
+
+// runModels lists the models analyses accept, and whether this server
+// enables them, and returns the exit code.
+func runModels(args []string, loadConfig func() (*config.Config, error)) int {
+	fs := newCommandFlags("models list")
+	format := fs.String("format", commandFormats["models list"][0], "output format: "+strings.Join(commandFormats["models list"], ", "))
+	output := fs.String("o", "", "file to write the output to, instead of stdout")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage:\n  %s [flags] models list [flags]\n\nFlags:\n", serverName)
+		fs.PrintDefaults()
+	}
+	rest, err := parseCommand(fs, args)
+	if err != nil {
+		return parseExitCode(err)
+	}
+	if len(rest) > 0 {
+		err = fmt.Errorf("unexpected arguments %q", strings.Join(rest, " "))
+	}
+	if err := errors.Join(err, checkFormat("models list", *format)); err != nil {
+		return usageError(fs, err)
+	}
+	cfg, _, logger := setUp(loadConfig)
+
+	type model struct {
+		ModelID string `json:"modelId"`
+		Enabled bool   `json:"enabled"`
+	}
+	var models []model
+	for _, m := range usecase.SupportedModels() {
+		models = append(models, model{ModelID: m, Enabled: len(cfg.AllowedModels) == 0 || slices.Contains(cfg.AllowedModels, m)})
+	}
+	var buf bytes.Buffer
+	if *format == "json" {
+		if err := writeJSON(&buf, models); err != nil {
+			logger.Error("Failed to write output", "error", err)
+			return 1
+		}
+	} else {
+		for _, m := range models {
+			state := "enabled"
+			if !m.Enabled {
+				state = "disabled"
+			}
+			fmt.Fprintf(&buf, "%s\t%s\n", m.ModelID, state)
+		}
+	}
+	return writeOutput(*output, buf.Bytes(), logger)
+}
+
+// runDocumentCommand runs the analyze, classify or tables command on the
+// document given by file path, URL, or - for the standard input, and
+// returns the exit code.
+func runDocumentCommand(ctx context.Context, command string, args []string, loadConfig func() (*config.Config, error)) int {
+	fs := newCommandFlags(command)
+	var f documentFlags
+	fs.StringVar(&f.model, "model", commandModels[command], "model analyzing the document: "+strings.Join(usecase.SupportedModels(), ", "))
+	fs.StringVar(&f.format, "format", commandFormats[command][0], "output format: "+strings.Join(commandFormats[command], ", "))
+	fs.StringVar(&f.output, "o", "", "file to write the output to, instead of stdout")
+	fs.StringVar(&f.contentType, "content-type", "", "media type of the document, detected if not set")
+	fs.StringVar(&f.fetchMode, "fetch-mode", "", "who downloads a document URL: azure (default) or server")
+	fs.StringVar(&f.pages, "pages", "", "pages to analyze, such as 1-3,5")
+	fs.StringVar(&f.region, "region", "", "only analyze the document on endpoints of this region")
+	fs.BoolVar(&f.redactPII, "redact-pii", false, "replace personal data in the result by placeholders")
+	if command == "analyze" {
+		fs.BoolVar(&f.simplify, "simplify", false, "flatten the fields of extracted documents")
+	}
+	if command == "tables" {
+		fs.IntVar(&f.table, "table", 0, "1-based number of the only table to write")
+	}
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage:\n  %s [flags] %s [flags] <file | URL | ->\n\nFlags:\n", serverName, command)
+		fs.PrintDefaults()
+	}
+	rest, err := parseCommand(fs, args)
+	if err != nil {
+		return parseExitCode(err)
+	}
+	if len(rest) != 1 {
+		err = errors.New("expected one document: a file path, a URL, or - for the standard input")
+	}
+	if f.table < 0 {
+		err = errors.Join(err, errors.New("table must not be negative"))
+	}
+	if err := errors.Join(err, checkFormat(command, f.format)); err != nil {
+		return usageError(fs, err)
+	}
+	cfg, redactor, logger := setUp(loadConfig)
+	ctx, cancel := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	analyzer, stop, err := newDocumentAnalyzer(ctx, cfg, redactor, logger)
+	if err != nil {
+		logger.Error("Invalid config", "error", err)
+		return 1
+	}
+	defer stop()
+
+	params := &usecase.AnalysisParams{
+		ModelID:     f.model,
+		ContentType: f.contentType,
+		FetchMode:   f.fetchMode,
+		Pages:       f.pages,
+		Region:      f.region,
+		Simplify:    f.simplify,
+		RedactPII:   f.redactPII,
+	}
+	content, err := readDocument(rest[0], params, cfg.FetchMaxBytes)
+	if err != nil {
+		logger.Error("Failed to read document", "error", err)
+		return 1
+	}
+
+	var buf bytes.Buffer
+	if command == "classify" {
+		var classifications []*usecase.Classification
+		classifications, err = analyzer.Classify(ctx, params, content)
+		if err == nil {
+			err = writeClassifications(&buf, classifications, f.format)
+		}
+	} else {
+		var output *usecase.AnalysisOutput
+		output, err = analyzer.Analyze(ctx, params, content)
+		if err == nil && command == "analyze" {
+			err = writeAnalysis(&buf, output, f.format)
+		} else if err == nil {
+			err = writeTables(&buf, output, f.table, f.format)
+		}
+	}
+	if err != nil {
+		logger.Error("Failed to analyze document", "document", rest[0], "error", err)
+		return 1
+	}
+	return writeOutput(f.output, buf.Bytes(), logger)
+}
+
+// parseExitCode returns the exit code of a command whose flags failed to
+// parse. The flag set has already reported the error.
+func parseExitCode(err error) int {
+	if errors.Is(err, flag.ErrHelp) {
+		return 0
+	}
+	return 2
+}
+
+// usageError reports invalid command arguments and returns the exit code.
+func usageError(fs *flag.FlagSet, err error) int {
+	fmt.Fprintln(fs.Output(), err)
+	fs.Usage()
+	return 2
+}
+
+// newDocumentAnalyzer returns the analyzer of the document commands, with
+// the budgets, URL policy, personal data redaction and history of the
+// server. The returned function stops the backend.
+func newDocumentAnalyzer(ctx context.Context, cfg *config.Config, redactor *redact.Redactor, logger *slog.Logger) (*usecase.DocumentAnalyzer, func(), error) {
+	stop := startBackend(ctx, cfg, logger)
+	analyzer, err := func() (*usecase.DocumentAnalyzer, error) {
+		analysisRepo, err := newAnalysisRepository(cfg, redactor, metrics.New())
+		if err != nil {
+			return nil, err
+		}
+		usageMeter, err := newUsageMeter(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load usage ledger: %w", err)
+		}
+		piiDetector, err := newPIIDetector(cfg.PIIPatterns)
+		if err != nil {
+			return nil, err
+		}
+		urlPolicy, fetchPolicy := newURLPolicy(cfg), newFetchPolicy(cfg)
+		options := []usecase.HandlerOption{
+			usecase.WithLogger(logger),
+			usecase.WithRedactor(redactor),
+			usecase.WithPIIRedaction(piiDetector, cfg.PIIRedactAlways),
+			usecase.WithUsageMeter(usageMeter),
+			usecase.WithURLValidator(urlPolicy),
+			usecase.WithFetchURLValidator(fetchPolicy),
+			usecase.WithDocumentFetcher(fetch.New(fetch.Options{
+				MaxBytes:     cfg.FetchMaxBytes,
+				Timeout:      time.Duration(cfg.FetchTimeout) * time.Second,
+				MaxRedirects: cfg.FetchMaxRedirects,
+				Headers:      cfg.FetchHostHeaders,
+				Policy:       fetchPolicy,
+			})),
+		}
+		if len(cfg.AllowedModels) > 0 {
+			options = append(options, usecase.WithAllowedModels(cfg.AllowedModels))
+		}
+		if cfg.HistoryEnabled {
+			store, err := historyinfra.NewFileStore(historyDir(cfg), historyRetention(cfg))
+			if err != nil {
+				return nil, fmt.Errorf("failed to load analysis history: %w", err)
+			}
+			options = append(options, usecase.WithResultStore(store))
+		}
+		return usecase.NewDocumentAnalyzer(analysisRepo, options...), nil
+	}()
+	if err != nil {
+		stop()
+		return nil, nil, err
+	}
+	return analyzer, stop, nil
+}
+
+// readDocument sets the URL of the document named by arg on params, or
+// returns the content of the file or of the standard input for -. An
+// explicit content type takes precedence over the detected one.
+func readDocument(arg string, params *usecase.AnalysisParams, maxBytes int64) ([]byte, error) {
+	if strings.HasPrefix(arg, "http://") || strings.HasPrefix(arg, "https://") {
+		params.DocumentURL = arg
+		return nil, nil
+	}
+	r, name := io.Reader(os.Stdin), ""
+	if arg != "-" {
+		file, err := os.Open(arg)
+		if err != nil {
+			return nil, err
+		}
+		defer func() { _ = file.Close() }()
+		r, name = file, arg
+	}
+	content, contentType, err := fetch.ReadDocument(r, name, maxBytes)
+	if err != nil {
+		return nil, err
+	}
+	if params.ContentType == "" {
+		params.ContentType = contentType
+	}
+	return content, nil
+}
+
+// writeAnalysis writes the output of an analysis as JSON, or its content as
+// markdown.
+func writeAnalysis(w io.Writer, output *usecase.AnalysisOutput, format string) error {
+	if format == "json" {
+		return writeJSON(w, output)
+	}
+	if output.AnalyzeResult != nil {
+		_, err := io.WriteString(w, output.AnalyzeResult.Content)
+		return err
+	}
+	return nil
+}
+
+// writeClassifications writes the documents found as JSON, or as CSV rows
+// of type, confidence and pages.
+func writeClassifications(w io.Writer, classifications []*usecase.Classification, format string) error {
+	if format == "json" {
+		return writeJSON(w, classifications)
+	}
+	records := [][]string{{"docType", "confidence", "pages"}}
+	for _, c := range classifications {
+		pages := make([]string, len(c.Pages))
+		for i, p := range c.Pages {
+			pages[i] = strconv.Itoa(p)
+		}
+		records = append(records, []string{c.DocType, strconv.FormatFloat(float64(c.Confidence), 'f', -1, 32), strings.Join(pages, ",")})
+	}
+	return csv.NewWriter(w).WriteAll(records)
+}
+
+// writeTables writes the tables of an analysis, or only table number n if
+// positive. As CSV and markdown, tables are separated by a blank line; as
+// JSON, they're an array of rows of cells.
+func writeTables(w io.Writer, output *usecase.AnalysisOutput, n int, format string) error {
+	var tables []*analysis.Table
+	if output.AnalyzeResult != nil {
+		tables = output.AnalyzeResult.Tables
+	}
+	if n > 0 {
+		if n > len(tables) {
+			return fmt.Errorf("no table %d: the document has %d tables", n, len(tables))
+		}
+		tables = tables[n-1 : n]
+	}
+	grids := make([][][]string, len(tables))
+	for i, t := range tables {
+		grids[i] = t.Grid()
+	}
+	if format == "json" {
+		return writeJSON(w, grids)
+	}
+	for i, grid := range grids {
+		if i > 0 {
+			if _, err := io.WriteString(w, "\n"); err != nil {
+				return err
+			}
+		}
+		var err error
+		if format == "csv" {
+			err = csv.NewWriter(w).WriteAll(grid)
+		} else {
+			err = writeMarkdownTable(w, grid)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to write table: %w", err)
+		}
+	}
+	return nil
+}
+
+// writeMarkdownTable writes grid as a markdown table whose header is its
+// first row.
+func writeMarkdownTable(w io.Writer, grid [][]string) error {
+	if len(grid) == 0 {
+		return nil
+	}
+	cell := strings.NewReplacer("|", `\|`, "\r\n", "<br>", "\n", "<br>")
+	var b strings.Builder
+	for i, row := range grid {
+		b.WriteString("|")
+		for _, c := range row {
+			b.WriteString(" " + cell.Replace(c) + " |")
+		}
+		b.WriteString("\n")
+		if i == 0 {
+			b.WriteString("|" + strings.Repeat(" --- |", len(row)) + "\n")
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// writeJSON writes v as indented JSON.
+func writeJSON(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// writeOutput writes data to the file at path, or to stdout if path is
+// empty, and returns the exit code.
+func writeOutput(path string, data []byte, logger *slog.Logger) int {
+	var err error
+	if path == "" {
+		_, err = os.Stdout.Write(data)
+	} else {
+		err = os.WriteFile(path, data, 0o644)
+	}
+	if err != nil {
+		logger.Error("Failed to write output", "error", err)
+		return 1
+	}
+	return 0
+}
@@ -1,21 +1,385 @@
 package config
 
-import "github.com/kelseyhightower/envconfig"
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
 
-// Config holds the application configuration.
+	"github.com/kelseyhightower/envconfig"
+)
+
+// Config holds the application configuration. It's read from an optional
+// YAML or TOML file, whose keys are given by the yaml tags, and from
+// environment variables, which take precedence.
 type Config struct {
-	AzureEndpoint     string `envconfig:"AZURE_DOCUMENT_INTELLIGENCE_ENDPOINT" required:"true"`
-	AzureAPIKey       string `envconfig:"AZURE_DOCUMENT_INTELLIGENCE_API_KEY" required:"true"`
-	Port              int    `envconfig:"PORT" default:"8081"`
-	HTTPClientTimeout int    `envconfig:"HTTP_CLIENT_TIMEOUT" default:"30"`
+	AzureEndpoint string `envconfig:"AZURE_DOCUMENT_INTELLIGENCE_ENDPOINT" yaml:"azure_endpoint"`
+	AzureAPIKey   string `envconfig:"AZURE_DOCUMENT_INTELLIGENCE_API_KEY" yaml:"azure_api_key"`
+	// AzureEndpoints configures several resources to route requests to, in
+	// place of AzureEndpoint and AzureAPIKey, as a JSON array in the environment.
+	AzureEndpoints Endpoints `envconfig:"AZURE_DOCUMENT_INTELLIGENCE_ENDPOINTS" yaml:"azure_endpoints"`
+	// RoutingStrategy picks the endpoint of each request: "round-robin" by
+	// weight, or "least-loaded". Endpoints fail over on 429 and 5xx responses.
+	RoutingStrategy string `envconfig:"ROUTING_STRATEGY" default:"round-robin" yaml:"routing_strategy"`
+	// CircuitFailureThreshold consecutive failures stop requests to an
+	// endpoint for CircuitCooldown seconds.
+	CircuitFailureThreshold int `envconfig:"CIRCUIT_FAILURE_THRESHOLD" default:"5" yaml:"circuit_failure_threshold"`
+	CircuitCooldown         int `envconfig:"CIRCUIT_COOLDOWN" default:"30" yaml:"circuit_cooldown"`
+	Port                    int `envconfig:"PORT" default:"8081" yaml:"port"`
+	HTTPClientTimeout       int `envconfig:"HTTP_CLIENT_TIMEOUT" default:"30" yaml:"http_client_timeout"`
+	// MaxConcurrentAnalyses bounds the analyses in progress on the server;
+	// further ones wait in a queue served in turn across sessions.
+	// MaxConcurrentPerEndpoint bounds those on each endpoint, unless the
+	// endpoint sets max_concurrent. Zero means no limit.
+	MaxConcurrentAnalyses    int `envconfig:"MAX_CONCURRENT_ANALYSES" default:"10" yaml:"max_concurrent_analyses"`
+	MaxConcurrentPerEndpoint int `envconfig:"MAX_CONCURRENT_PER_ENDPOINT" yaml:"max_concurrent_per_endpoint"`
+	// InitiateRateLimit and PollRateLimit are the analyze and polling
+	// requests per second sent to each endpoint. The defaults are the limits
+	// of the S0 tier. Zero means no limit.
+	InitiateRateLimit int `envconfig:"INITIATE_RATE_LIMIT" default:"15" yaml:"initiate_rate_limit"`
+	PollRateLimit     int `envconfig:"POLL_RATE_LIMIT" default:"50" yaml:"poll_rate_limit"`
+	// Transport is how clients connect: "stdio", or "http" to serve streamable
+	// HTTP on Port along with Prometheus metrics at /metrics.
+	Transport string `envconfig:"MCP_TRANSPORT" default:"stdio" yaml:"transport"`
+	// FakeBackend serves analyses from an in-process fake service instead of Azure.
+	FakeBackend bool `envconfig:"FAKE_BACKEND" yaml:"fake_backend"`
+	// FakeFixturesDir holds additional JSON results for the fake service, named <modelId>.json.
+	FakeFixturesDir string `envconfig:"FAKE_FIXTURES_DIR" yaml:"fake_fixtures_dir"`
+	// OTLPEndpoint is the base URL of an OTLP/HTTP collector, such as
+	// http://localhost:4318, to export traces to. Tracing is off when it's empty.
+	OTLPEndpoint string `envconfig:"OTEL_EXPORTER_OTLP_ENDPOINT" yaml:"otlp_endpoint"`
+	// UsageLedgerPath is the JSON Lines file recording the pages analyzed.
+	// Defaults to usage.jsonl in the user configuration directory.
+	UsageLedgerPath string `envconfig:"USAGE_LEDGER_PATH" yaml:"usage_ledger_path"`
+	// DailyPageBudget, MonthlyPageBudget and MaxPagesPerRequest limit the pages
+	// analyzed per UTC day, per UTC month and per request. Zero means no limit.
+	DailyPageBudget    int `envconfig:"DAILY_PAGE_BUDGET" yaml:"daily_page_budget"`
+	MonthlyPageBudget  int `envconfig:"MONTHLY_PAGE_BUDGET" yaml:"monthly_page_budget"`
+	MaxPagesPerRequest int `envconfig:"MAX_PAGES_PER_REQUEST" yaml:"max_pages_per_request"`
+	// URLAllowedSchemes, URLAllowedHosts and URLDeniedHosts restrict the
+	// documentUrl values accepted. Host entries like "*.example.com" match
+	// subdomains; an empty allowlist accepts any public host.
+	URLAllowedSchemes []string `envconfig:"URL_ALLOWED_SCHEMES" default:"https" yaml:"url_allowed_schemes"`
+	URLAllowedHosts   []string `envconfig:"URL_ALLOWED_HOSTS" yaml:"url_allowed_hosts"`
+	URLDeniedHosts    []string `envconfig:"URL_DENIED_HOSTS" yaml:"url_denied_hosts"`
+	// URLAllowPrivateNetworks accepts documentUrl values on private and
+	// loopback addresses, for local development only.
+	URLAllowPrivateNetworks bool `envconfig:"URL_ALLOW_PRIVATE_NETWORKS" yaml:"url_allow_private_networks"`
+	// FetchMaxBytes, FetchTimeout (in seconds) and FetchMaxRedirects limit the
+	// downloads of fetchMode "server".
+	FetchMaxBytes     int64 `envconfig:"FETCH_MAX_BYTES" default:"52428800" yaml:"fetch_max_bytes"`
+	FetchTimeout      int   `envconfig:"FETCH_TIMEOUT" default:"60" yaml:"fetch_timeout"`
+	FetchMaxRedirects int   `envconfig:"FETCH_MAX_REDIRECTS" default:"5" yaml:"fetch_max_redirects"`
+	// FetchHostHeaders are headers, such as Authorization or Cookie, sent with
+	// the downloads from a host, as JSON: {"intranet.example.com": {"Cookie": "session=..."}}.
+	FetchHostHeaders HostHeaders `envconfig:"FETCH_HOST_HEADERS" yaml:"fetch_host_headers"`
+	// FetchPrivateHosts are hosts, such as intranet or VPN hosts, that
+	// fetchMode "server" may download from even though they're local or on
+	// private networks. Entries like "*.corp.example.com" match subdomains.
+	// They don't apply to the URLs sent to the service.
+	FetchPrivateHosts []string `envconfig:"FETCH_PRIVATE_HOSTS" yaml:"fetch_private_hosts"`
+	// RedactPatterns are regular expressions, as a JSON array, matching
+	// secrets to remove from errors, logs and tool outputs in addition to the
+	// API key, fetch headers, bearer tokens and URL signatures. The first group
+	// of a pattern, if any, is kept.
+	RedactPatterns Patterns `envconfig:"REDACT_PATTERNS" yaml:"redact_patterns"`
+	// PIIRedactAlways redacts personal data from every analysis result, not
+	// only those of calls setting redactPii.
+	PIIRedactAlways bool `envconfig:"PII_REDACT_ALWAYS" yaml:"pii_redact_always"`
+	// PIIPatterns are additional personal data detectors, as a JSON object
+	// mapping categories to regular expressions: {"EMPLOYEE_ID": "EMP-[0-9]{6}"}.
+	PIIPatterns NamedPatterns `envconfig:"PII_PATTERNS" yaml:"pii_patterns"`
+	// AllowedModels restricts the model IDs the analysis tool accepts to a
+	// subset of the supported ones. Empty means all supported models.
+	AllowedModels []string `envconfig:"ALLOWED_MODELS" yaml:"allowed_models"`
+	// DisabledTools lists tools, such as get_usage, not to offer to clients.
+	DisabledTools []string `envconfig:"DISABLED_TOOLS" yaml:"disabled_tools"`
+	// HistoryDir is the directory storing every completed analysis for the
+	// history tools. Defaults to history in the user configuration directory.
+	// HistoryEnabled turns the store and its tools off when false.
+	HistoryDir     string `envconfig:"HISTORY_DIR" yaml:"history_dir"`
+	HistoryEnabled bool   `envconfig:"HISTORY_ENABLED" default:"true" yaml:"history_enabled"`
+	// HistoryMaxRecords bounds the number of stored analyses, and
+	// HistoryMaxAgeDays how long they're kept. Zero means no limit.
+	HistoryMaxRecords int `envconfig:"HISTORY_MAX_RECORDS" default:"1000" yaml:"history_max_records"`
+	HistoryMaxAgeDays int `envconfig:"HISTORY_MAX_AGE_DAYS" default:"90" yaml:"history_max_age_days"`
+	// LocalTextExtraction answers prebuilt-read analyses of uploaded PDFs
+	// from their text layer, without calling the service, when at least
+	// LocalTextMinCoverage of the pages have text.
+	LocalTextExtraction  bool    `envconfig:"LOCAL_TEXT_EXTRACTION" yaml:"local_text_extraction"`
+	LocalTextMinCoverage float64 `envconfig:"LOCAL_TEXT_MIN_COVERAGE" default:"0.9" yaml:"local_text_min_coverage"`
+
+	// SplitMaxPages and SplitMaxBytes are the limits of the service per
+	// request; larger documents are analyzed in parts, SplitConcurrency at
+	// a time for uploaded PDFs and one after the other for page ranges of
+	// URLs, and their results merged. Zero means no limit.
+	SplitMaxPages    int   `envconfig:"SPLIT_MAX_PAGES" default:"2000" yaml:"split_max_pages"`
+	SplitMaxBytes    int64 `envconfig:"SPLIT_MAX_BYTES" default:"524288000" yaml:"split_max_bytes"`
+	SplitConcurrency int   `envconfig:"SPLIT_CONCURRENCY" default:"4" yaml:"split_concurrency"`
+
+	// DocumentDirs are the directories of this server whose files
+	// analyze_documents may read by documentPath. Empty refuses paths.
+	DocumentDirs []string `envconfig:"DOCUMENT_DIRS" yaml:"document_dirs"`
+	// BatchConcurrency bounds the documents of an analyze_documents call
+	// analyzed at once, and BatchMaxDocuments the documents of a call.
+	BatchConcurrency  int `envconfig:"BATCH_CONCURRENCY" default:"4" yaml:"batch_concurrency"`
+	BatchMaxDocuments int `envconfig:"BATCH_MAX_DOCUMENTS" default:"50" yaml:"batch_max_documents"`
+
+	// WatchDirs are the directories whose new and changed files the watch
+	// command analyzes, with the model of the first rule of WatchModels
+	// matching their name, writing the results to WatchOutputDir.
+	// WatchPollInterval, in seconds, scans the directories instead of
+	// waiting for file system events, which network shares may not deliver.
+	WatchDirs         []string   `envconfig:"WATCH_DIRS" yaml:"watch_dirs"`
+	WatchModels       ModelRules `envconfig:"WATCH_MODELS" yaml:"watch_models"`
+	WatchOutputDir    string     `envconfig:"WATCH_OUTPUT_DIR" yaml:"watch_output_dir"`
+	WatchPollInterval int        `envconfig:"WATCH_POLL_INTERVAL" yaml:"watch_poll_interval"`
+
+	// ResultCacheSize bounds how many completed analyses are kept as resources.
+	ResultCacheSize int `envconfig:"RESULT_CACHE_SIZE" default:"100" yaml:"result_cache_size"`
+	// LogLevel is the minimum level of log records written to stderr: DEBUG, INFO, WARN or ERROR.
+	LogLevel slog.Level `envconfig:"LOG_LEVEL" default:"INFO" yaml:"log_level"`
+}
+
+// HostHeaders maps host names to the headers sent to them.
+type HostHeaders map[string]map[string]string
+
+// Decode implements envconfig.Decoder by parsing JSON.
+func (h *HostHeaders) Decode(value string) error {
+	if err := json.Unmarshal([]byte(value), h); err != nil {
+		return fmt.Errorf("invalid host headers: %w", err)
+	}
+	return nil
+}
+
+// Patterns is a list of regular expressions. It's decoded from JSON rather
+// than split on commas, which are common in expressions.
+type Patterns []string
+
+// Decode implements envconfig.Decoder by parsing JSON.
+func (p *Patterns) Decode(value string) error {
+	if err := json.Unmarshal([]byte(value), p); err != nil {
+		return fmt.Errorf("invalid patterns: %w", err)
+	}
+	return nil
+}
+
+// Endpoint is a Document Intelligence resource of AzureEndpoints.
+type Endpoint struct {
+	Endpoint string `json:"endpoint" yaml:"endpoint"`
+	APIKey   string `json:"apiKey" yaml:"api_key"`
+	// Weight is the relative share of requests sent to the endpoint. Zero means 1.
+	Weight int `json:"weight" yaml:"weight"`
+	// Region tags the endpoint for requests restricted to a region, such as westeurope.
+	Region string `json:"region" yaml:"region"`
+	// Models lists the model IDs deployed on the endpoint. Empty means all.
+	Models []string `json:"models" yaml:"models"`
+	// MaxConcurrent bounds the analyses in progress on the endpoint,
+	// overriding MaxConcurrentPerEndpoint.
+	MaxConcurrent int `json:"maxConcurrent" yaml:"max_concurrent"`
+}
+
+// Endpoints is a list of endpoints.
+type Endpoints []Endpoint
+
+// Decode implements envconfig.Decoder by parsing JSON.
+func (e *Endpoints) Decode(value string) error {
+	if err := json.Unmarshal([]byte(value), e); err != nil {
+		return fmt.Errorf("invalid endpoints: %w", err)
+	}
+	return nil
 }
 
-// Load reads configuration from environment variables.
-func Load() (*Config, error) {
+// NamedPatterns maps names to regular expressions.
+type NamedPatterns map[string]string
+
+// Decode implements envconfig.Decoder by parsing JSON.
+func (p *NamedPatterns) Decode(value string) error {
+	if err := json.Unmarshal([]byte(value), p); err != nil {
+		return fmt.Errorf("invalid patterns: %w", err)
+	}
+	return nil
+}
+
+// ModelRule selects the model analyzing the watched files matching a glob
+// pattern, such as invoice-*.pdf.
+type ModelRule struct {
+	Pattern string `json:"pattern" yaml:"pattern"`
+	Model   string `json:"model" yaml:"model"`
+}
+
+// ModelRules is a list of model rules, tried in order.
+type ModelRules []ModelRule
+
+// Decode implements envconfig.Decoder by parsing JSON.
+func (r *ModelRules) Decode(value string) error {
+	if err := json.Unmarshal([]byte(value), r); err != nil {
+		return fmt.Errorf("invalid model rules: %w", err)
+	}
+	return nil
+}
+
+// Secrets returns the configured values that must never be revealed: the
+// API keys and the values of the fetch headers.
+func (c *Config) Secrets() []string {
+	secrets := []string{c.AzureAPIKey}
+	for _, e := range c.AzureEndpoints {
+		secrets = append(secrets, e.APIKey)
+	}
+	for _, headers := range c.FetchHostHeaders {
+		for _, v := range headers {
+			secrets = append(secrets, v)
+		}
+	}
+	return secrets
+}
+
+// Environment variables selecting the configuration file and profile when
+// they aren't passed to Load.
+const (
+	FileEnv    = "CONFIG_FILE"
+	ProfileEnv = "CONFIG_PROFILE"
+)
+
+// Load reads the configuration file at path, with the settings of profile
+// applied over its top-level ones, and then environment variables. Path and
+// profile default to CONFIG_FILE and CONFIG_PROFILE; without a file, only
+// environment variables are read.
+func Load(path, profile string) (*Config, error) {
 	var cfg Config
 	err := envconfig.Process("", &cfg)
 	if err != nil {
 		return nil, err
 	}
+	if path == "" {
+		path = os.Getenv(FileEnv)
+	}
+	if profile == "" {
+		profile = os.Getenv(ProfileEnv)
+	}
+	if path == "" {
+		if profile != "" {
+			return nil, fmt.Errorf("profile %q requires a configuration file", profile)
+		}
+		return &cfg, nil
+	}
+	if err := cfg.applyFile(path, profile); err != nil {
+		return nil, err
+	}
 	return &cfg, nil
 }
+
+// Transports.
+const (
+	TransportStdio = "stdio"
+	TransportHTTP  = "http"
+)
+
+// Validate checks that the settings are usable, reporting all problems at
+// once. The Azure endpoints and keys are only required without the fake backend.
+func (c *Config) Validate() error {
+	var errs []error
+	if c.Transport != TransportStdio && c.Transport != TransportHTTP {
+		errs = append(errs, fmt.Errorf("invalid transport %q: must be %q or %q", c.Transport, TransportStdio, TransportHTTP))
+	}
+	if c.Port < 1 || c.Port > 65535 {
+		errs = append(errs, fmt.Errorf("invalid port %d: must be between 1 and 65535", c.Port))
+	}
+	if c.HTTPClientTimeout <= 0 || c.FetchTimeout <= 0 {
+		errs = append(errs, errors.New("http_client_timeout and fetch_timeout must be positive"))
+	}
+	if c.FetchMaxBytes <= 0 || c.FetchMaxRedirects < 0 {
+		errs = append(errs, errors.New("fetch_max_bytes must be positive and fetch_max_redirects must not be negative"))
+	}
+	if c.DailyPageBudget < 0 || c.MonthlyPageBudget < 0 || c.MaxPagesPerRequest < 0 {
+		errs = append(errs, errors.New("page budgets must not be negative"))
+	}
+	if c.HistoryMaxRecords < 0 || c.HistoryMaxAgeDays < 0 {
+		errs = append(errs, errors.New("history_max_records and history_max_age_days must not be negative"))
+	}
+	if c.ResultCacheSize <= 0 {
+		errs = append(errs, fmt.Errorf("invalid result_cache_size %d: must be positive", c.ResultCacheSize))
+	}
+	for _, host := range c.FetchPrivateHosts {
+		if strings.TrimSpace(host) == "" || strings.ContainsAny(host, "/@") {
+			errs = append(errs, fmt.Errorf("invalid host %q in fetch_private_hosts: must be a host name or address", host))
+		}
+	}
+	for _, scheme := range c.URLAllowedSchemes {
+		if scheme != "http" && scheme != "https" {
+			errs = append(errs, fmt.Errorf("invalid URL scheme %q in url_allowed_schemes: must be http or https", scheme))
+		}
+	}
+	if c.RoutingStrategy != "round-robin" && c.RoutingStrategy != "least-loaded" {
+		errs = append(errs, fmt.Errorf("invalid routing_strategy %q: must be round-robin or least-loaded", c.RoutingStrategy))
+	}
+	if c.CircuitFailureThreshold <= 0 || c.CircuitCooldown <= 0 {
+		errs = append(errs, errors.New("circuit_failure_threshold and circuit_cooldown must be positive"))
+	}
+	if c.MaxConcurrentAnalyses < 0 || c.MaxConcurrentPerEndpoint < 0 {
+		errs = append(errs, errors.New("max_concurrent_analyses and max_concurrent_per_endpoint must not be negative"))
+	}
+	if c.InitiateRateLimit < 0 || c.PollRateLimit < 0 {
+		errs = append(errs, errors.New("initiate_rate_limit and poll_rate_limit must not be negative"))
+	}
+	if c.LocalTextExtraction && (c.LocalTextMinCoverage <= 0 || c.LocalTextMinCoverage > 1) {
+		errs = append(errs, fmt.Errorf("invalid local_text_min_coverage %g: must be greater than 0 and at most 1", c.LocalTextMinCoverage))
+	}
+	if c.SplitMaxPages < 0 || c.SplitMaxBytes < 0 || c.SplitConcurrency < 0 {
+		errs = append(errs, errors.New("split_max_pages, split_max_bytes and split_concurrency must not be negative"))
+	}
+	if c.BatchConcurrency <= 0 || c.BatchMaxDocuments <= 0 {
+		errs = append(errs, errors.New("batch_concurrency and batch_max_documents must be positive"))
+	}
+	for i, r := range c.WatchModels {
+		if _, err := filepath.Match(r.Pattern, ""); err != nil || r.Pattern == "" {
+			errs = append(errs, fmt.Errorf("invalid pattern %q in watch_models[%d]", r.Pattern, i))
+		}
+	}
+	if c.WatchPollInterval < 0 {
+		errs = append(errs, errors.New("watch_poll_interval must not be negative"))
+	}
+	if c.FakeBackend {
+		return errors.Join(errs...)
+	}
+	if len(c.AzureEndpoints) > 0 {
+		if c.AzureEndpoint != "" {
+			errs = append(errs, errors.New("set either azure_endpoint or azure_endpoints, not both"))
+		}
+		for i, e := range c.AzureEndpoints {
+			if err := validateEndpoint(e.Endpoint); err != nil {
+				errs = append(errs, fmt.Errorf("azure_endpoints[%d]: %w", i, err))
+			}
+			if e.APIKey == "" {
+				errs = append(errs, fmt.Errorf("azure_endpoints[%d]: missing api_key", i))
+			}
+			if e.Weight < 0 {
+				errs = append(errs, fmt.Errorf("azure_endpoints[%d]: weight must not be negative", i))
+			}
+			if e.MaxConcurrent < 0 {
+				errs = append(errs, fmt.Errorf("azure_endpoints[%d]: max_concurrent must not be negative", i))
+			}
+		}
+		return errors.Join(errs...)
+	}
+	if c.AzureEndpoint == "" {
+		errs = append(errs, errors.New("missing Azure endpoint: set AZURE_DOCUMENT_INTELLIGENCE_ENDPOINT or azure_endpoint, or azure_endpoints"))
+	} else if err := validateEndpoint(c.AzureEndpoint); err != nil {
+		errs = append(errs, err)
+	}
+	if c.AzureAPIKey == "" {
+		errs = append(errs, errors.New("missing Azure API key: set AZURE_DOCUMENT_INTELLIGENCE_API_KEY or azure_api_key"))
+	}
+	return errors.Join(errs...)
+}
+
+func validateEndpoint(endpoint string) error {
+	if u, err := url.Parse(endpoint); err != nil || u.Scheme == "" || u.Host == "" {
+		return fmt.Errorf("invalid Azure endpoint %q: must be a URL such as https://<resource>.cognitiveservices.azure.com", endpoint)
+	}
+	return nil
+}
@@ -0,0 +1,190 @@
+package config
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestLoad_YAMLWithProfile(t *testing.T) {
+	path := writeFile(t, "config.yaml", `
+azure_endpoint: https://docs.cognitiveservices.azure.com
+azure_api_key: file-key
+daily_page_budget: 100
+allowed_models: [prebuilt-read]
+fetch_host_headers:
+  intranet.example.com:
+    Cookie: session=1
+log_level: WARN
+profiles:
+  prod:
+    daily_page_budget: 1000
+    transport: http
+`)
+	t.Setenv("AZURE_DOCUMENT_INTELLIGENCE_API_KEY", "env-key")
+
+	cfg, err := Load(path, "prod")
+	require.NoError(t, err)
+
+	assert.Equal(t, "https://docs.cognitiveservices.azure.com", cfg.AzureEndpoint)
+	assert.Equal(t, "env-key", cfg.AzureAPIKey, "environment variables take precedence")
+	assert.Equal(t, 1000, cfg.DailyPageBudget, "the profile overrides the top-level settings")
+	assert.Equal(t, TransportHTTP, cfg.Transport)
+	assert.Equal(t, []string{"prebuilt-read"}, cfg.AllowedModels)
+	assert.Equal(t, HostHeaders{"intranet.example.com": {"Cookie": "session=1"}}, cfg.FetchHostHeaders)
+	assert.Equal(t, slog.LevelWarn, cfg.LogLevel)
+	assert.Equal(t, 8081, cfg.Port, "defaults apply to keys missing from the file")
+	assert.NoError(t, cfg.Validate())
+}
+
+func TestLoad_TOML(t *testing.T) {
+	path := writeFile(t, "config.toml", `
+azure_endpoint = "https://docs.cognitiveservices.azure.com"
+fetch_max_bytes = 1024
+url_allowed_hosts = ["*.example.com"]
+
+[profiles.dev]
+fake_backend = true
+`)
+	t.Setenv(ProfileEnv, "dev")
+
+	cfg, err := Load(path, "")
+	require.NoError(t, err)
+
+	assert.Equal(t, int64(1024), cfg.FetchMaxBytes)
+	assert.Equal(t, []string{"*.example.com"}, cfg.URLAllowedHosts)
+	assert.True(t, cfg.FakeBackend)
+}
+
+func TestLoad_FileFromEnvironment(t *testing.T) {
+	t.Setenv(FileEnv, writeFile(t, "config.yml", "port: 9000\n"))
+
+	cfg, err := Load("", "")
+	require.NoError(t, err)
+
+	assert.Equal(t, 9000, cfg.Port)
+}
+
+func TestLoad_Errors(t *testing.T) {
+	tests := map[string]struct {
+		name, content, profile string
+		want                   []string
+	}{
+		"unknown keys": {
+			name:    "config.yaml",
+			content: "azure_endpont: https://x\nPORT: 80\nprofiles:\n  dev:\n    bogus: 1\n",
+			want: []string{
+				`unknown key "azure_endpont" (did you mean "azure_endpoint"?)`,
+				`unknown key "PORT" (did you mean "port"?)`,
+				`profile "dev": unknown key "bogus"`,
+			},
+		},
+		"wrong types": {
+			name:    "config.yaml",
+			content: "port: eighty\nfake_backend: maybe\n",
+			want:    []string{"invalid fake_backend: expected true or false, got maybe", "invalid port: expected an integer, got eighty"},
+		},
+		"missing profile": {
+			name:    "config.yaml",
+			content: "profiles:\n  dev: {}\n  prod: {}\n",
+			profile: "staging",
+			want:    []string{`profile "staging" not found; available profiles: dev, prod`},
+		},
+		"syntax error": {
+			name:    "config.toml",
+			content: "port = \n",
+			want:    []string{"config.toml"},
+		},
+		"unsupported format": {
+			name:    "config.json",
+			content: "{}",
+			want:    []string{`unsupported configuration file format ".json"`},
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := Load(writeFile(t, tt.name, tt.content), tt.profile)
+			require.Error(t, err)
+			for _, want := range tt.want {
+				assert.Contains(t, err.Error(), want)
+			}
+		})
+	}
+}
+
+func TestConfig_Validate(t *testing.T) {
+	cfg := &Config{Transport: "grpc", Port: 0, HTTPClientTimeout: 30, FetchTimeout: 60, FetchMaxBytes: 1, ResultCacheSize: 100, URLAllowedSchemes: []string{"ftp"}, AzureEndpoint: "not a url", LocalTextExtraction: true, LocalTextMinCoverage: 1.5, SplitMaxPages: -1, HistoryMaxAgeDays: -1, BatchConcurrency: 4, WatchModels: ModelRules{{Pattern: "[a-", Model: "prebuilt-read"}}, FetchPrivateHosts: []string{"https://intranet.example.com"}}
+
+	err := cfg.Validate()
+
+	require.Error(t, err)
+	for _, want := range []string{`invalid transport "grpc"`, "invalid port 0", `invalid URL scheme "ftp"`, `invalid Azure endpoint "not a url"`, "missing Azure API key", "invalid local_text_min_coverage 1.5", "split_max_pages, split_max_bytes and split_concurrency must not be negative", "batch_concurrency and batch_max_documents must be positive", "history_max_records and history_max_age_days must not be negative", `invalid pattern "[a-" in watch_models[0]`, `invalid host "https://intranet.example.com" in fetch_private_hosts`} {
+		assert.Contains(t, err.Error(), want)
+	}
+}
+
+func TestLoad_Endpoints(t *testing.T) {
+	path := writeFile(t, "config.yaml", `
+routing_strategy: least-loaded
+azure_endpoints:
+  - endpoint: https://eu.cognitiveservices.azure.com
+    api_key: eu-key
+    weight: 2
+    region: westeurope
+    models: [prebuilt-read]
+  - endpoint: https://us.cognitiveservices.azure.com
+    api_key: us-key
+`)
+
+	cfg, err := Load(path, "")
+	require.NoError(t, err)
+
+	assert.Equal(t, Endpoints{
+		{Endpoint: "https://eu.cognitiveservices.azure.com", APIKey: "eu-key", Weight: 2, Region: "westeurope", Models: []string{"prebuilt-read"}},
+		{Endpoint: "https://us.cognitiveservices.azure.com", APIKey: "us-key"},
+	}, cfg.AzureEndpoints)
+	assert.Equal(t, "least-loaded", cfg.RoutingStrategy)
+	assert.Subset(t, cfg.Secrets(), []string{"eu-key", "us-key"})
+	assert.NoError(t, cfg.Validate())
+
+	t.Setenv("AZURE_DOCUMENT_INTELLIGENCE_ENDPOINTS", `[{"endpoint": "not a url"}]`)
+	cfg, err = Load(path, "")
+	require.NoError(t, err)
+	err = cfg.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `azure_endpoints[0]: invalid Azure endpoint "not a url"`)
+	assert.Contains(t, err.Error(), "azure_endpoints[0]: missing api_key")
+}
+
+func TestLoad_WatchModels(t *testing.T) {
+	path := writeFile(t, "config.yaml", `
+watch_dirs: [/srv/scans]
+watch_output_dir: /srv/results
+watch_models:
+  - pattern: invoice-*.pdf
+    model: prebuilt-invoice
+  - pattern: "*.pdf"
+    model: prebuilt-layout
+`)
+
+	cfg, err := Load(path, "")
+	require.NoError(t, err)
+	assert.Equal(t, ModelRules{{Pattern: "invoice-*.pdf", Model: "prebuilt-invoice"}, {Pattern: "*.pdf", Model: "prebuilt-layout"}}, cfg.WatchModels)
+	assert.Equal(t, []string{"/srv/scans"}, cfg.WatchDirs)
+
+	t.Setenv("WATCH_MODELS", `[{"pattern": "*.png", "model": "prebuilt-read"}]`)
+	cfg, err = Load(path, "")
+	require.NoError(t, err)
+	assert.Equal(t, ModelRules{{Pattern: "*.png", Model: "prebuilt-read"}}, cfg.WatchModels)
+}
@@ -0,0 +1,216 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// profilesKey holds the named profiles of a configuration file.
+const profilesKey = "profiles"
+
+// fileField is a Config field settable from a configuration file.
+type fileField struct {
+	value reflect.Value
+	env   string
+}
+
+// applyFile sets the fields given in the file at path, and then those of its
+// profile, unless their environment variable is set. All problems of the
+// file are reported at once.
+func (c *Config) applyFile(path, profile string) error {
+	settings, err := readFile(path)
+	if err != nil {
+		return err
+	}
+	profiles, err := splitProfiles(path, settings)
+	if err != nil {
+		return err
+	}
+
+	fields := c.fileFields()
+	errs := unknownKeys(fields, settings, path)
+	for _, name := range slices.Sorted(maps.Keys(profiles)) {
+		errs = append(errs, unknownKeys(fields, profiles[name], fmt.Sprintf("%s, profile %q", path, name))...)
+	}
+	if profile != "" {
+		overrides, ok := profiles[profile]
+		if !ok {
+			return fmt.Errorf("%s: profile %q not found; available profiles: %s", path, profile, listOrNone(slices.Sorted(maps.Keys(profiles))))
+		}
+		maps.Copy(settings, overrides)
+	}
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+
+	for _, key := range slices.Sorted(maps.Keys(settings)) {
+		f := fields[key]
+		if _, set := os.LookupEnv(f.env); set {
+			continue
+		}
+		if err := decodeValue(settings[key], f.value); err != nil {
+			errs = append(errs, fmt.Errorf("%s: invalid %s: %w", path, key, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func readFile(path string) (map[string]any, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read configuration file: %w", err)
+	}
+	settings := map[string]any{}
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &settings)
+	case ".toml":
+		err = toml.Unmarshal(data, &settings)
+	default:
+		return nil, fmt.Errorf("%s: unsupported configuration file format %q: use .yaml, .yml or .toml", path, ext)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return settings, nil
+}
+
+// splitProfiles removes the profiles from settings and returns them by name.
+func splitProfiles(path string, settings map[string]any) (map[string]map[string]any, error) {
+	raw, ok := settings[profilesKey]
+	if !ok {
+		return nil, nil
+	}
+	delete(settings, profilesKey)
+	table, ok := raw.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("%s: %s must map profile names to settings", path, profilesKey)
+	}
+	profiles := make(map[string]map[string]any, len(table))
+	for name, p := range table {
+		overrides, ok := p.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("%s: profile %q must map keys to values", path, name)
+		}
+		profiles[name] = overrides
+	}
+	return profiles, nil
+}
+
+// fileFields returns the fields of c by configuration file key.
+func (c *Config) fileFields() map[string]fileField {
+	v := reflect.ValueOf(c).Elem()
+	fields := make(map[string]fileField, v.NumField())
+	for i := range v.NumField() {
+		sf := v.Type().Field(i)
+		if key := sf.Tag.Get("yaml"); key != "" {
+			fields[key] = fileField{value: v.Field(i), env: sf.Tag.Get("envconfig")}
+		}
+	}
+	return fields
+}
+
+func unknownKeys(fields map[string]fileField, settings map[string]any, where string) []error {
+	var errs []error
+	for _, key := range slices.Sorted(maps.Keys(settings)) {
+		if _, ok := fields[key]; ok {
+			continue
+		}
+		err := fmt.Errorf("%s: unknown key %q", where, key)
+		if suggestion := suggestKey(fields, key); suggestion != "" {
+			err = fmt.Errorf("%w (did you mean %q?)", err, suggestion)
+		}
+		errs = append(errs, err)
+	}
+	return errs
+}
+
+// suggestKey returns the known key meant by an unknown one: the key of an
+// environment variable used as key, or a key at most two edits away.
+func suggestKey(fields map[string]fileField, unknown string) string {
+	best, bestDistance := "", 3
+	for _, key := range slices.Sorted(maps.Keys(fields)) {
+		if strings.EqualFold(fields[key].env, unknown) {
+			return key
+		}
+		if d := editDistance(strings.ToLower(unknown), key); d < bestDistance {
+			best, bestDistance = key, d
+		}
+	}
+	return best
+}
+
+// editDistance returns the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
+
+// decodeValue sets field to value, as decoded from YAML or TOML. Values are
+// converted through YAML, whose decoder also handles TOML's types and text
+// unmarshalers such as slog.Level.
+func decodeValue(value any, field reflect.Value) error {
+	data, err := yaml.Marshal(value)
+	if err != nil {
+		return err
+	}
+	decoded := reflect.New(field.Type())
+	if err := yaml.Unmarshal(data, decoded.Interface()); err != nil {
+		var typeErr *yaml.TypeError
+		if errors.As(err, &typeErr) {
+			return fmt.Errorf("expected %s, got %s", describeType(field.Type()), strings.TrimSpace(string(data)))
+		}
+		return err
+	}
+	field.Set(decoded.Elem())
+	return nil
+}
+
+func describeType(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Bool:
+		return "true or false"
+	case reflect.Int, reflect.Int64:
+		if t.PkgPath() == "log/slog" {
+			return "a log level"
+		}
+		return "an integer"
+	case reflect.Slice:
+		return "a list"
+	case reflect.Map:
+		return "a table"
+	default:
+		return "a string"
+	}
+}
+
+func listOrNone(names []string) string {
+	if len(names) == 0 {
+		return "none"
+	}
+	return strings.Join(names, ", ")
+}
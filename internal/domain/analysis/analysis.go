@@ -1,283 +1,290 @@
 package analysis
 
-import "time"
-
 // AnalyzeDocumentRequest represents the request body for analyzing a document.
 type AnalyzeDocumentRequest struct {
-	URLSource      *string `json:"urlSource,omitempty"`
-	Base64Source   []byte  `json:"base64Source,omitempty"`
+	URLSource    *string `json:"urlSource,omitempty" jsonschema:"URL of the document to analyze."`
+	Base64Source []byte  `json:"base64Source,omitempty" jsonschema:"Base64 encoded bytes of the document to analyze."`
 }
 
 // AnalyzeOperationResult represents the status and result of the analyze operation.
 type AnalyzeOperationResult struct {
-	Status              string         `json:"status"`
-	CreatedDateTime     time.Time      `json:"createdDateTime"`
-	LastUpdatedDateTime time.Time      `json:"lastUpdatedDateTime"`
-	Error               *Error         `json:"error,omitempty"`
-	AnalyzeResult       *AnalyzeResult `json:"analyzeResult,omitempty"`
+	Status              string         `json:"status" jsonschema:"Operation status: notStarted, running, succeeded, failed, canceled or skipped."`
+	CreatedDateTime     string         `json:"createdDateTime" jsonschema:"Date and time (UTC) when the analyze operation was submitted, in RFC 3339 format."`
+	LastUpdatedDateTime string         `json:"lastUpdatedDateTime" jsonschema:"Date and time (UTC) when the status was last updated, in RFC 3339 format."`
+	Error               *Error         `json:"error,omitempty" jsonschema:"Encountered error during document analysis."`
+	AnalyzeResult       *AnalyzeResult `json:"analyzeResult,omitempty" jsonschema:"Document analysis result."`
+	Source              string         `json:"source,omitempty" jsonschema:"Set to local when the result was read from the text layer of a PDF on this server, without calling the service, or to shared when it's the result of an identical analysis requested concurrently."`
 }
 
+const (
+	// SourceLocal marks the results produced on this server rather than by the service.
+	SourceLocal = "local"
+	// SourceShared marks the results of an analysis already requested by an
+	// identical call, and so already billed.
+	SourceShared = "shared"
+)
+
 // AnalyzeResult represents the document analysis result.
 type AnalyzeResult struct {
-	ApiVersion      string          `json:"apiVersion"`
-	ModelID         string          `json:"modelId"`
-	StringIndexType string          `json:"stringIndexType"`
-	ContentFormat   *string         `json:"contentFormat,omitempty"`
-	Content         string          `json:"content"`
-	Pages           []Page          `json:"pages"`
-	Paragraphs      []*Paragraph    `json:"paragraphs,omitempty"`
-	Tables          []*Table        `json:"tables,omitempty"`
-	Figures         []*Figure       `json:"figures,omitempty"`
-	Sections        []*Section      `json:"sections,omitempty"`
-	KeyValuePairs   []*KeyValuePair `json:"keyValuePairs,omitempty"`
-	Styles          []*Style        `json:"styles,omitempty"`
-	Languages       []*Language     `json:"languages,omitempty"`
-	Documents       []*Document     `json:"documents,omitempty"`
-	Warnings        []*Warning      `json:"warnings,omitempty"`
+	ApiVersion      string          `json:"apiVersion" jsonschema:"API version used to produce this result."`
+	ModelID         string          `json:"modelId" jsonschema:"Document model ID used to produce this result."`
+	StringIndexType string          `json:"stringIndexType" jsonschema:"Method used to compute string offset and length: textElements, unicodeCodePoint or utf16CodeUnit."`
+	ContentFormat   *string         `json:"contentFormat,omitempty" jsonschema:"Format of the analyze result top-level content: text or markdown."`
+	Content         string          `json:"content" jsonschema:"Concatenated string representation of all textual and visual elements in reading order."`
+	Pages           []Page          `json:"pages" jsonschema:"Analyzed pages."`
+	Paragraphs      []*Paragraph    `json:"paragraphs,omitempty" jsonschema:"Extracted paragraphs."`
+	Tables          []*Table        `json:"tables,omitempty" jsonschema:"Extracted tables."`
+	Figures         []*Figure       `json:"figures,omitempty" jsonschema:"Extracted figures."`
+	Sections        []*Section      `json:"sections,omitempty" jsonschema:"Extracted sections."`
+	KeyValuePairs   []*KeyValuePair `json:"keyValuePairs,omitempty" jsonschema:"Extracted key-value pairs."`
+	Styles          []*Style        `json:"styles,omitempty" jsonschema:"Extracted font styles."`
+	Languages       []*Language     `json:"languages,omitempty" jsonschema:"Detected languages."`
+	Documents       []*Document     `json:"documents,omitempty" jsonschema:"Extracted documents with typed fields."`
+	Warnings        []*Warning      `json:"warnings,omitempty" jsonschema:"List of warnings encountered."`
 }
 
 // Page represents content and layout elements extracted from a page from the input.
 type Page struct {
-	PageNumber     int32            `json:"pageNumber"`
-	Angle          *float32         `json:"angle,omitempty"`
-	Width          *float32         `json:"width,omitempty"`
-	Height         *float32         `json:"height,omitempty"`
-	Unit           *string          `json:"unit,omitempty"`
-	Spans          []Span           `json:"spans"`
-	Words          []*Word          `json:"words,omitempty"`
-	SelectionMarks []*SelectionMark `json:"selectionMarks,omitempty"`
-	Lines          []*Line          `json:"lines,omitempty"`
-	Barcodes       []*Barcode       `json:"barcodes,omitempty"`
-	Formulas       []*Formula       `json:"formulas,omitempty"`
+	PageNumber     int32            `json:"pageNumber" jsonschema:"1-based page number in the input document."`
+	Angle          *float32         `json:"angle,omitempty" jsonschema:"The general orientation of the content in clockwise direction, measured in degrees between (-180, 180]."`
+	Width          *float32         `json:"width,omitempty" jsonschema:"The width of the image/PDF in pixels/inches, respectively."`
+	Height         *float32         `json:"height,omitempty" jsonschema:"The height of the image/PDF in pixels/inches, respectively."`
+	Unit           *string          `json:"unit,omitempty" jsonschema:"The unit used by the width, height, and polygon properties: pixel for images and inch for PDF."`
+	Spans          []Span           `json:"spans" jsonschema:"Location of the page in the reading order concatenated content."`
+	Words          []*Word          `json:"words,omitempty" jsonschema:"Extracted words from the page."`
+	SelectionMarks []*SelectionMark `json:"selectionMarks,omitempty" jsonschema:"Extracted selection marks from the page."`
+	Lines          []*Line          `json:"lines,omitempty" jsonschema:"Extracted lines from the page, potentially containing both textual and visual elements."`
+	Barcodes       []*Barcode       `json:"barcodes,omitempty" jsonschema:"Extracted barcodes from the page."`
+	Formulas       []*Formula       `json:"formulas,omitempty" jsonschema:"Extracted formulas from the page."`
 }
 
 // Span represents a contiguous region of the concatenated content property.
 type Span struct {
-	Offset int32 `json:"offset"`
-	Length int32 `json:"length"`
+	Offset int32 `json:"offset" jsonschema:"Zero-based index of the content represented by the span."`
+	Length int32 `json:"length" jsonschema:"Number of characters in the content represented by the span."`
 }
 
 // Word represents a word object.
 type Word struct {
-	Content    string     `json:"content"`
-	Polygon    []float32  `json:"polygon,omitempty"`
-	Span       Span       `json:"span"`
-	Confidence float32    `json:"confidence"`
+	Content    string    `json:"content" jsonschema:"Text content of the word."`
+	Polygon    []float32 `json:"polygon,omitempty" jsonschema:"Bounding polygon of the word, as a flat list of x, y coordinates."`
+	Span       Span      `json:"span" jsonschema:"Location of the word in the reading order concatenated content."`
+	Confidence float32   `json:"confidence" jsonschema:"Confidence of correctly extracting the word, between 0 and 1."`
 }
 
 // SelectionMark represents a selection mark object.
 type SelectionMark struct {
-	State      string    `json:"state"`
-	Polygon    []float32 `json:"polygon,omitempty"`
-	Span       Span      `json:"span"`
-	Confidence float32   `json:"confidence"`
+	State      string    `json:"state" jsonschema:"State of the selection mark: selected or unselected."`
+	Polygon    []float32 `json:"polygon,omitempty" jsonschema:"Bounding polygon of the selection mark, as a flat list of x, y coordinates."`
+	Span       Span      `json:"span" jsonschema:"Location of the selection mark in the reading order concatenated content."`
+	Confidence float32   `json:"confidence" jsonschema:"Confidence of correctly extracting the selection mark, between 0 and 1."`
 }
 
 // Line represents a content line object.
 type Line struct {
-	Content string    `json:"content"`
-	Polygon []float32 `json:"polygon,omitempty"`
-	Spans   []Span    `json:"spans"`
+	Content string    `json:"content" jsonschema:"Concatenated content of the contained elements in reading order."`
+	Polygon []float32 `json:"polygon,omitempty" jsonschema:"Bounding polygon of the line, as a flat list of x, y coordinates."`
+	Spans   []Span    `json:"spans" jsonschema:"Location of the line in the reading order concatenated content."`
 }
 
 // Barcode represents a barcode object.
 type Barcode struct {
-	Kind       string    `json:"kind"`
-	Value      string    `json:"value"`
-	Polygon    []float32 `json:"polygon,omitempty"`
-	Span       Span      `json:"span"`
-	Confidence float32   `json:"confidence"`
+	Kind       string    `json:"kind" jsonschema:"Barcode kind, for example QRCode or EAN13."`
+	Value      string    `json:"value" jsonschema:"Barcode value."`
+	Polygon    []float32 `json:"polygon,omitempty" jsonschema:"Bounding polygon of the barcode, as a flat list of x, y coordinates."`
+	Span       Span      `json:"span" jsonschema:"Location of the barcode in the reading order concatenated content."`
+	Confidence float32   `json:"confidence" jsonschema:"Confidence of correctly extracting the barcode, between 0 and 1."`
 }
 
 // Formula represents a formula object.
 type Formula struct {
-	Kind       string    `json:"kind"`
-	Value      string    `json:"value"`
-	Polygon    []float32 `json:"polygon,omitempty"`
-	Span       Span      `json:"span"`
-	Confidence float32   `json:"confidence"`
+	Kind       string    `json:"kind" jsonschema:"Formula kind: inline or display."`
+	Value      string    `json:"value" jsonschema:"LaTeX expression describing the formula."`
+	Polygon    []float32 `json:"polygon,omitempty" jsonschema:"Bounding polygon of the formula, as a flat list of x, y coordinates."`
+	Span       Span      `json:"span" jsonschema:"Location of the formula in the reading order concatenated content."`
+	Confidence float32   `json:"confidence" jsonschema:"Confidence of correctly extracting the formula, between 0 and 1."`
 }
 
 // Paragraph represents a paragraph object.
 type Paragraph struct {
-	Role            *string          `json:"role,omitempty"`
-	Content         string           `json:"content"`
-	BoundingRegions []BoundingRegion `json:"boundingRegions,omitempty"`
-	Spans           []Span           `json:"spans"`
+	Role            *string          `json:"role,omitempty" jsonschema:"Semantic role of the paragraph, for example title, sectionHeading or pageNumber."`
+	Content         string           `json:"content" jsonschema:"Concatenated content of the paragraph in reading order."`
+	BoundingRegions []BoundingRegion `json:"boundingRegions,omitempty" jsonschema:"Bounding regions covering the paragraph."`
+	Spans           []Span           `json:"spans" jsonschema:"Location of the paragraph in the reading order concatenated content."`
 }
 
 // BoundingRegion represents a bounding polygon on a specific page.
 type BoundingRegion struct {
-	PageNumber int32   `json:"pageNumber"`
-	Polygon    []float32 `json:"polygon"`
+	PageNumber int32     `json:"pageNumber" jsonschema:"1-based page number of page containing the bounding region."`
+	Polygon    []float32 `json:"polygon" jsonschema:"Bounding polygon on the page, as a flat list of x, y coordinates."`
 }
 
 // Table represents a table object.
 type Table struct {
-	RowCount        int32            `json:"rowCount"`
-	ColumnCount     int32            `json:"columnCount"`
-	Cells           []Cell           `json:"cells"`
-	BoundingRegions []BoundingRegion `json:"boundingRegions,omitempty"`
-	Spans           []Span           `json:"spans"`
-	Caption         *Caption         `json:"caption,omitempty"`
-	Footnotes       []*Footnote      `json:"footnotes,omitempty"`
+	RowCount        int32            `json:"rowCount" jsonschema:"Number of rows in the table."`
+	ColumnCount     int32            `json:"columnCount" jsonschema:"Number of columns in the table."`
+	Cells           []Cell           `json:"cells" jsonschema:"Cells contained within the table."`
+	BoundingRegions []BoundingRegion `json:"boundingRegions,omitempty" jsonschema:"Bounding regions covering the table."`
+	Spans           []Span           `json:"spans" jsonschema:"Location of the table in the reading order concatenated content."`
+	Caption         *Caption         `json:"caption,omitempty" jsonschema:"Caption associated with the table."`
+	Footnotes       []*Footnote      `json:"footnotes,omitempty" jsonschema:"List of footnotes associated with the table."`
 }
 
 // Cell represents a cell in a table.
 type Cell struct {
-	Kind            *string          `json:"kind,omitempty"`
-	RowIndex        int32            `json:"rowIndex"`
-	ColumnIndex     int32            `json:"columnIndex"`
-	RowSpan         *int32           `json:"rowSpan,omitempty"`
-	ColumnSpan      *int32           `json:"columnSpan,omitempty"`
-	Content         string           `json:"content"`
-	BoundingRegions []BoundingRegion `json:"boundingRegions,omitempty"`
-	Spans           []Span           `json:"spans"`
-	Elements        []string         `json:"elements,omitempty"`
+	Kind            *string          `json:"kind,omitempty" jsonschema:"Table cell kind: content, rowHeader, columnHeader, stubHead or description."`
+	RowIndex        int32            `json:"rowIndex" jsonschema:"Row index of the cell."`
+	ColumnIndex     int32            `json:"columnIndex" jsonschema:"Column index of the cell."`
+	RowSpan         *int32           `json:"rowSpan,omitempty" jsonschema:"Number of rows spanned by this cell."`
+	ColumnSpan      *int32           `json:"columnSpan,omitempty" jsonschema:"Number of columns spanned by this cell."`
+	Content         string           `json:"content" jsonschema:"Concatenated content of the table cell in reading order."`
+	BoundingRegions []BoundingRegion `json:"boundingRegions,omitempty" jsonschema:"Bounding regions covering the table cell."`
+	Spans           []Span           `json:"spans" jsonschema:"Location of the table cell in the reading order concatenated content."`
+	Elements        []string         `json:"elements,omitempty" jsonschema:"Child elements of the table cell, as JSON pointers such as /paragraphs/0."`
 }
 
 // Figure represents a figure in the document.
 type Figure struct {
-	BoundingRegions []BoundingRegion `json:"boundingRegions,omitempty"`
-	Spans           []Span           `json:"spans"`
-	Elements        []string         `json:"elements,omitempty"`
-	Caption         *Caption         `json:"caption,omitempty"`
-	Footnotes       []*Footnote      `json:"footnotes,omitempty"`
-	ID              *string          `json:"id,omitempty"`
+	BoundingRegions []BoundingRegion `json:"boundingRegions,omitempty" jsonschema:"Bounding regions covering the figure."`
+	Spans           []Span           `json:"spans" jsonschema:"Location of the figure in the reading order concatenated content."`
+	Elements        []string         `json:"elements,omitempty" jsonschema:"Child elements of the figure, excluding any caption or footnotes."`
+	Caption         *Caption         `json:"caption,omitempty" jsonschema:"Caption associated with the figure."`
+	Footnotes       []*Footnote      `json:"footnotes,omitempty" jsonschema:"List of footnotes associated with the figure."`
+	ID              *string          `json:"id,omitempty" jsonschema:"Figure ID."`
 }
 
 // Section represents a section in the document.
 type Section struct {
-	Spans    []Span   `json:"spans"`
-	Elements []string `json:"elements,omitempty"`
+	Spans    []Span   `json:"spans" jsonschema:"Location of the section in the reading order concatenated content."`
+	Elements []string `json:"elements,omitempty" jsonschema:"Child elements of the section, as JSON pointers such as /paragraphs/0."`
 }
 
 // Caption represents a caption for a table or figure.
 type Caption struct {
-	Content         string           `json:"content"`
-	BoundingRegions []BoundingRegion `json:"boundingRegions,omitempty"`
-	Spans           []Span           `json:"spans"`
-	Elements        []string         `json:"elements,omitempty"`
+	Content         string           `json:"content" jsonschema:"Content of the caption."`
+	BoundingRegions []BoundingRegion `json:"boundingRegions,omitempty" jsonschema:"Bounding regions covering the caption."`
+	Spans           []Span           `json:"spans" jsonschema:"Location of the caption in the reading order concatenated content."`
+	Elements        []string         `json:"elements,omitempty" jsonschema:"Child elements of the caption."`
 }
 
 // Footnote represents a footnote.
 type Footnote struct {
-	Content         string           `json:"content"`
-	BoundingRegions []BoundingRegion `json:"boundingRegions,omitempty"`
-	Spans           []Span           `json:"spans"`
-	Elements        []string         `json:"elements,omitempty"`
+	Content         string           `json:"content" jsonschema:"Content of the footnote."`
+	BoundingRegions []BoundingRegion `json:"boundingRegions,omitempty" jsonschema:"Bounding regions covering the footnote."`
+	Spans           []Span           `json:"spans" jsonschema:"Location of the footnote in the reading order concatenated content."`
+	Elements        []string         `json:"elements,omitempty" jsonschema:"Child elements of the footnote."`
 }
 
 // KeyValuePair represents a key-value pair.
 type KeyValuePair struct {
-	Key        KeyValueElement  `json:"key"`
-	Value      *KeyValueElement `json:"value,omitempty"`
-	Confidence float32          `json:"confidence"`
+	Key        KeyValueElement  `json:"key" jsonschema:"Field label of the key-value pair."`
+	Value      *KeyValueElement `json:"value,omitempty" jsonschema:"Field value of the key-value pair."`
+	Confidence float32          `json:"confidence" jsonschema:"Confidence of correctly extracting the key-value pair, between 0 and 1."`
 }
 
 // KeyValueElement represents the key or value in a key-value pair.
 type KeyValueElement struct {
-	Content         string           `json:"content"`
-	BoundingRegions []BoundingRegion `json:"boundingRegions,omitempty"`
-	Spans           []Span           `json:"spans"`
+	Content         string           `json:"content" jsonschema:"Concatenated content of the key-value element in reading order."`
+	BoundingRegions []BoundingRegion `json:"boundingRegions,omitempty" jsonschema:"Bounding regions covering the key-value element."`
+	Spans           []Span           `json:"spans" jsonschema:"Location of the key-value element in the reading order concatenated content."`
 }
 
 // Style represents observed text styles.
 type Style struct {
-	IsHandwritten     *bool    `json:"isHandwritten,omitempty"`
-	SimilarFontFamily *string  `json:"similarFontFamily,omitempty"`
-	FontStyle         *string  `json:"fontStyle,omitempty"`
-	FontWeight        *string  `json:"fontWeight,omitempty"`
-	Color             *string  `json:"color,omitempty"`
-	BackgroundColor   *string  `json:"backgroundColor,omitempty"`
-	Spans             []Span   `json:"spans"`
-	Confidence        float32  `json:"confidence"`
+	IsHandwritten     *bool   `json:"isHandwritten,omitempty" jsonschema:"Is content handwritten?"`
+	SimilarFontFamily *string `json:"similarFontFamily,omitempty" jsonschema:"Visually most similar font from among the set of supported font families."`
+	FontStyle         *string `json:"fontStyle,omitempty" jsonschema:"Font style: normal or italic."`
+	FontWeight        *string `json:"fontWeight,omitempty" jsonschema:"Font weight: normal or bold."`
+	Color             *string `json:"color,omitempty" jsonschema:"Foreground color in #rrggbb hexadecimal format."`
+	BackgroundColor   *string `json:"backgroundColor,omitempty" jsonschema:"Background color in #rrggbb hexadecimal format."`
+	Spans             []Span  `json:"spans" jsonschema:"Location of the text elements in the concatenated content the style applies to."`
+	Confidence        float32 `json:"confidence" jsonschema:"Confidence of correctly identifying the style, between 0 and 1."`
 }
 
 // Language represents a detected language.
 type Language struct {
-	Locale     string  `json:"locale"`
-	Spans      []Span  `json:"spans"`
-	Confidence float32 `json:"confidence"`
+	Locale     string  `json:"locale" jsonschema:"Detected language, as a BCP 47 language tag or ISO 639-1 language code."`
+	Spans      []Span  `json:"spans" jsonschema:"Location of the text elements in the concatenated content the language applies to."`
+	Confidence float32 `json:"confidence" jsonschema:"Confidence of correctly identifying the language, between 0 and 1."`
 }
 
 // Document represents an extracted document.
 type Document struct {
-	DocType         string                   `json:"docType"`
-	BoundingRegions []BoundingRegion         `json:"boundingRegions,omitempty"`
-	Spans           []Span                   `json:"spans"`
-	Fields          map[string]*DocumentField `json:"fields,omitempty"`
-	Confidence      float32                  `json:"confidence"`
+	DocType         string                    `json:"docType" jsonschema:"Document type."`
+	BoundingRegions []BoundingRegion          `json:"boundingRegions,omitempty" jsonschema:"Bounding regions covering the document."`
+	Spans           []Span                    `json:"spans" jsonschema:"Location of the document in the reading order concatenated content."`
+	Fields          map[string]*DocumentField `json:"fields,omitempty" jsonschema:"Dictionary of named field values."`
+	Confidence      float32                   `json:"confidence" jsonschema:"Confidence of correctly extracting the document, between 0 and 1."`
 }
 
 // DocumentField represents the content and location of a field value.
 type DocumentField struct {
-	Type               string                   `json:"type"`
-	ValueString        *string                  `json:"valueString,omitempty"`
-	ValueDate          *time.Time               `json:"valueDate,omitempty"`
-	ValueTime          *time.Time               `json:"valueTime,omitempty"`
-	ValuePhoneNumber   *string                  `json:"valuePhoneNumber,omitempty"`
-	ValueNumber        *float64                 `json:"valueNumber,omitempty"`
-	ValueInteger       *int64                   `json:"valueInteger,omitempty"`
-	ValueSelectionMark *string                  `json:"valueSelectionMark,omitempty"`
-	ValueSignature     *string                  `json:"valueSignature,omitempty"`
-	ValueCountryRegion *string                  `json:"valueCountryRegion,omitempty"`
-	ValueArray         []*DocumentField         `json:"valueArray,omitempty"`
-	ValueObject        map[string]*DocumentField `json:"valueObject,omitempty"`
-	ValueCurrency      *CurrencyValue           `json:"valueCurrency,omitempty"`
-	ValueAddress       *AddressValue            `json:"valueAddress,omitempty"`
-	ValueBoolean       *bool                    `json:"valueBoolean,omitempty"`
-	ValueSelectionGroup []string			 `json:"valueSelectionGroup,omitempty"`
-	Content            *string                  `json:"content,omitempty"`
-	BoundingRegions    []BoundingRegion         `json:"boundingRegions,omitempty"`
-	Spans              []Span                   `json:"spans,omitempty"`
-	Confidence         *float32                 `json:"confidence,omitempty"`
+	Type                string                    `json:"type" jsonschema:"Data type of the field value, which selects the value* property holding it."`
+	ValueString         *string                   `json:"valueString,omitempty" jsonschema:"String value."`
+	ValueDate           *string                   `json:"valueDate,omitempty" jsonschema:"Date value in ISO 8601 format (YYYY-MM-DD)."`
+	ValueTime           *string                   `json:"valueTime,omitempty" jsonschema:"Time value in ISO 8601 format (hh:mm:ss)."`
+	ValuePhoneNumber    *string                   `json:"valuePhoneNumber,omitempty" jsonschema:"Phone number value in E.164 format (ex. +19876543210)."`
+	ValueNumber         *float64                  `json:"valueNumber,omitempty" jsonschema:"Floating point value."`
+	ValueInteger        *int64                    `json:"valueInteger,omitempty" jsonschema:"Integer value."`
+	ValueSelectionMark  *string                   `json:"valueSelectionMark,omitempty" jsonschema:"Selection mark value: selected or unselected."`
+	ValueSignature      *string                   `json:"valueSignature,omitempty" jsonschema:"Presence of signature: signed or unsigned."`
+	ValueCountryRegion  *string                   `json:"valueCountryRegion,omitempty" jsonschema:"3-letter country code value (ISO 3166-1 alpha-3)."`
+	ValueArray          []*DocumentField          `json:"valueArray,omitempty" jsonschema:"Array of field values."`
+	ValueObject         map[string]*DocumentField `json:"valueObject,omitempty" jsonschema:"Dictionary of named field values."`
+	ValueCurrency       *CurrencyValue            `json:"valueCurrency,omitempty" jsonschema:"Currency value."`
+	ValueAddress        *AddressValue             `json:"valueAddress,omitempty" jsonschema:"Address value."`
+	ValueBoolean        *bool                     `json:"valueBoolean,omitempty" jsonschema:"Boolean value."`
+	ValueSelectionGroup []string                  `json:"valueSelectionGroup,omitempty" jsonschema:"Selection group value."`
+	Content             *string                   `json:"content,omitempty" jsonschema:"Field content."`
+	BoundingRegions     []BoundingRegion          `json:"boundingRegions,omitempty" jsonschema:"Bounding regions covering the field."`
+	Spans               []Span                    `json:"spans,omitempty" jsonschema:"Location of the field in the reading order concatenated content."`
+	Confidence          *float32                  `json:"confidence,omitempty" jsonschema:"Confidence of correctly extracting the field, between 0 and 1."`
 }
 
 // CurrencyValue represents a currency field value.
 type CurrencyValue struct {
-	Amount         float64 `json:"amount"`
-	CurrencySymbol *string `json:"currencySymbol,omitempty"`
-	CurrencyCode   *string `json:"currencyCode,omitempty"`
+	Amount         float64 `json:"amount" jsonschema:"Currency amount."`
+	CurrencySymbol *string `json:"currencySymbol,omitempty" jsonschema:"Currency symbol label, if any."`
+	CurrencyCode   *string `json:"currencyCode,omitempty" jsonschema:"Resolved currency code (ISO 4217), if any."`
 }
 
 // AddressValue represents an address field value.
 type AddressValue struct {
-	HouseNumber   *string `json:"houseNumber,omitempty"`
-	PoBox         *string `json:"poBox,omitempty"`
-	Road          *string `json:"road,omitempty"`
-	City          *string `json:"city,omitempty"`
-	State         *string `json:"state,omitempty"`
-	PostalCode    *string `json:"postalCode,omitempty"`
-	CountryRegion *string `json:"countryRegion,omitempty"`
-	StreetAddress *string `json:"streetAddress,omitempty"`
-	Unit          *string `json:"unit,omitempty"`
-	CityDistrict  *string `json:"cityDistrict,omitempty"`
-	StateDistrict *string `json:"stateDistrict,omitempty"`
-	Suburb        *string `json:"suburb,omitempty"`
-	House         *string `json:"house,omitempty"`
-	Level         *string `json:"level,omitempty"`
+	HouseNumber   *string `json:"houseNumber,omitempty" jsonschema:"House or building number."`
+	PoBox         *string `json:"poBox,omitempty" jsonschema:"Post office box number."`
+	Road          *string `json:"road,omitempty" jsonschema:"Street name."`
+	City          *string `json:"city,omitempty" jsonschema:"Name of city, town, village, etc."`
+	State         *string `json:"state,omitempty" jsonschema:"First-level administrative division."`
+	PostalCode    *string `json:"postalCode,omitempty" jsonschema:"Postal code used for mail sorting."`
+	CountryRegion *string `json:"countryRegion,omitempty" jsonschema:"Country/region."`
+	StreetAddress *string `json:"streetAddress,omitempty" jsonschema:"Street-level address, excluding city, state, countryRegion, and postalCode."`
+	Unit          *string `json:"unit,omitempty" jsonschema:"Apartment or office number."`
+	CityDistrict  *string `json:"cityDistrict,omitempty" jsonschema:"Districts or boroughs within a city, such as Brooklyn in New York City or City of Westminster in London."`
+	StateDistrict *string `json:"stateDistrict,omitempty" jsonschema:"Second-level administrative division used in certain locales."`
+	Suburb        *string `json:"suburb,omitempty" jsonschema:"Unofficial neighborhood name, like Chinatown."`
+	House         *string `json:"house,omitempty" jsonschema:"Build name, such as World Trade Center."`
+	Level         *string `json:"level,omitempty" jsonschema:"Floor number, such as 3F."`
 }
 
 // Warning represents a warning from the service.
 type Warning struct {
-	Code    string  `json:"code"`
-	Message string  `json:"message"`
-	Target  *string `json:"target,omitempty"`
+	Code    string  `json:"code" jsonschema:"One of a server-defined set of warning codes."`
+	Message string  `json:"message" jsonschema:"A human-readable representation of the warning."`
+	Target  *string `json:"target,omitempty" jsonschema:"The target of the error."`
 }
 
 // Error represents the error object from the service.
 type Error struct {
-	Code       string      `json:"code"`
-	Message    string      `json:"message"`
-	Target     *string     `json:"target,omitempty"`
-	Details    []*Error    `json:"details,omitempty"`
-	InnerError *InnerError `json:"innererror,omitempty"`
+	Code       string      `json:"code" jsonschema:"One of a server-defined set of error codes."`
+	Message    string      `json:"message" jsonschema:"A human-readable representation of the error."`
+	Target     *string     `json:"target,omitempty" jsonschema:"The target of the error."`
+	Details    []*Error    `json:"details,omitempty" jsonschema:"An array of details about specific errors that led to this reported error."`
+	InnerError *InnerError `json:"innererror,omitempty" jsonschema:"An object containing more specific information than the current object about the error."`
 }
 
 // InnerError represents more specific information about an error.
 type InnerError struct {
-	Code       *string     `json:"code,omitempty"`
-	Message    *string     `json:"message,omitempty"`
-	InnerError *InnerError `json:"innererror,omitempty"`
-}
\ No newline at end of file
+	Code       *string     `json:"code,omitempty" jsonschema:"One of a server-defined set of error codes."`
+	Message    *string     `json:"message,omitempty" jsonschema:"A human-readable representation of the error."`
+	InnerError *InnerError `json:"innererror,omitempty" jsonschema:"Inner error."`
+}
@@ -0,0 +1,181 @@
+package analysis
+
+import (
+	"maps"
+	"slices"
+	"strconv"
+)
+
+// Review item kinds reported in ReviewItem.Kind.
+const (
+	ReviewKindWord          = "word"
+	ReviewKindSelectionMark = "selectionMark"
+	ReviewKindKeyValuePair  = "keyValuePair"
+	ReviewKindField         = "field"
+)
+
+// ConfidenceThresholds holds the minimum confidence per element type.
+// A zero threshold disables the check for that element type.
+type ConfidenceThresholds struct {
+	Word          float32
+	SelectionMark float32
+	KeyValuePair  float32
+	Field         float32
+}
+
+// ReviewItem describes an extracted element whose confidence is below its threshold.
+type ReviewItem struct {
+	Kind       string    `json:"kind" jsonschema:"Element type: word, selectionMark, keyValuePair or field."`
+	Name       string    `json:"name,omitempty" jsonschema:"Key of a key-value pair or dotted path of a field."`
+	Value      any       `json:"value,omitempty" jsonschema:"Extracted value of the element."`
+	Confidence float32   `json:"confidence" jsonschema:"Confidence of correctly extracting the element, between 0 and 1."`
+	PageNumber int32     `json:"pageNumber,omitempty" jsonschema:"1-based page number the element appears on."`
+	Polygon    []float32 `json:"polygon,omitempty" jsonschema:"Bounding polygon of the element, as a flat list of x, y coordinates."`
+}
+
+// LowConfidence returns the words, selection marks, key-value pairs and
+// document fields whose confidence is below the thresholds. Fields are named
+// by their dotted path, prefixed with the document index for results with
+// more than one document.
+func (r *AnalyzeResult) LowConfidence(th ConfidenceThresholds) []*ReviewItem {
+	var items []*ReviewItem
+	for _, page := range r.Pages {
+		for _, w := range page.Words {
+			if below(w.Confidence, th.Word) {
+				items = append(items, &ReviewItem{Kind: ReviewKindWord, Value: w.Content, Confidence: w.Confidence, PageNumber: page.PageNumber, Polygon: w.Polygon})
+			}
+		}
+		for _, m := range page.SelectionMarks {
+			if below(m.Confidence, th.SelectionMark) {
+				items = append(items, &ReviewItem{Kind: ReviewKindSelectionMark, Value: m.State, Confidence: m.Confidence, PageNumber: page.PageNumber, Polygon: m.Polygon})
+			}
+		}
+	}
+	for _, kv := range r.KeyValuePairs {
+		if !below(kv.Confidence, th.KeyValuePair) {
+			continue
+		}
+		item := &ReviewItem{Kind: ReviewKindKeyValuePair, Name: kv.Key.Content, Confidence: kv.Confidence}
+		region := kv.Key.BoundingRegions
+		if kv.Value != nil {
+			item.Value = kv.Value.Content
+			if len(kv.Value.BoundingRegions) > 0 {
+				region = kv.Value.BoundingRegions
+			}
+		}
+		if len(region) > 0 {
+			item.PageNumber, item.Polygon = region[0].PageNumber, region[0].Polygon
+		}
+		items = append(items, item)
+	}
+	for i, doc := range r.Documents {
+		prefix := ""
+		if len(r.Documents) > 1 {
+			prefix = strconv.Itoa(i) + "."
+		}
+		for _, name := range sortedFieldNames(doc.Fields) {
+			items = appendLowConfidenceFields(items, prefix+name, doc.Fields[name], th.Field)
+		}
+	}
+	return items
+}
+
+func appendLowConfidenceFields(items []*ReviewItem, path string, f *DocumentField, threshold float32) []*ReviewItem {
+	if f == nil {
+		return items
+	}
+	if f.Confidence != nil && below(*f.Confidence, threshold) {
+		item := &ReviewItem{Kind: ReviewKindField, Name: path, Value: f.Value(), Confidence: *f.Confidence}
+		if item.Value == nil && f.Content != nil {
+			item.Value = *f.Content
+		}
+		if len(f.BoundingRegions) > 0 {
+			item.PageNumber, item.Polygon = f.BoundingRegions[0].PageNumber, f.BoundingRegions[0].Polygon
+		}
+		items = append(items, item)
+	}
+	for i, elem := range f.ValueArray {
+		items = appendLowConfidenceFields(items, path+"."+strconv.Itoa(i), elem, threshold)
+	}
+	for _, name := range sortedFieldNames(f.ValueObject) {
+		items = appendLowConfidenceFields(items, path+"."+name, f.ValueObject[name], threshold)
+	}
+	return items
+}
+
+// WithoutLowConfidence returns a copy of the result without the words,
+// selection marks, key-value pairs and document fields whose confidence is
+// below the thresholds. Elements that are kept are shared with r.
+func (r *AnalyzeResult) WithoutLowConfidence(th ConfidenceThresholds) *AnalyzeResult {
+	filtered := *r
+	filtered.Pages = make([]Page, len(r.Pages))
+	for i, page := range r.Pages {
+		page.Words = filterSlice(page.Words, func(w *Word) bool { return !below(w.Confidence, th.Word) })
+		page.SelectionMarks = filterSlice(page.SelectionMarks, func(m *SelectionMark) bool { return !below(m.Confidence, th.SelectionMark) })
+		filtered.Pages[i] = page
+	}
+	filtered.KeyValuePairs = filterSlice(r.KeyValuePairs, func(kv *KeyValuePair) bool { return !below(kv.Confidence, th.KeyValuePair) })
+	if r.Documents != nil {
+		filtered.Documents = make([]*Document, len(r.Documents))
+		for i, doc := range r.Documents {
+			d := *doc
+			d.Fields = filterFields(doc.Fields, th.Field)
+			filtered.Documents[i] = &d
+		}
+	}
+	return &filtered
+}
+
+func filterFields(fields map[string]*DocumentField, threshold float32) map[string]*DocumentField {
+	if fields == nil {
+		return nil
+	}
+	kept := make(map[string]*DocumentField, len(fields))
+	for name, f := range fields {
+		if f = filterField(f, threshold); f != nil {
+			kept[name] = f
+		}
+	}
+	return kept
+}
+
+func filterField(f *DocumentField, threshold float32) *DocumentField {
+	if f == nil || (f.Confidence != nil && below(*f.Confidence, threshold)) {
+		return nil
+	}
+	if f.ValueArray == nil && f.ValueObject == nil {
+		return f
+	}
+	c := *f
+	if f.ValueArray != nil {
+		c.ValueArray = make([]*DocumentField, 0, len(f.ValueArray))
+		for _, elem := range f.ValueArray {
+			if elem = filterField(elem, threshold); elem != nil {
+				c.ValueArray = append(c.ValueArray, elem)
+			}
+		}
+	}
+	c.ValueObject = filterFields(f.ValueObject, threshold)
+	return &c
+}
+
+func filterSlice[T any](s []T, keep func(T) bool) []T {
+	if s == nil {
+		return nil
+	}
+	kept := make([]T, 0, len(s))
+	for _, v := range s {
+		if keep(v) {
+			kept = append(kept, v)
+		}
+	}
+	return kept
+}
+
+func below(confidence, threshold float32) bool {
+	return threshold > 0 && confidence < threshold
+}
+
+func sortedFieldNames(fields map[string]*DocumentField) []string {
+	return slices.Sorted(maps.Keys(fields))
+}
@@ -0,0 +1,84 @@
+package analysis
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func ptr[T any](v T) *T { return &v }
+
+func newConfidenceResult() *AnalyzeResult {
+	return &AnalyzeResult{
+		Pages: []Page{{
+			PageNumber: 1,
+			Words: []*Word{
+				{Content: "Total", Confidence: 0.99, Polygon: []float32{1, 1}},
+				{Content: "1O0", Confidence: 0.4, Polygon: []float32{2, 2}},
+			},
+			SelectionMarks: []*SelectionMark{{State: "selected", Confidence: 0.5}},
+		}},
+		KeyValuePairs: []*KeyValuePair{
+			{
+				Key:        KeyValueElement{Content: "Name"},
+				Value:      &KeyValueElement{Content: "Jane", BoundingRegions: []BoundingRegion{{PageNumber: 1, Polygon: []float32{3, 3}}}},
+				Confidence: 0.6,
+			},
+			{Key: KeyValueElement{Content: "Date"}, Confidence: 0.95},
+		},
+		Documents: []*Document{{
+			DocType: "invoice",
+			Fields: map[string]*DocumentField{
+				"VendorName": {Type: FieldTypeString, ValueString: ptr("Contoso"), Confidence: ptr(float32(0.3)), BoundingRegions: []BoundingRegion{{PageNumber: 1, Polygon: []float32{4, 4}}}},
+				"Items": {Type: FieldTypeArray, Confidence: ptr(float32(0.9)), ValueArray: []*DocumentField{
+					{Type: FieldTypeObject, Confidence: ptr(float32(0.9)), ValueObject: map[string]*DocumentField{
+						"Amount":      {Type: FieldTypeNumber, ValueNumber: ptr(10.0), Confidence: ptr(float32(0.2))},
+						"Description": {Type: FieldTypeString, ValueString: ptr("Pen"), Confidence: ptr(float32(0.95))},
+					}},
+				}},
+			},
+		}},
+	}
+}
+
+func TestAnalyzeResult_LowConfidence(t *testing.T) {
+	r := newConfidenceResult()
+
+	items := r.LowConfidence(ConfidenceThresholds{Word: 0.5, KeyValuePair: 0.8, Field: 0.5})
+
+	require.Len(t, items, 4)
+	assert.Equal(t, &ReviewItem{Kind: ReviewKindWord, Value: "1O0", Confidence: 0.4, PageNumber: 1, Polygon: []float32{2, 2}}, items[0])
+	assert.Equal(t, &ReviewItem{Kind: ReviewKindKeyValuePair, Name: "Name", Value: "Jane", Confidence: 0.6, PageNumber: 1, Polygon: []float32{3, 3}}, items[1])
+	assert.Equal(t, &ReviewItem{Kind: ReviewKindField, Name: "Items.0.Amount", Value: 10.0, Confidence: 0.2}, items[2])
+	assert.Equal(t, &ReviewItem{Kind: ReviewKindField, Name: "VendorName", Value: "Contoso", Confidence: 0.3, PageNumber: 1, Polygon: []float32{4, 4}}, items[3])
+}
+
+func TestAnalyzeResult_LowConfidenceDisabled(t *testing.T) {
+	r := newConfidenceResult()
+
+	assert.Empty(t, r.LowConfidence(ConfidenceThresholds{}))
+}
+
+func TestAnalyzeResult_WithoutLowConfidence(t *testing.T) {
+	r := newConfidenceResult()
+
+	filtered := r.WithoutLowConfidence(ConfidenceThresholds{Word: 0.5, SelectionMark: 0.6, KeyValuePair: 0.8, Field: 0.5})
+
+	require.Len(t, filtered.Pages[0].Words, 1)
+	assert.Equal(t, "Total", filtered.Pages[0].Words[0].Content)
+	assert.Empty(t, filtered.Pages[0].SelectionMarks)
+	require.Len(t, filtered.KeyValuePairs, 1)
+	assert.Equal(t, "Date", filtered.KeyValuePairs[0].Key.Content)
+	fields := filtered.Documents[0].Fields
+	assert.NotContains(t, fields, "VendorName")
+	item := fields["Items"].ValueArray[0].ValueObject
+	assert.NotContains(t, item, "Amount")
+	assert.Contains(t, item, "Description")
+
+	// The original result is left untouched.
+	assert.Len(t, r.Pages[0].Words, 2)
+	assert.Len(t, r.KeyValuePairs, 2)
+	assert.Contains(t, r.Documents[0].Fields, "VendorName")
+	assert.Contains(t, r.Documents[0].Fields["Items"].ValueArray[0].ValueObject, "Amount")
+}
@@ -0,0 +1,204 @@
+package analysis
+
+import (
+	"strconv"
+	"time"
+)
+
+// Field types reported in DocumentField.Type.
+const (
+	FieldTypeString         = "string"
+	FieldTypeDate           = "date"
+	FieldTypeTime           = "time"
+	FieldTypePhoneNumber    = "phoneNumber"
+	FieldTypeNumber         = "number"
+	FieldTypeInteger        = "integer"
+	FieldTypeSelectionMark  = "selectionMark"
+	FieldTypeSignature      = "signature"
+	FieldTypeCountryRegion  = "countryRegion"
+	FieldTypeArray          = "array"
+	FieldTypeObject         = "object"
+	FieldTypeCurrency       = "currency"
+	FieldTypeAddress        = "address"
+	FieldTypeBoolean        = "boolean"
+	FieldTypeSelectionGroup = "selectionGroup"
+)
+
+const (
+	dateLayout = "2006-01-02"
+	timeLayout = "15:04:05"
+)
+
+// Value returns the typed value selected by Type, or nil if the service did not
+// return one. Arrays and objects are returned as []any and map[string]any of
+// their elements' values.
+func (f *DocumentField) Value() any {
+	if f == nil {
+		return nil
+	}
+	switch f.Type {
+	case FieldTypeString:
+		return derefOrNil(f.ValueString)
+	case FieldTypeDate:
+		return derefOrNil(f.ValueDate)
+	case FieldTypeTime:
+		return derefOrNil(f.ValueTime)
+	case FieldTypePhoneNumber:
+		return derefOrNil(f.ValuePhoneNumber)
+	case FieldTypeNumber:
+		return derefOrNil(f.ValueNumber)
+	case FieldTypeInteger:
+		return derefOrNil(f.ValueInteger)
+	case FieldTypeSelectionMark:
+		return derefOrNil(f.ValueSelectionMark)
+	case FieldTypeSignature:
+		return derefOrNil(f.ValueSignature)
+	case FieldTypeCountryRegion:
+		return derefOrNil(f.ValueCountryRegion)
+	case FieldTypeBoolean:
+		return derefOrNil(f.ValueBoolean)
+	case FieldTypeCurrency:
+		if f.ValueCurrency == nil {
+			return nil
+		}
+		return *f.ValueCurrency
+	case FieldTypeAddress:
+		if f.ValueAddress == nil {
+			return nil
+		}
+		return *f.ValueAddress
+	case FieldTypeSelectionGroup:
+		if f.ValueSelectionGroup == nil {
+			return nil
+		}
+		return f.ValueSelectionGroup
+	case FieldTypeArray:
+		if f.ValueArray == nil {
+			return nil
+		}
+		values := make([]any, len(f.ValueArray))
+		for i, item := range f.ValueArray {
+			values[i] = item.Value()
+		}
+		return values
+	case FieldTypeObject:
+		if f.ValueObject == nil {
+			return nil
+		}
+		values := make(map[string]any, len(f.ValueObject))
+		for name, item := range f.ValueObject {
+			values[name] = item.Value()
+		}
+		return values
+	}
+	return nil
+}
+
+// AsString returns the value of string-like fields (string, phoneNumber,
+// selectionMark, signature and countryRegion) and the text of date, time,
+// number and integer fields.
+func (f *DocumentField) AsString() (string, bool) {
+	switch v := f.Value().(type) {
+	case string:
+		return v, true
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), true
+	case int64:
+		return strconv.FormatInt(v, 10), true
+	}
+	return "", false
+}
+
+// AsNumber returns the value of number and integer fields.
+func (f *DocumentField) AsNumber() (float64, bool) {
+	switch v := f.Value().(type) {
+	case float64:
+		return v, true
+	case int64:
+		return float64(v), true
+	}
+	return 0, false
+}
+
+// AsCurrency returns the value of currency fields.
+func (f *DocumentField) AsCurrency() (CurrencyValue, bool) {
+	v, ok := f.Value().(CurrencyValue)
+	return v, ok
+}
+
+// AsAddress returns the value of address fields.
+func (f *DocumentField) AsAddress() (AddressValue, bool) {
+	v, ok := f.Value().(AddressValue)
+	return v, ok
+}
+
+// AsBool returns the value of boolean fields.
+func (f *DocumentField) AsBool() (bool, bool) {
+	v, ok := f.Value().(bool)
+	return v, ok
+}
+
+// AsDate returns the value of date fields as midnight UTC of that day.
+func (f *DocumentField) AsDate() (time.Time, bool) {
+	if f == nil || f.Type != FieldTypeDate || f.ValueDate == nil {
+		return time.Time{}, false
+	}
+	t, err := time.Parse(dateLayout, *f.ValueDate)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+// AsTime returns the value of time fields as an offset from midnight.
+func (f *DocumentField) AsTime() (time.Duration, bool) {
+	if f == nil || f.Type != FieldTypeTime || f.ValueTime == nil {
+		return 0, false
+	}
+	t, err := time.Parse(timeLayout, *f.ValueTime)
+	if err != nil {
+		return 0, false
+	}
+	return t.Sub(time.Date(0, 1, 1, 0, 0, 0, 0, time.UTC)), true
+}
+
+// Flatten converts the fields of the document into a map from dotted paths
+// (for example "Items.0.Amount") to the plain values of the leaf fields.
+// Fields without a typed value fall back to their content.
+func (d *Document) Flatten() map[string]any {
+	flat := make(map[string]any)
+	for name, field := range d.Fields {
+		flattenField(flat, name, field)
+	}
+	return flat
+}
+
+func flattenField(flat map[string]any, path string, f *DocumentField) {
+	if f == nil {
+		flat[path] = nil
+		return
+	}
+	switch {
+	case f.Type == FieldTypeArray && f.ValueArray != nil:
+		for i, item := range f.ValueArray {
+			flattenField(flat, path+"."+strconv.Itoa(i), item)
+		}
+	case f.Type == FieldTypeObject && f.ValueObject != nil:
+		for name, item := range f.ValueObject {
+			flattenField(flat, path+"."+name, item)
+		}
+	default:
+		value := f.Value()
+		if value == nil && f.Content != nil {
+			value = *f.Content
+		}
+		flat[path] = value
+	}
+}
+
+func derefOrNil[T any](p *T) any {
+	if p == nil {
+		return nil
+	}
+	return *p
+}
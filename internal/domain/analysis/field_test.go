@@ -0,0 +1,91 @@
+package analysis
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const invoiceDocument = `{
+	"docType": "invoice",
+	"confidence": 0.95,
+	"fields": {
+		"VendorName": {"type": "string", "valueString": "Contoso", "content": "CONTOSO LTD."},
+		"InvoiceDate": {"type": "date", "valueDate": "2019-11-15", "content": "11/15/2019"},
+		"InvoiceTotal": {"type": "currency", "valueCurrency": {"amount": 110, "currencySymbol": "$", "currencyCode": "USD"}},
+		"PurchaseOrder": {"type": "string", "content": "PO-3333"},
+		"Items": {"type": "array", "valueArray": [
+			{"type": "object", "valueObject": {
+				"Description": {"type": "string", "valueString": "Consulting"},
+				"Quantity": {"type": "number", "valueNumber": 2}
+			}}
+		]}
+	}
+}`
+
+func decodeInvoice(t *testing.T) *Document {
+	t.Helper()
+	var doc Document
+	require.NoError(t, json.Unmarshal([]byte(invoiceDocument), &doc))
+	return &doc
+}
+
+func TestDocumentField_Accessors(t *testing.T) {
+	doc := decodeInvoice(t)
+
+	s, ok := doc.Fields["VendorName"].AsString()
+	assert.True(t, ok)
+	assert.Equal(t, "Contoso", s)
+
+	d, ok := doc.Fields["InvoiceDate"].AsDate()
+	assert.True(t, ok)
+	assert.Equal(t, time.Date(2019, 11, 15, 0, 0, 0, 0, time.UTC), d)
+
+	c, ok := doc.Fields["InvoiceTotal"].AsCurrency()
+	assert.True(t, ok)
+	assert.Equal(t, 110.0, c.Amount)
+	assert.Equal(t, "USD", *c.CurrencyCode)
+
+	_, ok = doc.Fields["InvoiceTotal"].AsString()
+	assert.False(t, ok)
+	_, ok = doc.Fields["VendorName"].AsDate()
+	assert.False(t, ok)
+	assert.Nil(t, doc.Fields["PurchaseOrder"].Value())
+}
+
+func TestDocumentField_ValueOfArray(t *testing.T) {
+	doc := decodeInvoice(t)
+
+	value := doc.Fields["Items"].Value()
+
+	assert.Equal(t, []any{map[string]any{"Description": "Consulting", "Quantity": 2.0}}, value)
+}
+
+func TestDocumentField_AsTime(t *testing.T) {
+	v := "13:59:00"
+	f := &DocumentField{Type: FieldTypeTime, ValueTime: &v}
+
+	d, ok := f.AsTime()
+
+	assert.True(t, ok)
+	assert.Equal(t, 13*time.Hour+59*time.Minute, d)
+}
+
+func TestDocument_Flatten(t *testing.T) {
+	doc := decodeInvoice(t)
+
+	flat := doc.Flatten()
+
+	symbol, code := "$", "USD"
+	assert.Equal(t, map[string]any{
+		"VendorName":          "Contoso",
+		"InvoiceDate":         "2019-11-15",
+		"InvoiceTotal":        CurrencyValue{Amount: 110, CurrencySymbol: &symbol, CurrencyCode: &code},
+		"PurchaseOrder":       "PO-3333",
+		"Items.0.Description": "Consulting",
+		"Items.0.Quantity":    2.0,
+	}, flat)
+}
@@ -0,0 +1,262 @@
+package analysis
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/rivo/uniseg"
+)
+
+// Part is the result of the analysis of some of the pages of a document.
+type Part struct {
+	Result *AnalyzeResult
+	// PageOffset is added to the page numbers of the result: the number of
+	// pages preceding the part when it was analyzed as a document of its own,
+	// or 0 when it was analyzed with a page range of the whole document.
+	PageOffset int32
+}
+
+// pageBreak separates the content of parts in markdown, as the service
+// separates pages.
+const pageBreak = "\n<!-- PageBreak -->\n"
+
+// Merge returns the result of a document analyzed in parts, given in page
+// order, as if it had been analyzed at once. The content of the parts is
+// concatenated, pages are renumbered, and the spans, element references such
+// as /paragraphs/3, and figure IDs of every part are moved to match. The parts
+// are left unchanged.
+//
+// Elements spanning two parts, such as a table continued on the next page,
+// stay split, and documents found in several parts are listed once per part.
+func Merge(parts []Part) (*AnalyzeResult, error) {
+	if len(parts) == 0 {
+		return nil, errors.New("no results to merge")
+	}
+	var merged *AnalyzeResult
+	var content strings.Builder
+	var length int32 // of content, in string index units
+	for i, part := range parts {
+		r, err := part.Result.clone()
+		if err != nil {
+			return nil, err
+		}
+		if merged == nil {
+			merged = &AnalyzeResult{ApiVersion: r.ApiVersion, ModelID: r.ModelID, StringIndexType: r.StringIndexType, ContentFormat: r.ContentFormat}
+		} else if r.StringIndexType != merged.StringIndexType {
+			return nil, fmt.Errorf("part %d has string index type %s, not %s", i+1, r.StringIndexType, merged.StringIndexType)
+		}
+
+		m := &merger{pages: part.PageOffset, elements: map[string]int{
+			"pages":         len(merged.Pages),
+			"paragraphs":    len(merged.Paragraphs),
+			"tables":        len(merged.Tables),
+			"figures":       len(merged.Figures),
+			"sections":      len(merged.Sections),
+			"keyValuePairs": len(merged.KeyValuePairs),
+		}}
+		if content.Len() > 0 && r.Content != "" {
+			separator := "\n"
+			if merged.ContentFormat != nil && *merged.ContentFormat == "markdown" {
+				separator = pageBreak
+			}
+			content.WriteString(separator)
+			length += textLength(separator, merged.StringIndexType)
+		}
+		m.offset = length
+		m.shift(r)
+		content.WriteString(r.Content)
+		length += textLength(r.Content, merged.StringIndexType)
+
+		merged.Pages = append(merged.Pages, r.Pages...)
+		merged.Paragraphs = append(merged.Paragraphs, r.Paragraphs...)
+		merged.Tables = append(merged.Tables, r.Tables...)
+		merged.Figures = append(merged.Figures, r.Figures...)
+		merged.Sections = append(merged.Sections, r.Sections...)
+		merged.KeyValuePairs = append(merged.KeyValuePairs, r.KeyValuePairs...)
+		merged.Styles = append(merged.Styles, r.Styles...)
+		merged.Languages = append(merged.Languages, r.Languages...)
+		merged.Documents = append(merged.Documents, r.Documents...)
+		merged.Warnings = append(merged.Warnings, r.Warnings...)
+	}
+	merged.Content = content.String()
+	if merged.Pages == nil {
+		merged.Pages = []Page{}
+	}
+	return merged, nil
+}
+
+// textLength returns the length of text in string index units: grapheme
+// clusters for textElements, the default of the service.
+func textLength(text, stringIndexType string) int32 {
+	if stringIndexType == "textElements" {
+		return int32(uniseg.GraphemeClusterCount(text))
+	}
+	var n int32
+	for _, r := range text {
+		n++
+		if stringIndexType == "utf16CodeUnit" && utf16.RuneLen(r) == 2 {
+			n++
+		}
+	}
+	return n
+}
+
+// merger moves the elements of a part to their place in the merged result.
+type merger struct {
+	// offset is added to span offsets, and pages to page numbers.
+	offset int32
+	pages  int32
+	// elements holds the number of elements of each collection before the part.
+	elements map[string]int
+}
+
+func (m *merger) shift(r *AnalyzeResult) {
+	for i := range r.Pages {
+		page := &r.Pages[i]
+		page.PageNumber += m.pages
+		m.spans(page.Spans)
+		for _, w := range page.Words {
+			w.Span.Offset += m.offset
+		}
+		for _, s := range page.SelectionMarks {
+			s.Span.Offset += m.offset
+		}
+		for _, l := range page.Lines {
+			m.spans(l.Spans)
+		}
+		for _, b := range page.Barcodes {
+			b.Span.Offset += m.offset
+		}
+		for _, f := range page.Formulas {
+			f.Span.Offset += m.offset
+		}
+	}
+	for _, p := range r.Paragraphs {
+		m.regions(p.BoundingRegions)
+		m.spans(p.Spans)
+	}
+	for _, t := range r.Tables {
+		m.regions(t.BoundingRegions)
+		m.spans(t.Spans)
+		for i := range t.Cells {
+			c := &t.Cells[i]
+			m.regions(c.BoundingRegions)
+			m.spans(c.Spans)
+			m.pointers(c.Elements)
+		}
+		m.caption(t.Caption)
+		m.footnotes(t.Footnotes)
+	}
+	for _, f := range r.Figures {
+		m.regions(f.BoundingRegions)
+		m.spans(f.Spans)
+		m.pointers(f.Elements)
+		m.caption(f.Caption)
+		m.footnotes(f.Footnotes)
+		if f.ID != nil {
+			id := m.figureID(*f.ID)
+			f.ID = &id
+		}
+	}
+	for _, s := range r.Sections {
+		m.spans(s.Spans)
+		m.pointers(s.Elements)
+	}
+	for _, kv := range r.KeyValuePairs {
+		m.regions(kv.Key.BoundingRegions)
+		m.spans(kv.Key.Spans)
+		if kv.Value != nil {
+			m.regions(kv.Value.BoundingRegions)
+			m.spans(kv.Value.Spans)
+		}
+	}
+	for _, s := range r.Styles {
+		m.spans(s.Spans)
+	}
+	for _, l := range r.Languages {
+		m.spans(l.Spans)
+	}
+	for _, doc := range r.Documents {
+		m.regions(doc.BoundingRegions)
+		m.spans(doc.Spans)
+		for _, f := range doc.Fields {
+			m.field(f)
+		}
+	}
+}
+
+func (m *merger) field(f *DocumentField) {
+	if f == nil {
+		return
+	}
+	m.regions(f.BoundingRegions)
+	m.spans(f.Spans)
+	for _, item := range f.ValueArray {
+		m.field(item)
+	}
+	for _, prop := range f.ValueObject {
+		m.field(prop)
+	}
+}
+
+func (m *merger) caption(c *Caption) {
+	if c != nil {
+		m.regions(c.BoundingRegions)
+		m.spans(c.Spans)
+		m.pointers(c.Elements)
+	}
+}
+
+func (m *merger) footnotes(footnotes []*Footnote) {
+	for _, f := range footnotes {
+		m.regions(f.BoundingRegions)
+		m.spans(f.Spans)
+		m.pointers(f.Elements)
+	}
+}
+
+func (m *merger) spans(spans []Span) {
+	for i := range spans {
+		spans[i].Offset += m.offset
+	}
+}
+
+func (m *merger) regions(regions []BoundingRegion) {
+	for i := range regions {
+		regions[i].PageNumber += m.pages
+	}
+}
+
+// pointers moves element references such as /paragraphs/3 by the number of
+// elements of their collection before the part.
+func (m *merger) pointers(pointers []string) {
+	for i, p := range pointers {
+		collection, rest, ok := strings.Cut(strings.TrimPrefix(p, "/"), "/")
+		before, known := m.elements[collection]
+		if !ok || !known || !strings.HasPrefix(p, "/") {
+			continue
+		}
+		index, suffix, hasSuffix := strings.Cut(rest, "/")
+		n, err := strconv.Atoi(index)
+		if err != nil {
+			continue
+		}
+		pointers[i] = fmt.Sprintf("/%s/%d", collection, n+before)
+		if hasSuffix {
+			pointers[i] += "/" + suffix
+		}
+	}
+}
+
+// figureID renumbers the page of a figure ID, which is <page>.<index>.
+func (m *merger) figureID(id string) string {
+	page, index, ok := strings.Cut(id, ".")
+	n, err := strconv.Atoi(page)
+	if !ok || err != nil {
+		return id
+	}
+	return strconv.Itoa(n+int(m.pages)) + "." + index
+}
@@ -0,0 +1,115 @@
+package analysis
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// partResult returns the result of a two-page part with a paragraph, a
+// table and a figure on its first page, and a document on its second.
+func partResult(content string) *AnalyzeResult {
+	id, total := "1.1", 42.0
+	n := int32(len(content))
+	return &AnalyzeResult{
+		ModelID:         "prebuilt-layout",
+		StringIndexType: "utf16CodeUnit",
+		Content:         content,
+		Pages: []Page{
+			{PageNumber: 1, Spans: []Span{{0, 4}}, Words: []*Word{{Content: content[:4], Span: Span{0, 4}}}},
+			{PageNumber: 2, Spans: []Span{{5, n - 5}}, Lines: []*Line{{Content: content[5:], Spans: []Span{{5, n - 5}}}}},
+		},
+		Paragraphs: []*Paragraph{{Content: content[:4], Spans: []Span{{0, 4}}, BoundingRegions: []BoundingRegion{{PageNumber: 1}}}},
+		Tables: []*Table{{
+			Cells:           []Cell{{Content: content[:4], Spans: []Span{{0, 4}}, Elements: []string{"/paragraphs/0"}, BoundingRegions: []BoundingRegion{{PageNumber: 1}}}},
+			BoundingRegions: []BoundingRegion{{PageNumber: 1}},
+			Spans:           []Span{{0, 4}},
+		}},
+		Figures:  []*Figure{{ID: &id, Elements: []string{"/paragraphs/0"}, Spans: []Span{{0, 4}}}},
+		Sections: []*Section{{Spans: []Span{{0, n}}, Elements: []string{"/paragraphs/0", "/tables/0", "/figures/0"}}},
+		Documents: []*Document{{
+			DocType: "invoice",
+			Spans:   []Span{{5, n - 5}},
+			Fields: map[string]*DocumentField{
+				"Items": {Type: FieldTypeArray, ValueArray: []*DocumentField{{
+					Type: FieldTypeObject,
+					ValueObject: map[string]*DocumentField{
+						"Amount": {Type: FieldTypeNumber, ValueNumber: &total, Spans: []Span{{5, 2}}, BoundingRegions: []BoundingRegion{{PageNumber: 2}}},
+					},
+				}}},
+			},
+		}},
+	}
+}
+
+func TestMerge(t *testing.T) {
+	first := partResult("Page 42 €")
+	second := partResult("Next 42 😀")
+
+	merged, err := Merge([]Part{{Result: first}, {Result: second, PageOffset: 2}})
+	require.NoError(t, err)
+
+	assert.Equal(t, "Page 42 €\nNext 42 😀", merged.Content)
+	assert.Equal(t, "prebuilt-layout", merged.ModelID)
+	require.Len(t, merged.Pages, 4)
+	for i, p := range merged.Pages {
+		assert.Equal(t, int32(i+1), p.PageNumber)
+	}
+	// The first part is 11 bytes and 9 UTF-16 code units long, followed by a line break.
+	assert.Equal(t, []Span{{10, 4}}, merged.Pages[2].Spans)
+	assert.Equal(t, Span{10, 4}, merged.Pages[2].Words[0].Span)
+	assert.Equal(t, []Span{{15, int32(len("Next 42 😀")) - 5}}, merged.Pages[3].Lines[0].Spans)
+
+	require.Len(t, merged.Paragraphs, 2)
+	assert.Equal(t, []BoundingRegion{{PageNumber: 3}}, merged.Paragraphs[1].BoundingRegions)
+	cell := merged.Tables[1].Cells[0]
+	assert.Equal(t, []string{"/paragraphs/1"}, cell.Elements)
+	assert.Equal(t, []BoundingRegion{{PageNumber: 3}}, cell.BoundingRegions)
+	assert.Equal(t, "3.1", *merged.Figures[1].ID)
+	assert.Equal(t, "1.1", *merged.Figures[0].ID)
+	assert.Equal(t, []string{"/paragraphs/1", "/tables/1", "/figures/1"}, merged.Sections[1].Elements)
+	assert.Equal(t, []string{"/paragraphs/0", "/tables/0", "/figures/0"}, merged.Sections[0].Elements)
+
+	require.Len(t, merged.Documents, 2)
+	amount := merged.Documents[1].Fields["Items"].ValueArray[0].ValueObject["Amount"]
+	assert.Equal(t, []Span{{15, 2}}, amount.Spans)
+	assert.Equal(t, []BoundingRegion{{PageNumber: 4}}, amount.BoundingRegions)
+
+	assert.Equal(t, int32(2), second.Pages[1].PageNumber, "parts are left unchanged")
+	assert.Equal(t, int32(5), second.Pages[1].Lines[0].Spans[0].Offset)
+	assert.Equal(t, []string{"/paragraphs/0"}, second.Tables[0].Cells[0].Elements)
+}
+
+func TestMerge_PageRanges(t *testing.T) {
+	markdown := "markdown"
+	first := &AnalyzeResult{StringIndexType: "textElements", ContentFormat: &markdown, Content: "# One", Pages: []Page{{PageNumber: 1, Spans: []Span{{0, 5}}}}}
+	second := &AnalyzeResult{StringIndexType: "textElements", ContentFormat: &markdown, Content: "Two", Pages: []Page{{PageNumber: 2, Spans: []Span{{0, 3}}}}}
+
+	merged, err := Merge([]Part{{Result: first}, {Result: second}})
+	require.NoError(t, err)
+	assert.Equal(t, "# One\n<!-- PageBreak -->\nTwo", merged.Content)
+	assert.Equal(t, int32(2), merged.Pages[1].PageNumber, "pages of a range keep their number")
+	assert.Equal(t, []Span{{25, 3}}, merged.Pages[1].Spans)
+}
+
+func TestMerge_TextElements(t *testing.T) {
+	// "é" as e and a combining accent, and a flag, are one text element each.
+	first := &AnalyzeResult{StringIndexType: "textElements", Content: "Cafe\u0301 \U0001F1EB\U0001F1F7", Pages: []Page{{PageNumber: 1, Spans: []Span{{0, 6}}}}}
+	second := &AnalyzeResult{StringIndexType: "textElements", Content: "Two", Pages: []Page{{PageNumber: 2, Spans: []Span{{0, 3}}}}}
+
+	merged, err := Merge([]Part{{Result: first}, {Result: second}})
+	require.NoError(t, err)
+	assert.Equal(t, []Span{{7, 3}}, merged.Pages[1].Spans)
+}
+
+func TestMerge_Errors(t *testing.T) {
+	_, err := Merge(nil)
+	assert.Error(t, err)
+
+	_, err = Merge([]Part{
+		{Result: &AnalyzeResult{StringIndexType: "textElements"}},
+		{Result: &AnalyzeResult{StringIndexType: "utf16CodeUnit"}},
+	})
+	assert.Error(t, err)
+}
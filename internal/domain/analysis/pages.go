@@ -0,0 +1,88 @@
+package analysis
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// pageSpan is a range of pages of a page range, from and to included.
+type pageSpan struct{ from, to int }
+
+// parsePages returns the spans of a page range, sorted by their first page.
+func parsePages(pages string) ([]pageSpan, error) {
+	var spans []pageSpan
+	for part := range strings.SplitSeq(pages, ",") {
+		first, last, isRange := strings.Cut(strings.TrimSpace(part), "-")
+		from, err := strconv.Atoi(strings.TrimSpace(first))
+		if err != nil || from < 1 {
+			return nil, fmt.Errorf("invalid page range %q", pages)
+		}
+		to := from
+		if isRange {
+			to, err = strconv.Atoi(strings.TrimSpace(last))
+			if err != nil || to < from {
+				return nil, fmt.Errorf("invalid page range %q", pages)
+			}
+		}
+		spans = append(spans, pageSpan{from, to})
+	}
+	slices.SortFunc(spans, func(a, b pageSpan) int { return cmp.Compare(a.from, b.from) })
+	return spans, nil
+}
+
+// PageRanges returns the ranges of pages selected by a page range in the
+// syntax of the service, as their first and last page, sorted, with
+// overlapping and adjacent ranges merged.
+func PageRanges(pages string) ([][2]int, error) {
+	spans, err := parsePages(pages)
+	if err != nil {
+		return nil, err
+	}
+	var ranges [][2]int
+	for _, s := range spans {
+		if n := len(ranges); n > 0 && s.from <= ranges[n-1][1]+1 {
+			ranges[n-1][1] = max(ranges[n-1][1], s.to)
+			continue
+		}
+		ranges = append(ranges, [2]int{s.from, s.to})
+	}
+	return ranges, nil
+}
+
+// CountPages returns the number of pages selected by a page range in the
+// syntax of the service, such as "1-3,5,7-9". Overlapping ranges are counted once.
+func CountPages(pages string) (int, error) {
+	ranges, err := PageRanges(pages)
+	if err != nil {
+		return 0, err
+	}
+	count := 0
+	for _, r := range ranges {
+		count += r[1] - r[0] + 1
+	}
+	return count, nil
+}
+
+// PageNumbers returns the numbers of the pages of a document of total pages
+// selected by a page range, in order and without duplicates. An empty range
+// selects every page; pages past the end of the document are ignored, as the
+// service does.
+func PageNumbers(pages string, total int) ([]int, error) {
+	ranges := [][2]int{{1, total}}
+	if pages != "" {
+		var err error
+		if ranges, err = PageRanges(pages); err != nil {
+			return nil, err
+		}
+	}
+	var numbers []int
+	for _, r := range ranges {
+		for n := r[0]; n <= min(r[1], total); n++ {
+			numbers = append(numbers, n)
+		}
+	}
+	return numbers, nil
+}
@@ -0,0 +1,66 @@
+package analysis
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCountPages(t *testing.T) {
+	tests := []struct {
+		pages string
+		want  int
+	}{
+		{"1", 1},
+		{"1-3", 3},
+		{"1-3,5,7-9", 7},
+		{"2-4, 3-6", 5},
+		{"5,1-10", 10},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pages, func(t *testing.T) {
+			got, err := CountPages(tt.pages)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	for _, invalid := range []string{"", "0", "3-1", "a-b", "1,,2"} {
+		_, err := CountPages(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestPageRanges(t *testing.T) {
+	got, err := PageRanges("9, 1-3,2-4,5,7")
+	require.NoError(t, err)
+	assert.Equal(t, [][2]int{{1, 5}, {7, 7}, {9, 9}}, got)
+
+	_, err = PageRanges("1-")
+	assert.Error(t, err)
+}
+
+func TestPageNumbers(t *testing.T) {
+	tests := []struct {
+		pages string
+		total int
+		want  []int
+	}{
+		{"", 3, []int{1, 2, 3}},
+		{"2", 3, []int{2}},
+		{"5,1-2", 10, []int{1, 2, 5}},
+		{"2-4, 3-6", 10, []int{2, 3, 4, 5, 6}},
+		{"2-9", 4, []int{2, 3, 4}},
+		{"7", 4, nil},
+		{"", 0, nil},
+	}
+	for _, tt := range tests {
+		got, err := PageNumbers(tt.pages, tt.total)
+		require.NoError(t, err)
+		assert.Equal(t, tt.want, got, "%q of %d", tt.pages, tt.total)
+	}
+
+	_, err := PageNumbers("3-1", 5)
+	assert.Error(t, err)
+}
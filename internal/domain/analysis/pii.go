@@ -0,0 +1,385 @@
+package analysis
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+
+	"github.com/linzhengen/azure-document-intelligence-mcp/internal/domain/pii"
+)
+
+// PIIItem describes a piece of PII replaced by RedactPII. The value itself is
+// never reported.
+type PIIItem struct {
+	Category    string `json:"category" jsonschema:"Kind of PII: EMAIL, PHONE, CREDIT_CARD, IBAN, SSN or a custom category."`
+	Placeholder string `json:"placeholder" jsonschema:"Text replacing the value. The same value is replaced by the same placeholder throughout the result."`
+	PageNumber  int32  `json:"pageNumber,omitempty" jsonschema:"1-based page number the value appears on."`
+	Span        *Span  `json:"span,omitempty" jsonschema:"Location of the placeholder in the redacted content."`
+	Field       string `json:"field,omitempty" jsonschema:"Dotted path of the document field holding the value, for values found in fields rather than in the content."`
+}
+
+// RedactPII returns a copy of the result with the PII found by detector
+// replaced by placeholders such as [EMAIL_1], and the list of replacements.
+//
+// PII is detected in the content, and every element is rewritten to match:
+// spans are moved to the redacted content, and the content of elements is
+// taken from the redacted content where their span covers it exactly, or has
+// the same values replaced otherwise. Field values, which may be normalized,
+// such as phone numbers, are checked as well. Offsets in textElements are
+// treated as code points, which only differ for combining sequences.
+func (r *AnalyzeResult) RedactPII(detector pii.Detector) (*AnalyzeResult, []*PIIItem, error) {
+	redacted, err := r.clone()
+	if err != nil {
+		return nil, nil, err
+	}
+	red := &piiRedactor{
+		detector:     detector,
+		placeholders: map[string]string{},
+		counts:       map[string]int{},
+		old:          newTextIndex(r.Content, r.StringIndexType),
+	}
+	items := red.redactContent(redacted)
+	red.redactElements(redacted)
+	items = append(items, red.fieldItems...)
+	return redacted, items, nil
+}
+
+func (r *AnalyzeResult) clone() (*AnalyzeResult, error) {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to copy result: %w", err)
+	}
+	var c AnalyzeResult
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("failed to copy result: %w", err)
+	}
+	return &c, nil
+}
+
+type piiRedactor struct {
+	detector pii.Detector
+	// placeholders maps a category and normalized value to its placeholder.
+	placeholders map[string]string
+	counts       map[string]int
+	// replacements are the values replaced in the content, longest first.
+	replacements []replacement
+	old, new     *textIndex
+	edits        []edit
+	fieldItems   []*PIIItem
+}
+
+type replacement struct {
+	value, placeholder string
+}
+
+// edit is the replacement of [oldStart, oldEnd) in the content by [newStart,
+// newEnd) in the redacted content, in string index units.
+type edit struct {
+	oldStart, oldEnd, newStart, newEnd int32
+}
+
+// placeholder returns the placeholder of value, numbering the values of each category.
+func (p *piiRedactor) placeholder(category, value string) string {
+	key := category + "\x00" + normalizePII(value)
+	if ph, ok := p.placeholders[key]; ok {
+		return ph
+	}
+	p.counts[category]++
+	ph := "[" + category + "_" + strconv.Itoa(p.counts[category]) + "]"
+	p.placeholders[key] = ph
+	return ph
+}
+
+// normalizePII keeps the letters and digits of value, so that "(425) 555-0100"
+// and "425.555.0100" get the same placeholder.
+func normalizePII(value string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+		return -1
+	}, value)
+}
+
+func (p *piiRedactor) redactContent(r *AnalyzeResult) []*PIIItem {
+	var items []*PIIItem
+	var b strings.Builder
+	last := 0
+	var shift int32
+	for _, m := range p.detector.Detect(r.Content) {
+		value := r.Content[m.Start:m.End]
+		ph := p.placeholder(m.Category, value)
+		p.replacements = append(p.replacements, replacement{value: value, placeholder: ph})
+
+		oldStart, oldEnd := p.old.unit(m.Start), p.old.unit(m.End)
+		e := edit{oldStart: oldStart, oldEnd: oldEnd, newStart: oldStart + shift}
+		e.newEnd = e.newStart + int32(len(ph)) // placeholders are ASCII
+		shift += (e.newEnd - e.newStart) - (oldEnd - oldStart)
+		p.edits = append(p.edits, e)
+
+		b.WriteString(r.Content[last:m.Start])
+		b.WriteString(ph)
+		last = m.End
+		items = append(items, &PIIItem{
+			Category:    m.Category,
+			Placeholder: ph,
+			PageNumber:  pageOf(r.Pages, oldStart),
+			Span:        &Span{Offset: e.newStart, Length: e.newEnd - e.newStart},
+		})
+	}
+	b.WriteString(r.Content[last:])
+	r.Content = b.String()
+	p.new = newTextIndex(r.Content, r.StringIndexType)
+	slices.SortStableFunc(p.replacements, func(a, b replacement) int { return cmp.Compare(len(b.value), len(a.value)) })
+	return items
+}
+
+// pageOf returns the number of the page whose spans contain offset, or 0.
+func pageOf(pages []Page, offset int32) int32 {
+	for _, page := range pages {
+		for _, s := range page.Spans {
+			if offset >= s.Offset && offset < s.Offset+s.Length {
+				return page.PageNumber
+			}
+		}
+	}
+	return 0
+}
+
+func (p *piiRedactor) redactElements(r *AnalyzeResult) {
+	for i := range r.Pages {
+		page := &r.Pages[i]
+		p.spans(page.Spans)
+		for _, w := range page.Words {
+			w.Content, w.Span = p.element(w.Content, w.Span)
+		}
+		for _, m := range page.SelectionMarks {
+			m.Span = p.span(m.Span)
+		}
+		for _, l := range page.Lines {
+			l.Content = p.elementSpans(l.Content, l.Spans)
+		}
+		for _, bc := range page.Barcodes {
+			bc.Value, _ = p.text(bc.Value)
+			bc.Span = p.span(bc.Span)
+		}
+		for _, f := range page.Formulas {
+			f.Span = p.span(f.Span)
+		}
+	}
+	for _, para := range r.Paragraphs {
+		para.Content = p.elementSpans(para.Content, para.Spans)
+	}
+	for _, t := range r.Tables {
+		p.spans(t.Spans)
+		for i := range t.Cells {
+			c := &t.Cells[i]
+			c.Content = p.elementSpans(c.Content, c.Spans)
+		}
+		p.caption(t.Caption)
+		p.footnotes(t.Footnotes)
+	}
+	for _, f := range r.Figures {
+		p.spans(f.Spans)
+		p.caption(f.Caption)
+		p.footnotes(f.Footnotes)
+	}
+	for _, s := range r.Sections {
+		p.spans(s.Spans)
+	}
+	for _, kv := range r.KeyValuePairs {
+		kv.Key.Content = p.elementSpans(kv.Key.Content, kv.Key.Spans)
+		if kv.Value != nil {
+			kv.Value.Content = p.elementSpans(kv.Value.Content, kv.Value.Spans)
+		}
+	}
+	for _, s := range r.Styles {
+		p.spans(s.Spans)
+	}
+	for _, l := range r.Languages {
+		p.spans(l.Spans)
+	}
+	for i, doc := range r.Documents {
+		p.spans(doc.Spans)
+		prefix := ""
+		if len(r.Documents) > 1 {
+			prefix = strconv.Itoa(i) + "."
+		}
+		for _, name := range sortedFieldNames(doc.Fields) {
+			p.field(prefix+name, doc.Fields[name])
+		}
+	}
+}
+
+func (p *piiRedactor) caption(c *Caption) {
+	if c != nil {
+		c.Content = p.elementSpans(c.Content, c.Spans)
+	}
+}
+
+func (p *piiRedactor) footnotes(footnotes []*Footnote) {
+	for _, f := range footnotes {
+		f.Content = p.elementSpans(f.Content, f.Spans)
+	}
+}
+
+func (p *piiRedactor) field(path string, f *DocumentField) {
+	if f == nil {
+		return
+	}
+	if f.Content != nil {
+		content := p.elementSpans(*f.Content, f.Spans)
+		f.Content = &content
+	} else {
+		p.spans(f.Spans)
+	}
+	for _, v := range []**string{&f.ValueString, &f.ValuePhoneNumber} {
+		if *v == nil {
+			continue
+		}
+		value, found := p.text(**v)
+		*v = &value
+		for _, item := range found {
+			item.Field = path
+			if len(f.BoundingRegions) > 0 {
+				item.PageNumber = f.BoundingRegions[0].PageNumber
+			}
+			p.fieldItems = append(p.fieldItems, item)
+		}
+	}
+	if a := f.ValueAddress; a != nil {
+		for _, v := range []**string{&a.HouseNumber, &a.PoBox, &a.Road, &a.StreetAddress, &a.Unit, &a.House} {
+			if *v != nil {
+				value, _ := p.text(**v)
+				*v = &value
+			}
+		}
+	}
+	for i, elem := range f.ValueArray {
+		p.field(path+"."+strconv.Itoa(i), elem)
+	}
+	for _, name := range sortedFieldNames(f.ValueObject) {
+		p.field(path+"."+name, f.ValueObject[name])
+	}
+}
+
+// element returns the redacted content and span of an element with a single span.
+func (p *piiRedactor) element(content string, s Span) (string, Span) {
+	redacted := p.span(s)
+	if p.old.slice(s) == content {
+		return p.new.slice(redacted), redacted
+	}
+	content, _ = p.text(content)
+	return content, redacted
+}
+
+// elementSpans moves spans to the redacted content and returns the redacted content.
+func (p *piiRedactor) elementSpans(content string, spans []Span) string {
+	if len(spans) == 1 {
+		content, spans[0] = p.element(content, spans[0])
+		return content
+	}
+	p.spans(spans)
+	content, _ = p.text(content)
+	return content
+}
+
+// text replaces the values already replaced in the content, then the PII
+// detected in what remains. It reports the latter.
+func (p *piiRedactor) text(s string) (string, []*PIIItem) {
+	for _, r := range p.replacements {
+		s = strings.ReplaceAll(s, r.value, r.placeholder)
+	}
+	matches := p.detector.Detect(s)
+	if len(matches) == 0 {
+		return s, nil
+	}
+	var items []*PIIItem
+	var b strings.Builder
+	last := 0
+	for _, m := range matches {
+		ph := p.placeholder(m.Category, s[m.Start:m.End])
+		b.WriteString(s[last:m.Start])
+		b.WriteString(ph)
+		last = m.End
+		items = append(items, &PIIItem{Category: m.Category, Placeholder: ph})
+	}
+	b.WriteString(s[last:])
+	return b.String(), items
+}
+
+func (p *piiRedactor) spans(spans []Span) {
+	for i, s := range spans {
+		spans[i] = p.span(s)
+	}
+}
+
+// span moves s to the redacted content. A span starting or ending within a
+// replaced value is extended to the whole placeholder.
+func (p *piiRedactor) span(s Span) Span {
+	start, end := s.Offset, s.Offset+s.Length
+	var startShift, endShift int32
+	newStart, newEnd := int32(-1), int32(-1)
+	for _, e := range p.edits {
+		delta := (e.newEnd - e.newStart) - (e.oldEnd - e.oldStart)
+		switch {
+		case e.oldEnd <= start:
+			startShift += delta
+		case e.oldStart <= start && newStart < 0:
+			newStart = e.newStart
+		}
+		switch {
+		case e.oldEnd <= end:
+			endShift += delta
+		case e.oldStart < end && newEnd < 0:
+			newEnd = e.newEnd
+		}
+	}
+	if newStart < 0 {
+		newStart = start + startShift
+	}
+	if newEnd < 0 {
+		newEnd = end + endShift
+	}
+	return Span{Offset: newStart, Length: max(newEnd-newStart, 0)}
+}
+
+// textIndex converts between byte offsets in a string and the string index
+// units of the service.
+type textIndex struct {
+	text string
+	// offsets holds the byte offset of every unit, and the length of text.
+	offsets []int
+}
+
+func newTextIndex(text, stringIndexType string) *textIndex {
+	idx := &textIndex{text: text, offsets: make([]int, 0, len(text)+1)}
+	for i, r := range text {
+		idx.offsets = append(idx.offsets, i)
+		if stringIndexType == "utf16CodeUnit" && utf16.RuneLen(r) == 2 {
+			idx.offsets = append(idx.offsets, i)
+		}
+	}
+	idx.offsets = append(idx.offsets, len(text))
+	return idx
+}
+
+// unit returns the unit at byte offset i.
+func (t *textIndex) unit(i int) int32 {
+	return int32(sort.SearchInts(t.offsets, i))
+}
+
+// slice returns the text of s, or "" if s is out of range.
+func (t *textIndex) slice(s Span) string {
+	start, end := int(s.Offset), int(s.Offset+s.Length)
+	if start < 0 || end < start || end >= len(t.offsets) {
+		return ""
+	}
+	return t.text[t.offsets[start]:t.offsets[end]]
+}
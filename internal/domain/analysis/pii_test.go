@@ -0,0 +1,127 @@
+package analysis
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/linzhengen/azure-document-intelligence-mcp/internal/domain/pii"
+)
+
+// newPIIResult returns a two-page result:
+//
+//	page 1: "Email jane@contoso.com\n"      offsets 0-22
+//	page 2: "Call (425) 555-0100 or jane@contoso.com" offsets 23-62
+func newPIIResult() *AnalyzeResult {
+	content := "Email jane@contoso.com\nCall (425) 555-0100 or jane@contoso.com"
+	return &AnalyzeResult{
+		StringIndexType: "textElements",
+		Content:         content,
+		Pages: []Page{
+			{
+				PageNumber: 1,
+				Spans:      []Span{{Offset: 0, Length: 23}},
+				Words: []*Word{
+					{Content: "Email", Span: Span{Offset: 0, Length: 5}},
+					{Content: "jane@contoso.com", Span: Span{Offset: 6, Length: 16}},
+				},
+			},
+			{
+				PageNumber: 2,
+				Spans:      []Span{{Offset: 23, Length: 39}},
+				Words: []*Word{
+					{Content: "Call", Span: Span{Offset: 23, Length: 4}},
+					{Content: "(425)", Span: Span{Offset: 28, Length: 5}},
+					{Content: "555-0100", Span: Span{Offset: 34, Length: 8}},
+					{Content: "or", Span: Span{Offset: 43, Length: 2}},
+				},
+				Lines: []*Line{{Content: "Call (425) 555-0100 or jane@contoso.com", Spans: []Span{{Offset: 23, Length: 39}}}},
+			},
+		},
+		Paragraphs: []*Paragraph{
+			{Content: "Email jane@contoso.com", Spans: []Span{{Offset: 0, Length: 22}}},
+			{Content: "Call (425) 555-0100\nor jane@contoso.com", Spans: []Span{{Offset: 23, Length: 19}, {Offset: 43, Length: 19}}},
+		},
+		KeyValuePairs: []*KeyValuePair{{
+			Key:   KeyValueElement{Content: "Email", Spans: []Span{{Offset: 0, Length: 5}}},
+			Value: &KeyValueElement{Content: "jane@contoso.com", Spans: []Span{{Offset: 6, Length: 16}}},
+		}},
+		Documents: []*Document{{
+			Spans: []Span{{Offset: 0, Length: 62}},
+			Fields: map[string]*DocumentField{
+				"Phone": {
+					Type:             FieldTypePhoneNumber,
+					ValuePhoneNumber: ptr("+14255550100"),
+					Content:          ptr("(425) 555-0100"),
+					Spans:            []Span{{Offset: 28, Length: 14}},
+					BoundingRegions:  []BoundingRegion{{PageNumber: 2}},
+				},
+			},
+		}},
+	}
+}
+
+func TestAnalyzeResult_RedactPII(t *testing.T) {
+	r := newPIIResult()
+
+	redacted, items, err := r.RedactPII(pii.Default())
+	require.NoError(t, err)
+
+	assert.Equal(t, "Email [EMAIL_1]\nCall [PHONE_1] or [EMAIL_1]", redacted.Content)
+	assert.Equal(t, []*PIIItem{
+		{Category: pii.CategoryEmail, Placeholder: "[EMAIL_1]", PageNumber: 1, Span: &Span{Offset: 6, Length: 9}},
+		{Category: pii.CategoryPhone, Placeholder: "[PHONE_1]", PageNumber: 2, Span: &Span{Offset: 21, Length: 9}},
+		{Category: pii.CategoryEmail, Placeholder: "[EMAIL_1]", PageNumber: 2, Span: &Span{Offset: 34, Length: 9}},
+		{Category: pii.CategoryPhone, Placeholder: "[PHONE_2]", PageNumber: 2, Field: "Phone"},
+	}, items)
+
+	// Spans are moved to the redacted content and elements are rewritten to match it.
+	assert.Equal(t, []Span{{Offset: 0, Length: 16}}, redacted.Pages[0].Spans)
+	assert.Equal(t, []Span{{Offset: 16, Length: 27}}, redacted.Pages[1].Spans)
+	assert.Equal(t, &Word{Content: "[EMAIL_1]", Span: Span{Offset: 6, Length: 9}}, redacted.Pages[0].Words[1])
+	assert.Equal(t, &Word{Content: "[PHONE_1]", Span: Span{Offset: 21, Length: 9}}, redacted.Pages[1].Words[1])
+	assert.Equal(t, &Word{Content: "or", Span: Span{Offset: 31, Length: 2}}, redacted.Pages[1].Words[3])
+	assert.Equal(t, "Call [PHONE_1] or [EMAIL_1]", redacted.Pages[1].Lines[0].Content)
+	assert.Equal(t, "Call [PHONE_1]\nor [EMAIL_1]", redacted.Paragraphs[1].Content)
+	assert.Equal(t, []Span{{Offset: 16, Length: 14}, {Offset: 31, Length: 12}}, redacted.Paragraphs[1].Spans)
+	assert.Equal(t, "[EMAIL_1]", redacted.KeyValuePairs[0].Value.Content)
+	phone := redacted.Documents[0].Fields["Phone"]
+	assert.Equal(t, "[PHONE_1]", *phone.Content)
+	assert.Equal(t, "[PHONE_2]", *phone.ValuePhoneNumber)
+	for _, s := range append(redacted.Pages[1].Spans, redacted.Paragraphs[0].Spans...) {
+		assert.LessOrEqual(t, int(s.Offset+s.Length), len(redacted.Content))
+	}
+
+	// The original result is left untouched.
+	assert.Equal(t, newPIIResult(), r)
+}
+
+func TestAnalyzeResult_RedactPIINothingFound(t *testing.T) {
+	r := &AnalyzeResult{Content: "Total 12.50", Pages: []Page{{PageNumber: 1, Spans: []Span{{Offset: 0, Length: 11}}}}}
+
+	redacted, items, err := r.RedactPII(pii.Default())
+	require.NoError(t, err)
+
+	assert.Empty(t, items)
+	assert.Equal(t, r, redacted)
+}
+
+func TestAnalyzeResult_RedactPIIUTF16(t *testing.T) {
+	// "😀" is two UTF-16 code units.
+	r := &AnalyzeResult{
+		StringIndexType: "utf16CodeUnit",
+		Content:         "😀 jane@contoso.com ok",
+		Pages: []Page{{PageNumber: 1, Words: []*Word{
+			{Content: "jane@contoso.com", Span: Span{Offset: 3, Length: 16}},
+			{Content: "ok", Span: Span{Offset: 20, Length: 2}},
+		}}},
+	}
+
+	redacted, _, err := r.RedactPII(pii.Default())
+	require.NoError(t, err)
+
+	assert.Equal(t, "😀 [EMAIL_1] ok", redacted.Content)
+	assert.Equal(t, &Word{Content: "[EMAIL_1]", Span: Span{Offset: 3, Length: 9}}, redacted.Pages[0].Words[0])
+	assert.Equal(t, &Word{Content: "ok", Span: Span{Offset: 13, Length: 2}}, redacted.Pages[0].Words[1])
+}
@@ -6,6 +6,16 @@ type AnalyzeDocumentOptions struct {
 	DocURL      string
 	Content     []byte
 	ContentType string
+	// Pages restricts the analysis to a page range such as "1-3,5". Empty means all pages.
+	Pages string
+	// PageLimit, if not zero, is the number of pages the analysis of a whole
+	// document was restricted to: Pages is then "1-<PageLimit>", set by this
+	// server rather than given by the caller, and may go past the end of the
+	// document.
+	PageLimit int
+	// Region restricts the analysis to endpoints tagged with this region, for
+	// data residency. Empty means any endpoint.
+	Region string
 }
 
 type Repository interface {
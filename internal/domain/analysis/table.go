@@ -0,0 +1,17 @@
+package analysis
+
+// Grid lays out the table cells as RowCount rows of ColumnCount strings.
+// Cells spanning several rows or columns are placed at their top-left position only.
+func (t *Table) Grid() [][]string {
+	grid := make([][]string, t.RowCount)
+	for i := range grid {
+		grid[i] = make([]string, t.ColumnCount)
+	}
+	for _, cell := range t.Cells {
+		if cell.RowIndex < 0 || cell.RowIndex >= t.RowCount || cell.ColumnIndex < 0 || cell.ColumnIndex >= t.ColumnCount {
+			continue
+		}
+		grid[cell.RowIndex][cell.ColumnIndex] = cell.Content
+	}
+	return grid
+}
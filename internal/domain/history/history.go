@@ -0,0 +1,209 @@
+// Package history keeps the results of past analyses, so that documents can
+// be recalled and searched without analyzing them again.
+package history
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/linzhengen/azure-document-intelligence-mcp/internal/domain/analysis"
+)
+
+// Record describes a stored analysis.
+type Record struct {
+	ID      string    `json:"id"`
+	Time    time.Time `json:"time"`
+	ModelID string    `json:"modelId"`
+	// Source is the URL of the document, empty for uploaded content.
+	Source      string `json:"source,omitempty"`
+	ContentType string `json:"contentType,omitempty"`
+	// Hash is the SHA-256 of the document content, or of its URL when the
+	// service downloaded it.
+	Hash    string `json:"hash"`
+	Client  string `json:"client,omitempty"`
+	Session string `json:"session,omitempty"`
+	Pages   int    `json:"pages"`
+}
+
+// Filter selects records. Zero fields select everything.
+type Filter struct {
+	ModelID string
+	Client  string
+	// Since selects the records at or after a time.
+	Since time.Time
+	// Scoped selects only the records of Session, which is empty for
+	// stdio sessions.
+	Scoped  bool
+	Session string
+	// Limit bounds the number of records returned.
+	Limit int
+}
+
+// Matches reports whether r is selected by f, ignoring the limit.
+func (f Filter) Matches(r *Record) bool {
+	return (f.ModelID == "" || r.ModelID == f.ModelID) &&
+		(f.Client == "" || r.Client == f.Client) &&
+		(!f.Scoped || r.Session == f.Session) &&
+		!r.Time.Before(f.Since)
+}
+
+// Hit is a record matching a search.
+type Hit struct {
+	*Record
+	// Score is the number of occurrences of the query terms.
+	Score int
+	// Snippets are the field values or excerpts of the content where the
+	// terms occur.
+	Snippets []string
+}
+
+// ErrNotFound is returned for unknown record IDs.
+var ErrNotFound = errors.New("analysis not found")
+
+// Store stores analysis results with their records.
+type Store interface {
+	// Save stores a result and its record, setting the ID of the record.
+	Save(ctx context.Context, record *Record, result *analysis.AnalyzeOperationResult) error
+	// Get returns a record and its result, or ErrNotFound.
+	Get(ctx context.Context, id string) (*Record, *analysis.AnalyzeOperationResult, error)
+	// List returns the records selected by filter, newest first.
+	List(ctx context.Context, filter Filter) ([]*Record, error)
+	// Search returns the records selected by filter whose result has every
+	// term of query in its content or field values, best first.
+	Search(ctx context.Context, query string, filter Filter) ([]*Hit, error)
+}
+
+// snippetRadius is the number of bytes kept on each side of a term.
+const snippetRadius = 40
+
+// Terms splits a search query into lower case terms.
+func Terms(query string) []string {
+	return strings.FieldsFunc(strings.ToLower(query), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r) && r != '-' && r != '.' && r != '@'
+	})
+}
+
+// Vocabulary returns the distinct terms of the content and field values of
+// result, sorted. A result can only match terms that are each part of one of
+// them, so that stores can skip loading results that can't match a search.
+func Vocabulary(result *analysis.AnalyzeOperationResult) []string {
+	var vocabulary []string
+	for _, text := range texts(result) {
+		vocabulary = append(vocabulary, Terms(text)...)
+	}
+	slices.Sort(vocabulary)
+	return slices.Compact(vocabulary)
+}
+
+// MayMatch reports whether a result with vocabulary may have every term,
+// that is whether each term is part of a word of the vocabulary.
+func MayMatch(vocabulary, terms []string) bool {
+	for _, term := range terms {
+		if !slices.ContainsFunc(vocabulary, func(word string) bool { return strings.Contains(word, term) }) {
+			return false
+		}
+	}
+	return true
+}
+
+// texts returns the searchable texts of result: its content, then its field
+// values, each prefixed by its path.
+func texts(result *analysis.AnalyzeOperationResult) []string {
+	if result == nil || result.AnalyzeResult == nil {
+		return nil
+	}
+	texts := []string{result.AnalyzeResult.Content}
+	for _, doc := range result.AnalyzeResult.Documents {
+		flat := doc.Flatten()
+		for _, path := range slices.Sorted(maps.Keys(flat)) {
+			if flat[path] != nil {
+				texts = append(texts, path+": "+formatValue(flat[path]))
+			}
+		}
+	}
+	return texts
+}
+
+// Match searches the content and field values of result for terms. It
+// returns the number of occurrences and, for the first occurrence of each
+// term, the field value or an excerpt of the content around it. The score is
+// zero unless every term occurs.
+func Match(result *analysis.AnalyzeOperationResult, terms []string) (score int, snippets []string) {
+	texts := texts(result)
+	if len(texts) == 0 || len(terms) == 0 {
+		return 0, nil
+	}
+	lower := make([]string, len(texts))
+	for i, text := range texts {
+		lower[i] = strings.ToLower(text)
+	}
+
+	for _, term := range terms {
+		found := false
+		for i, text := range lower {
+			n := strings.Count(text, term)
+			if n == 0 {
+				continue
+			}
+			score += n
+			if !found {
+				// Field values are short enough to be shown whole.
+				excerpt := texts[i]
+				if i == 0 {
+					excerpt = snippet(texts[i], strings.Index(text, term), len(term))
+				}
+				snippets = append(snippets, excerpt)
+				found = true
+			}
+		}
+		if !found {
+			return 0, nil
+		}
+	}
+	return score, slices.Compact(snippets)
+}
+
+// formatValue returns a field value as text: plain values as is, and
+// structured ones, such as currencies and addresses, as JSON.
+func formatValue(v any) string {
+	switch v := v.(type) {
+	case string, float64, int64, bool:
+		return fmt.Sprint(v)
+	default:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprint(v)
+		}
+		return string(data)
+	}
+}
+
+// snippet returns the text around the match at [start, start+n), on a
+// single line. Lower casing keeps the byte offsets of the text for all but
+// a few characters, so offsets are clamped to rune boundaries.
+func snippet(text string, start, n int) string {
+	from := min(max(0, start-snippetRadius), len(text))
+	to := max(from, min(len(text), start+n+snippetRadius))
+	for from > 0 && from < len(text) && !utf8.RuneStart(text[from]) {
+		from--
+	}
+	for to < len(text) && !utf8.RuneStart(text[to]) {
+		to++
+	}
+	s := strings.Join(strings.Fields(text[from:to]), " ")
+	if from > 0 {
+		s = "…" + s
+	}
+	if to < len(text) {
+		s += "…"
+	}
+	return s
+}
@@ -0,0 +1,93 @@
+package history
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/linzhengen/azure-document-intelligence-mcp/internal/domain/analysis"
+)
+
+func invoiceResult() *analysis.AnalyzeOperationResult {
+	vendor, symbol, code := "Contoso Ltd.", "$", "USD"
+	return &analysis.AnalyzeOperationResult{
+		Status: "succeeded",
+		AnalyzeResult: &analysis.AnalyzeResult{
+			Content: "INVOICE\nContoso Ltd.\n" + strings.Repeat("Consulting services rendered. ", 5) + "Total due: $110",
+			Documents: []*analysis.Document{{
+				DocType: "invoice",
+				Fields: map[string]*analysis.DocumentField{
+					"VendorName":   {Type: analysis.FieldTypeString, ValueString: &vendor},
+					"InvoiceTotal": {Type: analysis.FieldTypeCurrency, ValueCurrency: &analysis.CurrencyValue{Amount: 110, CurrencySymbol: &symbol, CurrencyCode: &code}},
+				},
+			}},
+		},
+	}
+}
+
+func TestTerms(t *testing.T) {
+	assert.Equal(t, []string{"contoso", "invoice-42", "a@b.com"}, Terms(`Contoso, "invoice-42" a@b.com`))
+	assert.Empty(t, Terms(" ,; "))
+}
+
+func TestMatch(t *testing.T) {
+	result := invoiceResult()
+
+	score, snippets := Match(result, Terms("contoso usd"))
+	assert.Equal(t, 3, score, "twice in the content and fields, once in the currency")
+	assert.Equal(t, []string{
+		"INVOICE Contoso Ltd. Consulting services rendered. Cons…",
+		`InvoiceTotal: {"amount":110,"currencySymbol":"$","currencyCode":"USD"}`,
+	}, snippets)
+
+	score, snippets = Match(result, Terms("total"))
+	assert.Equal(t, 2, score)
+	assert.Equal(t, "…rendered. Consulting services rendered. Total due: $110", snippets[0])
+
+	score, snippets = Match(result, Terms("contoso fabrikam"))
+	assert.Zero(t, score, "every term must occur")
+	assert.Empty(t, snippets)
+
+	score, _ = Match(&analysis.AnalyzeOperationResult{Status: "failed"}, Terms("contoso"))
+	assert.Zero(t, score)
+}
+
+func TestSnippet_RuneBoundaries(t *testing.T) {
+	text := strings.Repeat("é", 30) + "needle" + strings.Repeat("ü", 30)
+	s := snippet(text, strings.Index(text, "needle"), len("needle"))
+	assert.Contains(t, s, "needle")
+	assert.True(t, strings.HasPrefix(s, "…é"))
+	assert.True(t, strings.HasSuffix(s, "ü…"))
+	assert.NotPanics(t, func() { snippet("short", 10, 3) })
+}
+
+func TestFilter_Matches(t *testing.T) {
+	now := time.Now()
+	r := &Record{ModelID: "prebuilt-read", Client: "claude", Time: now}
+
+	assert.True(t, Filter{}.Matches(r))
+	assert.True(t, Filter{ModelID: "prebuilt-read", Client: "claude", Since: now}.Matches(r))
+	assert.False(t, Filter{ModelID: "prebuilt-layout"}.Matches(r))
+	assert.False(t, Filter{Client: "other"}.Matches(r))
+	assert.False(t, Filter{Since: now.Add(time.Second)}.Matches(r))
+	assert.True(t, Filter{Scoped: true}.Matches(r), "stdio sessions have no ID")
+	assert.False(t, Filter{Scoped: true, Session: "s1"}.Matches(r))
+}
+
+func TestVocabulary(t *testing.T) {
+	result := invoiceResult()
+	vocabulary := Vocabulary(result)
+	assert.Contains(t, vocabulary, "contoso")
+	assert.Contains(t, vocabulary, "vendorname")
+
+	// Every query matching the result may match its vocabulary.
+	for _, query := range []string{"contoso usd", "total", "ltd.", "consult ices", "110"} {
+		score, _ := Match(result, Terms(query))
+		assert.Positive(t, score, query)
+		assert.True(t, MayMatch(vocabulary, Terms(query)), query)
+	}
+	assert.False(t, MayMatch(vocabulary, Terms("contoso fabrikam")))
+	assert.Empty(t, Vocabulary(&analysis.AnalyzeOperationResult{Status: "failed"}))
+}
@@ -0,0 +1,204 @@
+// Package pii detects personally identifiable information, such as email
+// addresses, phone numbers and payment card numbers, in extracted text.
+package pii
+
+import (
+	"cmp"
+	"fmt"
+	"math/big"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// Categories of the built-in detectors.
+const (
+	CategoryEmail      = "EMAIL"
+	CategoryPhone      = "PHONE"
+	CategoryCreditCard = "CREDIT_CARD"
+	CategoryIBAN       = "IBAN"
+	CategorySSN        = "SSN"
+)
+
+// Match is a piece of PII found in a text, as byte offsets.
+type Match struct {
+	Category string
+	Start    int
+	End      int
+}
+
+// Detector finds PII in text.
+type Detector interface {
+	// Detect returns the matches in text, ordered by offset and not overlapping.
+	Detect(text string) []Match
+}
+
+// pattern detects the matches of a regular expression accepted by valid.
+type pattern struct {
+	category string
+	re       *regexp.Regexp
+	valid    func(string) bool
+}
+
+func (p *pattern) Detect(text string) []Match {
+	var matches []Match
+	for _, loc := range p.re.FindAllStringIndex(text, -1) {
+		if p.valid == nil || p.valid(text[loc[0]:loc[1]]) {
+			matches = append(matches, Match{Category: p.category, Start: loc[0], End: loc[1]})
+		}
+	}
+	return matches
+}
+
+// NewPattern returns a detector reporting the matches of the regular
+// expression expr as category.
+func NewPattern(category, expr string) (Detector, error) {
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid PII pattern %q: %w", expr, err)
+	}
+	return &pattern{category: category, re: re}, nil
+}
+
+// Built-in detectors. Numbers are validated by their checksum or format rules
+// where there is one, to avoid masking unrelated figures.
+var (
+	Email = &pattern{
+		category: CategoryEmail,
+		re:       regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9\-]+(?:\.[A-Za-z0-9\-]+)*\.[A-Za-z]{2,}`),
+	}
+	Phone = &pattern{
+		category: CategoryPhone,
+		re:       regexp.MustCompile(`\+?\(?\b\d[\d ().\-]{5,}\d\b`),
+		valid:    validPhone,
+	}
+	CreditCard = &pattern{
+		category: CategoryCreditCard,
+		re:       regexp.MustCompile(`\b\d(?:[ \-]?\d){12,18}\b`),
+		valid:    func(s string) bool { return luhn(digits(s)) },
+	}
+	IBAN = &iban{re: regexp.MustCompile(`\b[A-Z]{2}\d{2}(?: ?[A-Z0-9]){11,30}\b`)}
+	SSN  = &pattern{
+		category: CategorySSN,
+		re:       regexp.MustCompile(`\b\d{3}-\d{2}-\d{4}\b`),
+		valid:    validSSN,
+	}
+)
+
+// Default returns the built-in detectors.
+func Default() Detector {
+	return Combine(Email, CreditCard, IBAN, SSN, Phone)
+}
+
+// Combine returns a detector reporting the matches of all detectors. Where
+// matches overlap, the one of the earlier detector wins.
+func Combine(detectors ...Detector) Detector {
+	return combined(detectors)
+}
+
+type combined []Detector
+
+func (c combined) Detect(text string) []Match {
+	var matches []Match
+	for _, d := range c {
+		for _, m := range d.Detect(text) {
+			overlaps := slices.ContainsFunc(matches, func(a Match) bool { return m.Start < a.End && a.Start < m.End })
+			if !overlaps {
+				matches = append(matches, m)
+			}
+		}
+	}
+	slices.SortFunc(matches, func(a, b Match) int { return cmp.Compare(a.Start, b.Start) })
+	return matches
+}
+
+// iban detects IBANs with valid check digits. Since IBANs are often printed
+// in groups of four, a match that fails the check is retried without its
+// trailing groups, which may belong to the following text.
+type iban struct {
+	re *regexp.Regexp
+}
+
+func (d *iban) Detect(text string) []Match {
+	var matches []Match
+	for _, loc := range d.re.FindAllStringIndex(text, -1) {
+		start, end := loc[0], loc[1]
+		for !validIBAN(text[start:end]) {
+			i := strings.LastIndexByte(text[start:end], ' ')
+			if i < 0 {
+				end = start
+				break
+			}
+			end = start + i
+		}
+		if end > start {
+			matches = append(matches, Match{Category: CategoryIBAN, Start: start, End: end})
+		}
+	}
+	return matches
+}
+
+func validIBAN(s string) bool {
+	s = strings.ReplaceAll(s, " ", "")
+	if len(s) < 15 || len(s) > 34 {
+		return false
+	}
+	// Move the country code and check digits to the end and map letters to
+	// numbers: the result must be 1 modulo 97.
+	var numeric strings.Builder
+	for _, r := range s[4:] + s[:4] {
+		if unicode.IsLetter(r) {
+			fmt.Fprintf(&numeric, "%d", r-'A'+10)
+		} else {
+			numeric.WriteRune(r)
+		}
+	}
+	n, ok := new(big.Int).SetString(numeric.String(), 10)
+	return ok && new(big.Int).Mod(n, big.NewInt(97)).Int64() == 1
+}
+
+// validPhone accepts 10 to 15 digits, or 7 to 15 digits for numbers in
+// international format, with at most one group in parentheses.
+func validPhone(s string) bool {
+	n := len(digits(s))
+	if strings.Count(s, "(") > 1 || strings.Count(s, "(") != strings.Count(s, ")") {
+		return false
+	}
+	if strings.HasPrefix(s, "+") {
+		return n >= 7 && n <= 15
+	}
+	return n >= 10 && n <= 15
+}
+
+// validSSN rejects the area, group and serial numbers never assigned in US
+// Social Security numbers.
+func validSSN(s string) bool {
+	area, group, serial := s[0:3], s[4:6], s[7:11]
+	return area != "000" && area != "666" && area[0] != '9' && group != "00" && serial != "0000"
+}
+
+// luhn reports whether the digits have a valid Luhn check digit.
+func luhn(digits string) bool {
+	sum := 0
+	for i := range len(digits) {
+		d := int(digits[len(digits)-1-i] - '0')
+		if i%2 == 1 {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+	}
+	return len(digits) > 0 && sum%10 == 0
+}
+
+func digits(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, s)
+}
@@ -0,0 +1,56 @@
+package pii
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// found returns the text and category of the matches of d in text.
+func found(d Detector, text string) map[string]string {
+	matches := map[string]string{}
+	for _, m := range d.Detect(text) {
+		matches[text[m.Start:m.End]] = m.Category
+	}
+	return matches
+}
+
+func TestDefault(t *testing.T) {
+	tests := []struct {
+		text string
+		want map[string]string
+	}{
+		{"Contact jane.doe+invoices@mail.contoso.co.uk today.", map[string]string{"jane.doe+invoices@mail.contoso.co.uk": CategoryEmail}},
+		{"Call (425) 555-0100 or +44 20 7946 0958.", map[string]string{"(425) 555-0100": CategoryPhone, "+44 20 7946 0958": CategoryPhone}},
+		{"Card 4111 1111 1111 1111, exp 12/30", map[string]string{"4111 1111 1111 1111": CategoryCreditCard}},
+		{"Card 4111-1111-1111-1112 fails the Luhn check", map[string]string{}},
+		{"IBAN: DE89 3704 0044 0532 0130 00 BIC COBADEFFXXX", map[string]string{"DE89 3704 0044 0532 0130 00": CategoryIBAN}},
+		{"Account GB82WEST12345698765432.", map[string]string{"GB82WEST12345698765432": CategoryIBAN}},
+		{"Not an IBAN: GB00WEST12345698765432", map[string]string{}},
+		{"SSN 078-05-1120; invalid 666-12-3456, 123-00-4567", map[string]string{"078-05-1120": CategorySSN}},
+		{"Invoice 2024-01-15, total 1,234.56, PO 12345", map[string]string{}},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, found(Default(), tt.text), tt.text)
+	}
+}
+
+func TestCombine_Overlaps(t *testing.T) {
+	employee, err := NewPattern("EMPLOYEE_ID", `EMP-\d{6}`)
+	require.NoError(t, err)
+	digits, err := NewPattern("DIGITS", `\d{6}`)
+	require.NoError(t, err)
+
+	matches := Combine(employee, digits).Detect("EMP-123456 and 654321")
+
+	assert.Equal(t, []Match{
+		{Category: "EMPLOYEE_ID", Start: 0, End: 10},
+		{Category: "DIGITS", Start: 15, End: 21},
+	}, matches)
+}
+
+func TestNewPattern_Invalid(t *testing.T) {
+	_, err := NewPattern("BROKEN", "(")
+	assert.ErrorContains(t, err, "invalid PII pattern")
+}
@@ -0,0 +1,116 @@
+// Package usage accounts for the pages analyzed by the service, which bills
+// per page and model, and enforces page budgets.
+package usage
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+)
+
+// Entry records the pages of one completed analysis.
+type Entry struct {
+	Time    time.Time `json:"time"`
+	ModelID string    `json:"modelId"`
+	Client  string    `json:"client,omitempty"`
+	Session string    `json:"session,omitempty"`
+	Pages   int       `json:"pages"`
+}
+
+// Ledger stores usage entries.
+type Ledger interface {
+	// Append records an entry.
+	Append(ctx context.Context, entry Entry) error
+	// Entries returns the entries recorded at or after since, oldest first.
+	Entries(ctx context.Context, since time.Time) ([]Entry, error)
+}
+
+// Budget limits the pages analyzed. A zero limit disables the check.
+type Budget struct {
+	// Daily limits the pages analyzed per UTC day.
+	Daily int
+	// Monthly limits the pages analyzed per UTC calendar month.
+	Monthly int
+	// MaxPagesPerRequest limits the pages of a single analysis.
+	MaxPagesPerRequest int
+}
+
+// ExceededError reports a request that would exceed a budget.
+type ExceededError struct {
+	Period    string // "daily", "monthly" or "per-request"
+	Limit     int
+	Used      int
+	Requested int
+}
+
+func (e *ExceededError) Error() string {
+	if e.Period == "per-request" {
+		return fmt.Sprintf("page budget exceeded: request selects %d pages, but at most %d pages are allowed per request", e.Requested, e.Limit)
+	}
+	return fmt.Sprintf("page budget exceeded: %d of %d %s pages used, request needs at least %d more", e.Used, e.Limit, e.Period, e.Requested)
+}
+
+// Check returns an *ExceededError if analyzing requested more pages, on top of
+// the pages used today and this month, would exceed the budget.
+func (b Budget) Check(usedToday, usedThisMonth, requested int) error {
+	if b.MaxPagesPerRequest > 0 && requested > b.MaxPagesPerRequest {
+		return &ExceededError{Period: "per-request", Limit: b.MaxPagesPerRequest, Requested: requested}
+	}
+	if b.Daily > 0 && usedToday+requested > b.Daily {
+		return &ExceededError{Period: "daily", Limit: b.Daily, Used: usedToday, Requested: requested}
+	}
+	if b.Monthly > 0 && usedThisMonth+requested > b.Monthly {
+		return &ExceededError{Period: "monthly", Limit: b.Monthly, Used: usedThisMonth, Requested: requested}
+	}
+	return nil
+}
+
+// Day returns the start of the UTC day of t.
+func Day(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// Month returns the start of the UTC calendar month of t.
+func Month(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+// Summary aggregates the pages of entries.
+type Summary struct {
+	Pages     int            `json:"pages" jsonschema:"Total pages analyzed."`
+	ByModel   map[string]int `json:"byModel,omitempty" jsonschema:"Pages analyzed per model."`
+	ByClient  map[string]int `json:"byClient,omitempty" jsonschema:"Pages analyzed per MCP client."`
+	BySession map[string]int `json:"bySession,omitempty" jsonschema:"Pages analyzed per MCP session, which is only identified over HTTP."`
+	ByDay     []DayUsage     `json:"byDay,omitempty" jsonschema:"Pages analyzed per UTC day, oldest first."`
+}
+
+// DayUsage is the number of pages analyzed on a UTC day.
+type DayUsage struct {
+	Date  string `json:"date" jsonschema:"UTC date as YYYY-MM-DD."`
+	Pages int    `json:"pages"`
+}
+
+// Summarize aggregates the pages of entries.
+func Summarize(entries []Entry) *Summary {
+	s := &Summary{ByModel: make(map[string]int), ByClient: make(map[string]int), BySession: make(map[string]int)}
+	days := make(map[string]int)
+	for _, e := range entries {
+		s.Pages += e.Pages
+		s.ByModel[e.ModelID] += e.Pages
+		if e.Client != "" {
+			s.ByClient[e.Client] += e.Pages
+		}
+		if e.Session != "" {
+			s.BySession[e.Session] += e.Pages
+		}
+		days[e.Time.UTC().Format(time.DateOnly)] += e.Pages
+	}
+	for _, date := range slices.Sorted(maps.Keys(days)) {
+		s.ByDay = append(s.ByDay, DayUsage{Date: date, Pages: days[date]})
+	}
+	return s
+}
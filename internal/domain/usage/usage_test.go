@@ -0,0 +1,57 @@
+package usage
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBudget_Check(t *testing.T) {
+	budget := Budget{Daily: 10, Monthly: 100, MaxPagesPerRequest: 5}
+
+	assert.NoError(t, budget.Check(5, 50, 5))
+	assert.NoError(t, Budget{}.Check(1000, 1000, 1000))
+
+	tests := []struct {
+		name                    string
+		today, month, requested int
+		period                  string
+	}{
+		{"per request", 0, 0, 6, "per-request"},
+		{"daily", 8, 8, 3, "daily"},
+		{"monthly", 0, 99, 2, "monthly"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := budget.Check(tt.today, tt.month, tt.requested)
+			var exceeded *ExceededError
+			require.True(t, errors.As(err, &exceeded))
+			assert.Equal(t, tt.period, exceeded.Period)
+		})
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	day1 := time.Date(2024, 5, 1, 23, 0, 0, 0, time.UTC)
+	day2 := day1.Add(2 * time.Hour)
+	s := Summarize([]Entry{
+		{Time: day1, ModelID: "prebuilt-read", Client: "claude", Session: "s1", Pages: 3},
+		{Time: day2, ModelID: "prebuilt-invoice", Client: "claude", Session: "s2", Pages: 2},
+		{Time: day2, ModelID: "prebuilt-read", Pages: 1},
+	})
+
+	assert.Equal(t, 6, s.Pages)
+	assert.Equal(t, map[string]int{"prebuilt-read": 4, "prebuilt-invoice": 2}, s.ByModel)
+	assert.Equal(t, map[string]int{"claude": 5}, s.ByClient)
+	assert.Equal(t, map[string]int{"s1": 3, "s2": 2}, s.BySession)
+	assert.Equal(t, []DayUsage{{"2024-05-01", 3}, {"2024-05-02", 3}}, s.ByDay)
+}
+
+func TestDayAndMonth(t *testing.T) {
+	ts := time.Date(2024, 5, 17, 13, 4, 5, 0, time.FixedZone("JST", 9*3600))
+	assert.Equal(t, time.Date(2024, 5, 17, 0, 0, 0, 0, time.UTC), Day(ts))
+	assert.Equal(t, time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC), Month(ts))
+}
@@ -0,0 +1,244 @@
+// Package cassette provides an HTTP client that records Document Intelligence
+// interactions to a file and replays them deterministically, so that tests
+// run without network access. Cassettes recorded against a real resource
+// exercise the responses of the service; those recorded from the fake
+// backend only exercise the client.
+package cassette
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// RecordedHost replaces the service host in recorded URLs so that cassettes
+// don't reveal the name of the resource they were recorded against.
+const RecordedHost = "recorded.cognitiveservices.azure.com"
+
+// sensitiveHeaders are never written to a cassette.
+var sensitiveHeaders = []string{
+	"Ocp-Apim-Subscription-Key",
+	"Authorization",
+	"Set-Cookie",
+	"Cookie",
+}
+
+// Doer sends HTTP requests. It's implemented by *http.Client.
+type Doer interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// Request identifies a recorded request. The body is stored only as a hash.
+type Request struct {
+	Method   string `json:"method"`
+	Path     string `json:"path"`
+	Query    string `json:"query,omitempty"`
+	BodyHash string `json:"bodyHash"`
+}
+
+// Response is a recorded response.
+type Response struct {
+	StatusCode int         `json:"statusCode"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       string      `json:"body,omitempty"`
+}
+
+// Interaction is a request and the response it received.
+type Interaction struct {
+	Request  Request  `json:"request"`
+	Response Response `json:"response"`
+}
+
+// Cassette is the file format of recorded interactions.
+type Cassette struct {
+	Interactions []*Interaction `json:"interactions"`
+}
+
+// Mode selects whether a client records or replays interactions.
+type Mode string
+
+const (
+	// ModeReplay serves recorded interactions without network access.
+	ModeReplay Mode = "replay"
+	// ModeRecord sends requests to the service and records the interactions.
+	ModeRecord Mode = "record"
+)
+
+// Client records or replays interactions. It implements the HTTPClient
+// interface of the analysis repository.
+type Client struct {
+	path   string
+	next   Doer // nil when replaying
+	mu     sync.Mutex
+	tape   Cassette
+	played []bool
+}
+
+// New returns a recorder for ModeRecord and a replayer otherwise, so that an
+// unset mode (for example from an empty environment variable) replays.
+func New(path string, mode Mode, next Doer) (*Client, error) {
+	if mode == ModeRecord {
+		return NewRecorder(path, next), nil
+	}
+	return NewReplayer(path)
+}
+
+// NewRecorder returns a client that sends requests with next and appends the
+// sanitized interactions to the cassette file at path, replacing its content.
+func NewRecorder(path string, next Doer) *Client {
+	return &Client{path: path, next: next}
+}
+
+// NewReplayer returns a client that serves the interactions recorded in the
+// cassette file at path without sending any request.
+func NewReplayer(path string) (*Client, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cassette: %w", err)
+	}
+	c := &Client{path: path}
+	if err := json.Unmarshal(data, &c.tape); err != nil {
+		return nil, fmt.Errorf("failed to decode cassette %s: %w", path, err)
+	}
+	c.played = make([]bool, len(c.tape.Interactions))
+	return c, nil
+}
+
+// Do records or replays the request.
+func (c *Client) Do(req *http.Request) (*http.Response, error) {
+	key, err := requestKey(req)
+	if err != nil {
+		return nil, err
+	}
+	if c.next == nil {
+		return c.replay(req, key)
+	}
+	return c.record(req, key)
+}
+
+// replay serves the first unplayed interaction matching the request, so that
+// repeated polling requests get their responses in recorded order.
+func (c *Client) replay(req *http.Request, key Request) (*http.Response, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, in := range c.tape.Interactions {
+		if c.played[i] || in.Request != key {
+			continue
+		}
+		c.played[i] = true
+		return newResponse(req, in.Response), nil
+	}
+	return nil, fmt.Errorf("cassette %s: no recorded response for %s %s?%s", c.path, key.Method, key.Path, key.Query)
+}
+
+func (c *Client) record(req *http.Request, key Request) (*http.Response, error) {
+	resp, err := c.next.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	body, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	recorded := Response{StatusCode: resp.StatusCode, Header: sanitizeHeader(resp.Header), Body: string(body)}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.tape.Interactions = append(c.tape.Interactions, &Interaction{Request: key, Response: recorded})
+	if err := c.save(); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (c *Client) save() error {
+	data, err := json.MarshalIndent(&c.tape, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode cassette: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0o755); err != nil {
+		return fmt.Errorf("failed to create cassette directory: %w", err)
+	}
+	if err := os.WriteFile(c.path, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write cassette: %w", err)
+	}
+	return nil
+}
+
+// requestKey reads the request body, leaving it readable for the next client.
+func requestKey(req *http.Request) (Request, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		_ = req.Body.Close()
+		if err != nil {
+			return Request{}, fmt.Errorf("failed to read request body: %w", err)
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	hash := sha256.Sum256(body)
+	return Request{
+		Method:   req.Method,
+		Path:     req.URL.Path,
+		Query:    req.URL.Query().Encode(),
+		BodyHash: hex.EncodeToString(hash[:]),
+	}, nil
+}
+
+// sanitizeHeader drops credentials and cookies, and hides the service host in
+// the URLs returned by the service.
+func sanitizeHeader(h http.Header) http.Header {
+	h = h.Clone()
+	for _, name := range sensitiveHeaders {
+		h.Del(name)
+	}
+	for _, name := range []string{"Operation-Location", "Location"} {
+		if v := h.Get(name); v != "" {
+			if u, err := url.Parse(v); err == nil {
+				u.Host = RecordedHost
+				u.Scheme = "https"
+				h.Set(name, u.String())
+			}
+		}
+	}
+	return h
+}
+
+// newResponse builds a response from the recording. URLs pointing at the
+// recorded host are rewritten to the host of the request being served.
+func newResponse(req *http.Request, recorded Response) *http.Response {
+	header := recorded.Header.Clone()
+	if header == nil {
+		header = http.Header{}
+	}
+	for _, name := range []string{"Operation-Location", "Location"} {
+		if v := header.Get(name); v != "" {
+			if u, err := url.Parse(v); err == nil && u.Host == RecordedHost {
+				u.Scheme, u.Host = req.URL.Scheme, req.URL.Host
+				header.Set(name, u.String())
+			}
+		}
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", recorded.StatusCode, http.StatusText(recorded.StatusCode)),
+		StatusCode:    recorded.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader([]byte(recorded.Body))),
+		ContentLength: int64(len(recorded.Body)),
+		Request:       req,
+	}
+}
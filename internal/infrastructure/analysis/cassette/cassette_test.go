@@ -0,0 +1,112 @@
+package cassette
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/linzhengen/azure-document-intelligence-mcp/internal/infrastructure/analysis/fake"
+)
+
+func newRequest(t *testing.T, method, url, body string) *http.Request {
+	t.Helper()
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	require.NoError(t, err)
+	req.Header.Set("Ocp-Apim-Subscription-Key", "secret-key")
+	req.Header.Set("Content-Type", "application/json")
+	return req
+}
+
+func readBody(t *testing.T, resp *http.Response) string {
+	t.Helper()
+	defer func() { _ = resp.Body.Close() }()
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return string(body)
+}
+
+func TestClient_RecordAndReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	server := fake.NewServer("secret-key")
+	server.SetScenario("prebuilt-read", fake.Scenario{Polls: 1})
+	analyzeURL := server.URL + "/documentintelligence/documentModels/prebuilt-read:analyze?api-version=2024-11-30"
+	body := `{"urlSource":"https://example.com/doc.pdf?sig=abc"}`
+
+	// Record.
+	recorder := NewRecorder(path, server.Client())
+	resp, err := recorder.Do(newRequest(t, http.MethodPost, analyzeURL, body))
+	require.NoError(t, err)
+	require.Equal(t, http.StatusAccepted, resp.StatusCode)
+	location := resp.Header.Get("Operation-Location")
+	_ = readBody(t, resp)
+	var recorded []string
+	for range 2 {
+		resp, err := recorder.Do(newRequest(t, http.MethodGet, location, ""))
+		require.NoError(t, err)
+		recorded = append(recorded, readBody(t, resp))
+	}
+	server.Close()
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "secret-key")
+	assert.NotContains(t, string(data), "sig=abc")
+	assert.NotContains(t, string(data), strings.TrimPrefix(server.URL, "http://"))
+
+	// Replay against another host, without the server.
+	replayer, err := NewReplayer(path)
+	require.NoError(t, err)
+	endpoint := "https://" + RecordedHost
+	resp, err = replayer.Do(newRequest(t, http.MethodPost, endpoint+"/documentintelligence/documentModels/prebuilt-read:analyze?api-version=2024-11-30", body))
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusAccepted, resp.StatusCode)
+	replayedLocation := resp.Header.Get("Operation-Location")
+	assert.True(t, strings.HasPrefix(replayedLocation, endpoint+"/"), replayedLocation)
+
+	for i := range 2 {
+		resp, err := replayer.Do(newRequest(t, http.MethodGet, replayedLocation, ""))
+		require.NoError(t, err)
+		assert.Equal(t, recorded[i], readBody(t, resp))
+	}
+
+	// Every interaction is served once.
+	_, err = replayer.Do(newRequest(t, http.MethodGet, replayedLocation, ""))
+	assert.Error(t, err)
+}
+
+func TestClient_ReplayMatchesBody(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	recorder := NewRecorder(path, doerFunc(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: http.StatusAccepted, Header: http.Header{}, Body: io.NopCloser(bytes.NewReader(nil))}, nil
+	}))
+	_, err := recorder.Do(newRequest(t, http.MethodPost, "https://example.com/analyze?api-version=1", `{"urlSource":"a"}`))
+	require.NoError(t, err)
+
+	replayer, err := New(path, ModeReplay, nil)
+	require.NoError(t, err)
+
+	_, err = replayer.Do(newRequest(t, http.MethodPost, "https://example.com/analyze?api-version=1", `{"urlSource":"b"}`))
+	assert.Error(t, err)
+	_, err = replayer.Do(newRequest(t, http.MethodPost, "https://example.com/analyze?api-version=2", `{"urlSource":"a"}`))
+	assert.Error(t, err)
+	resp, err := replayer.Do(newRequest(t, http.MethodPost, "https://example.com/analyze?api-version=1", `{"urlSource":"a"}`))
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusAccepted, resp.StatusCode)
+}
+
+func TestNew_MissingCassette(t *testing.T) {
+	_, err := New(filepath.Join(t.TempDir(), "missing.json"), "", nil)
+
+	assert.Error(t, err)
+}
+
+type doerFunc func(req *http.Request) (*http.Response, error)
+
+func (f doerFunc) Do(req *http.Request) (*http.Response, error) { return f(req) }
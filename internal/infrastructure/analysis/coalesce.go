@@ -0,0 +1,128 @@
+package analysis
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+
+	"github.com/linzhengen/azure-document-intelligence-mcp/internal/domain/analysis"
+	"github.com/linzhengen/azure-document-intelligence-mcp/internal/logging"
+)
+
+// Coalesce returns a repository that shares one analysis of next among
+// concurrent identical calls: same model, document URL or content, content
+// type, pages and region. onShared, if not nil, is called for every call
+// answered by an analysis already in progress.
+//
+// The first caller to receive the result gets it as returned by next; the
+// others get a copy with Source set to analysis.SourceShared, so that the
+// analysis is only billed once.
+//
+// The shared analysis runs until it completes or every caller waiting for it
+// has given up; a caller whose context is done stops waiting without
+// canceling it for the others.
+func Coalesce(next analysis.Repository, onShared func(modelID string)) analysis.Repository {
+	return &coalescer{next: next, onShared: onShared, calls: map[string]*sharedCall{}}
+}
+
+type sharedCall struct {
+	done   chan struct{}
+	result *analysis.AnalyzeOperationResult
+	err    error
+	cancel context.CancelFunc
+
+	// Guarded by coalescer.mu.
+	waiters int
+	claimed bool
+}
+
+type coalescer struct {
+	next     analysis.Repository
+	onShared func(modelID string)
+
+	mu    sync.Mutex
+	calls map[string]*sharedCall
+}
+
+func (c *coalescer) AnalyzeDocument(ctx context.Context, modelID string, options analysis.AnalyzeDocumentOptions) (*analysis.AnalyzeOperationResult, error) {
+	key := coalesceKey(modelID, options)
+
+	c.mu.Lock()
+	call, shared := c.calls[key]
+	if !shared {
+		// The analysis keeps the values of the first caller's context, such
+		// as its logger and trace, but not its cancellation.
+		callCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+		call = &sharedCall{done: make(chan struct{}), cancel: cancel}
+		c.calls[key] = call
+		go c.run(callCtx, key, call, modelID, options)
+	}
+	call.waiters++
+	c.mu.Unlock()
+
+	if shared {
+		logging.FromContext(ctx).InfoContext(ctx, "joined identical analysis in progress", "modelId", modelID)
+		if c.onShared != nil {
+			c.onShared(modelID)
+		}
+	}
+
+	select {
+	case <-call.done:
+		return c.receive(call)
+	case <-ctx.Done():
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		call.waiters--
+		if call.waiters == 0 {
+			call.cancel()
+			c.forget(key, call)
+		}
+		return nil, ctx.Err()
+	}
+}
+
+func (c *coalescer) run(ctx context.Context, key string, call *sharedCall, modelID string, options analysis.AnalyzeDocumentOptions) {
+	defer call.cancel()
+	call.result, call.err = c.next.AnalyzeDocument(ctx, modelID, options)
+	c.mu.Lock()
+	c.forget(key, call)
+	c.mu.Unlock()
+	close(call.done)
+}
+
+// receive returns the result of call, marked as shared unless it's the first
+// time it's returned.
+func (c *coalescer) receive(call *sharedCall) (*analysis.AnalyzeOperationResult, error) {
+	if call.err != nil || call.result == nil {
+		return call.result, call.err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !call.claimed {
+		call.claimed = true
+		return call.result, nil
+	}
+	shared := *call.result
+	shared.Source = analysis.SourceShared
+	return &shared, nil
+}
+
+// forget stops new calls from joining call. c.mu must be held.
+func (c *coalescer) forget(key string, call *sharedCall) {
+	if c.calls[key] == call {
+		delete(c.calls, key)
+	}
+}
+
+// coalesceKey identifies the analyses giving the same result.
+func coalesceKey(modelID string, options analysis.AnalyzeDocumentOptions) string {
+	h := sha256.New()
+	for _, s := range []string{modelID, options.DocURL, options.ContentType, options.Pages, options.Region} {
+		h.Write([]byte(s))
+		h.Write([]byte{0})
+	}
+	h.Write(options.Content)
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,124 @@
+package analysis
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/linzhengen/azure-document-intelligence-mcp/internal/domain/analysis"
+)
+
+// blockingRepository counts its analyses, which complete once release is closed.
+type blockingRepository struct {
+	release chan struct{}
+	calls   atomic.Int32
+	ctxErr  chan error
+}
+
+func newBlockingRepository() *blockingRepository {
+	return &blockingRepository{release: make(chan struct{}), ctxErr: make(chan error, 10)}
+}
+
+func (r *blockingRepository) AnalyzeDocument(ctx context.Context, modelID string, options analysis.AnalyzeDocumentOptions) (*analysis.AnalyzeOperationResult, error) {
+	r.calls.Add(1)
+	select {
+	case <-r.release:
+		return &analysis.AnalyzeOperationResult{Status: "succeeded"}, nil
+	case <-ctx.Done():
+		r.ctxErr <- ctx.Err()
+		return nil, ctx.Err()
+	}
+}
+
+func TestCoalesce_SharesIdenticalAnalyses(t *testing.T) {
+	next := newBlockingRepository()
+	var shared []string
+	var mu sync.Mutex
+	repo := Coalesce(next, func(modelID string) {
+		mu.Lock()
+		shared = append(shared, modelID)
+		mu.Unlock()
+	})
+	content := analysis.AnalyzeDocumentOptions{Content: []byte("%PDF-1.7"), ContentType: "application/pdf"}
+	otherPages := content
+	otherPages.Pages = "1"
+
+	var wg sync.WaitGroup
+	results := make([]*analysis.AnalyzeOperationResult, 4)
+	for i, options := range []analysis.AnalyzeDocumentOptions{content, content, content, otherPages} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result, err := repo.AnalyzeDocument(context.Background(), "prebuilt-read", options)
+			assert.NoError(t, err)
+			results[i] = result
+		}()
+	}
+	require.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(shared) == 2
+	}, time.Second, time.Millisecond)
+	close(next.release)
+	wg.Wait()
+
+	assert.Equal(t, int32(2), next.calls.Load(), "one analysis per distinct request")
+	var sources []string
+	for _, result := range results[:3] {
+		assert.Equal(t, "succeeded", result.Status)
+		sources = append(sources, result.Source)
+	}
+	assert.ElementsMatch(t, []string{"", analysis.SourceShared, analysis.SourceShared}, sources, "only the first caller to receive the result isn't marked as shared")
+	assert.Empty(t, results[3].Source)
+	assert.Equal(t, []string{"prebuilt-read", "prebuilt-read"}, shared)
+
+	// Completed analyses are not reused.
+	_, err := repo.AnalyzeDocument(context.Background(), "prebuilt-read", content)
+	require.NoError(t, err)
+	assert.Equal(t, int32(3), next.calls.Load())
+}
+
+func TestCoalesce_CallerCancellation(t *testing.T) {
+	next := newBlockingRepository()
+	repo := Coalesce(next, nil)
+
+	first, cancelFirst := context.WithCancel(context.Background())
+	firstErr := make(chan error)
+	go func() {
+		_, err := repo.AnalyzeDocument(first, "prebuilt-read", urlOptions)
+		firstErr <- err
+	}()
+	require.Eventually(t, func() bool { return next.calls.Load() == 1 }, time.Second, time.Millisecond)
+	second, cancelSecond := context.WithCancel(context.Background())
+	secondErr := make(chan error)
+	go func() {
+		_, err := repo.AnalyzeDocument(second, "prebuilt-read", urlOptions)
+		secondErr <- err
+	}()
+	require.Eventually(t, func() bool {
+		c := repo.(*coalescer)
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		for _, call := range c.calls {
+			return call.waiters == 2
+		}
+		return false
+	}, time.Second, time.Millisecond)
+
+	cancelFirst()
+	assert.ErrorIs(t, <-firstErr, context.Canceled)
+	select {
+	case err := <-next.ctxErr:
+		t.Fatalf("the shared analysis stopped with %v while a caller waits for it", err)
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	cancelSecond()
+	assert.ErrorIs(t, <-secondErr, context.Canceled)
+	assert.ErrorIs(t, <-next.ctxErr, context.Canceled, "the analysis stops once nobody waits for it")
+}
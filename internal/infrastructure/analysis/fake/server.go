@@ -0,0 +1,342 @@
+// Package fake provides an in-process fake of the Azure Document Intelligence
+// REST API for tests and offline demos.
+//
+// The fake emulates the analyze lifecycle (POST returning 202 with an
+// Operation-Location header, followed by polling GETs), model listing, errors
+// and throttling. Responses are scripted per model with a Scenario, and
+// results come from JSON fixtures.
+package fake
+
+import (
+	"crypto/rand"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/linzhengen/azure-document-intelligence-mcp/internal/domain/analysis"
+)
+
+const modelsPath = "/documentintelligence/documentModels"
+
+//go:embed fixtures/*.json
+var defaultFixtures embed.FS
+
+// Scenario scripts how the fake responds to analyze requests for a model.
+type Scenario struct {
+	// Throttle is the number of analyze requests answered with 429 Too Many
+	// Requests before one is accepted.
+	Throttle int
+	// RetryAfter is the value of the Retry-After header of throttled responses, in seconds.
+	RetryAfter int
+	// InitiateStatus, if non-zero, is the status code returned for analyze
+	// requests instead of accepting them.
+	InitiateStatus int
+	// Polls is the number of polling requests answered with "running" before
+	// the operation completes.
+	Polls int
+	// Failure, if non-nil, completes the operation with status "failed" and this error.
+	Failure *analysis.Error
+	// Result, if non-nil, is returned instead of the fixture of the model.
+	Result *analysis.AnalyzeResult
+}
+
+// Request is an analyze request received by the fake.
+type Request struct {
+	ModelID     string
+	ContentType string
+	URLSource   string
+	Content     []byte
+	Query       string
+}
+
+type operation struct {
+	created  time.Time
+	polls    int
+	scenario Scenario
+	result   *analysis.AnalyzeResult
+}
+
+// Server is a fake Document Intelligence service listening on a local address.
+type Server struct {
+	*httptest.Server
+	apiKey string
+
+	mu         sync.Mutex
+	fixtures   map[string]*analysis.AnalyzeResult
+	scenarios  map[string]Scenario
+	throttled  map[string]int
+	operations map[string]*operation
+	requests   []Request
+}
+
+// NewServer starts a fake service that accepts apiKey and serves the built-in
+// fixtures for prebuilt-read, prebuilt-layout, prebuilt-invoice and
+// prebuilt-contract. The caller must call Close when done.
+func NewServer(apiKey string) *Server {
+	s := &Server{
+		apiKey:     apiKey,
+		fixtures:   make(map[string]*analysis.AnalyzeResult),
+		scenarios:  make(map[string]Scenario),
+		throttled:  make(map[string]int),
+		operations: make(map[string]*operation),
+	}
+	if err := s.loadFixtures(defaultFixtures, "fixtures"); err != nil {
+		panic(fmt.Sprintf("fake: invalid built-in fixtures: %v", err))
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+// LoadFixtures loads results from the JSON files in dir. Each file is named
+// after the model it is returned for (for example prebuilt-read.json) and
+// holds either an analyzeResult or a whole operation result.
+func (s *Server) LoadFixtures(dir string) error {
+	return s.loadFixtures(os.DirFS(dir), ".")
+}
+
+func (s *Server) loadFixtures(fsys fs.FS, dir string) error {
+	files, err := fs.Glob(fsys, filepath.ToSlash(filepath.Join(dir, "*.json")))
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		data, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return fmt.Errorf("failed to read fixture %s: %w", file, err)
+		}
+		result, err := decodeFixture(data)
+		if err != nil {
+			return fmt.Errorf("failed to decode fixture %s: %w", file, err)
+		}
+		s.SetResult(strings.TrimSuffix(filepath.Base(file), ".json"), result)
+	}
+	return nil
+}
+
+func decodeFixture(data []byte) (*analysis.AnalyzeResult, error) {
+	var op analysis.AnalyzeOperationResult
+	if err := json.Unmarshal(data, &op); err == nil && op.AnalyzeResult != nil {
+		return op.AnalyzeResult, nil
+	}
+	var result analysis.AnalyzeResult
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// SetResult sets the result returned for analyses with the model.
+func (s *Server) SetResult(modelID string, result *analysis.AnalyzeResult) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.fixtures[modelID] = result
+}
+
+// SetScenario scripts the responses for analyses with the model.
+func (s *Server) SetScenario(modelID string, scenario Scenario) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.scenarios[modelID] = scenario
+	delete(s.throttled, modelID)
+}
+
+// Requests returns the analyze requests received so far.
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.requests)
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Ocp-Apim-Subscription-Key") != s.apiKey {
+		writeError(w, http.StatusUnauthorized, "401", "Access denied due to invalid subscription key or wrong API endpoint.")
+		return
+	}
+	if r.URL.Query().Get("api-version") == "" {
+		writeError(w, http.StatusNotFound, "404", "Resource not found.")
+		return
+	}
+
+	path := strings.TrimPrefix(r.URL.Path, modelsPath)
+	switch {
+	case path == r.URL.Path:
+		writeError(w, http.StatusNotFound, "404", "Resource not found.")
+	case path == "" && r.Method == http.MethodGet:
+		s.listModels(w)
+	case strings.HasSuffix(path, ":analyze") && r.Method == http.MethodPost:
+		s.analyze(w, r, strings.TrimSuffix(strings.TrimPrefix(path, "/"), ":analyze"))
+	case strings.Contains(path, "/analyzeResults/") && r.Method == http.MethodGet:
+		s.getResult(w, path[strings.LastIndex(path, "/")+1:])
+	case strings.Count(path, "/") == 1 && r.Method == http.MethodGet:
+		s.getModel(w, strings.TrimPrefix(path, "/"))
+	default:
+		writeError(w, http.StatusNotFound, "404", "Resource not found.")
+	}
+}
+
+func (s *Server) listModels(w http.ResponseWriter) {
+	s.mu.Lock()
+	ids := make([]string, 0, len(s.fixtures))
+	for id := range s.fixtures {
+		ids = append(ids, id)
+	}
+	s.mu.Unlock()
+	slices.Sort(ids)
+
+	models := make([]map[string]string, len(ids))
+	for i, id := range ids {
+		models[i] = modelInfo(id)
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"value": models})
+}
+
+func (s *Server) getModel(w http.ResponseWriter, modelID string) {
+	s.mu.Lock()
+	_, ok := s.fixtures[modelID]
+	s.mu.Unlock()
+	if !ok {
+		writeError(w, http.StatusNotFound, "ModelNotFound", fmt.Sprintf("The requested model %s was not found.", modelID))
+		return
+	}
+	writeJSON(w, http.StatusOK, modelInfo(modelID))
+}
+
+func modelInfo(modelID string) map[string]string {
+	return map[string]string{
+		"modelId":         modelID,
+		"description":     "Fake " + modelID + " model.",
+		"createdDateTime": "2024-11-30T00:00:00Z",
+		"apiVersion":      "2024-11-30",
+	}
+}
+
+func (s *Server) analyze(w http.ResponseWriter, r *http.Request, modelID string) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "InvalidRequest", "Failed to read request body.")
+		return
+	}
+	req := Request{ModelID: modelID, ContentType: r.Header.Get("Content-Type"), Query: r.URL.RawQuery}
+	if req.ContentType == "application/json" {
+		var source analysis.AnalyzeDocumentRequest
+		if err := json.Unmarshal(body, &source); err != nil {
+			writeError(w, http.StatusBadRequest, "InvalidRequest", "Invalid request body.")
+			return
+		}
+		if source.URLSource != nil {
+			req.URLSource = *source.URLSource
+		}
+		req.Content = source.Base64Source
+	} else {
+		req.Content = body
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests = append(s.requests, req)
+
+	fixture, ok := s.fixtures[modelID]
+	if !ok {
+		writeError(w, http.StatusNotFound, "ModelNotFound", fmt.Sprintf("The requested model %s was not found.", modelID))
+		return
+	}
+	if req.URLSource == "" && len(req.Content) == 0 {
+		writeError(w, http.StatusBadRequest, "InvalidRequest", "Invalid request.")
+		return
+	}
+	scenario := s.scenarios[modelID]
+	if s.throttled[modelID] < scenario.Throttle {
+		s.throttled[modelID]++
+		w.Header().Set("Retry-After", strconv.Itoa(scenario.RetryAfter))
+		writeError(w, http.StatusTooManyRequests, "429", "Requests to the analyze operation have exceeded rate limit of your current pricing tier. Please retry after some time.")
+		return
+	}
+	if scenario.InitiateStatus != 0 {
+		writeError(w, scenario.InitiateStatus, "InternalServerError", "An unexpected error occurred.")
+		return
+	}
+
+	result := scenario.Result
+	if result == nil {
+		result = fixture
+	}
+	if pages := r.URL.Query().Get("pages"); pages != "" {
+		numbers, err := analysis.PageNumbers(pages, len(result.Pages))
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "InvalidArgument", "Invalid argument: pages.")
+			return
+		}
+		if len(numbers) == 0 {
+			writeError(w, http.StatusBadRequest, "InvalidArgument", fmt.Sprintf("The requested pages are past the end of the document of %d pages.", len(result.Pages)))
+			return
+		}
+		result = selectPages(result, numbers)
+	}
+
+	id := strings.ToLower(rand.Text())
+	s.operations[id] = &operation{created: time.Now().UTC(), scenario: scenario, result: result}
+	w.Header().Set("Operation-Location", fmt.Sprintf("%s%s/%s/analyzeResults/%s?api-version=%s", s.URL, modelsPath, modelID, id, r.URL.Query().Get("api-version")))
+	w.Header().Set("apim-request-id", id)
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (s *Server) getResult(w http.ResponseWriter, id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	op, ok := s.operations[id]
+	if !ok {
+		writeError(w, http.StatusNotFound, "NotFound", "Resource not found.")
+		return
+	}
+	op.polls++
+	result := analysis.AnalyzeOperationResult{
+		Status:              "running",
+		CreatedDateTime:     op.created.Format(time.RFC3339),
+		LastUpdatedDateTime: time.Now().UTC().Format(time.RFC3339),
+	}
+	switch {
+	case op.polls <= op.scenario.Polls:
+	case op.scenario.Failure != nil:
+		result.Status = "failed"
+		result.Error = op.scenario.Failure
+	default:
+		result.Status = "succeeded"
+		result.AnalyzeResult = op.result
+	}
+	writeJSON(w, http.StatusOK, &result)
+}
+
+// selectPages returns a copy of result with only the pages numbered in
+// numbers. The content and the other elements are kept as they are.
+func selectPages(result *analysis.AnalyzeResult, numbers []int) *analysis.AnalyzeResult {
+	selected := *result
+	selected.Pages = nil
+	for _, page := range result.Pages {
+		if slices.Contains(numbers, int(page.PageNumber)) {
+			selected.Pages = append(selected.Pages, page)
+		}
+	}
+	return &selected
+}
+
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	writeJSON(w, status, map[string]*analysis.Error{"error": {Code: code, Message: message}})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
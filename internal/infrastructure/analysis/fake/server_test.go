@@ -0,0 +1,207 @@
+package fake
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/linzhengen/azure-document-intelligence-mcp/internal/domain/analysis"
+)
+
+const testKey = "test-key"
+
+func do(t *testing.T, method, url, contentType string, body []byte) *http.Response {
+	t.Helper()
+	req, err := http.NewRequest(method, url, bytes.NewReader(body))
+	require.NoError(t, err)
+	req.Header.Set("Ocp-Apim-Subscription-Key", testKey)
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = resp.Body.Close() })
+	return resp
+}
+
+func analyzeURL(s *Server, modelID string) string {
+	return s.URL + "/documentintelligence/documentModels/" + modelID + ":analyze?api-version=2024-11-30"
+}
+
+func poll(t *testing.T, operationLocation string) *analysis.AnalyzeOperationResult {
+	t.Helper()
+	resp := do(t, http.MethodGet, operationLocation, "", nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var result analysis.AnalyzeOperationResult
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+	return &result
+}
+
+func TestServer_AnalyzeLifecycle(t *testing.T) {
+	s := NewServer(testKey)
+	defer s.Close()
+	s.SetScenario("prebuilt-layout", Scenario{Polls: 2})
+
+	resp := do(t, http.MethodPost, analyzeURL(s, "prebuilt-layout"), "application/json", []byte(`{"urlSource":"https://example.com/doc.pdf"}`))
+	require.Equal(t, http.StatusAccepted, resp.StatusCode)
+	location := resp.Header.Get("Operation-Location")
+	require.NotEmpty(t, location)
+
+	assert.Equal(t, "running", poll(t, location).Status)
+	assert.Equal(t, "running", poll(t, location).Status)
+	result := poll(t, location)
+	assert.Equal(t, "succeeded", result.Status)
+	require.NotNil(t, result.AnalyzeResult)
+	assert.Equal(t, "prebuilt-layout", result.AnalyzeResult.ModelID)
+	assert.Len(t, result.AnalyzeResult.Tables, 1)
+
+	requests := s.Requests()
+	require.Len(t, requests, 1)
+	assert.Equal(t, "https://example.com/doc.pdf", requests[0].URLSource)
+}
+
+func TestServer_AnalyzeContent(t *testing.T) {
+	s := NewServer(testKey)
+	defer s.Close()
+
+	resp := do(t, http.MethodPost, analyzeURL(s, "prebuilt-read"), "application/pdf", []byte("%PDF-1.7"))
+
+	require.Equal(t, http.StatusAccepted, resp.StatusCode)
+	requests := s.Requests()
+	require.Len(t, requests, 1)
+	assert.Equal(t, "application/pdf", requests[0].ContentType)
+	assert.Equal(t, []byte("%PDF-1.7"), requests[0].Content)
+}
+
+func TestServer_AnalyzeContract(t *testing.T) {
+	s := NewServer(testKey)
+	defer s.Close()
+
+	resp := do(t, http.MethodPost, analyzeURL(s, "prebuilt-contract"), "application/pdf", []byte("%PDF-1.7"))
+	require.Equal(t, http.StatusAccepted, resp.StatusCode)
+	result := poll(t, resp.Header.Get("Operation-Location"))
+
+	require.Equal(t, "succeeded", result.Status)
+	require.Len(t, result.AnalyzeResult.Documents, 1)
+	doc := result.AnalyzeResult.Documents[0]
+	assert.Equal(t, "contract", doc.DocType)
+	title := doc.Fields["Title"]
+	require.NotNil(t, title)
+	assert.Equal(t, "Service Agreement", *title.ValueString)
+	assert.Equal(t, *title.Content, result.AnalyzeResult.Content[title.Spans[0].Offset:title.Spans[0].Offset+title.Spans[0].Length], "spans locate fields in the content")
+	parties := doc.Fields["Parties"]
+	require.NotNil(t, parties)
+	require.Len(t, parties.ValueArray, 2)
+	assert.Equal(t, "Fabrikam Inc.", *parties.ValueArray[1].ValueObject["Name"].ValueString)
+}
+
+func TestServer_AnalyzePages(t *testing.T) {
+	s := NewServer(testKey)
+	defer s.Close()
+	s.SetResult("prebuilt-layout", &analysis.AnalyzeResult{ModelID: "prebuilt-layout", Pages: []analysis.Page{{PageNumber: 1}, {PageNumber: 2}, {PageNumber: 3}}})
+	body := []byte(`{"urlSource":"https://example.com/doc.pdf"}`)
+
+	resp := do(t, http.MethodPost, analyzeURL(s, "prebuilt-layout")+"&pages=2-3,9", "application/json", body)
+	require.Equal(t, http.StatusAccepted, resp.StatusCode)
+	result := poll(t, resp.Header.Get("Operation-Location"))
+	require.Equal(t, "succeeded", result.Status)
+	var numbers []int32
+	for _, p := range result.AnalyzeResult.Pages {
+		numbers = append(numbers, p.PageNumber)
+	}
+	assert.Equal(t, []int32{2, 3}, numbers, "only the requested pages are returned")
+
+	resp = do(t, http.MethodPost, analyzeURL(s, "prebuilt-read")+"&pages=2-2000", "application/json", body)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode, "ranges past the end of the fixture are rejected")
+	resp = do(t, http.MethodPost, analyzeURL(s, "prebuilt-read")+"&pages=x", "application/json", body)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+func TestServer_Throttling(t *testing.T) {
+	s := NewServer(testKey)
+	defer s.Close()
+	s.SetScenario("prebuilt-read", Scenario{Throttle: 2, RetryAfter: 3})
+	body := []byte(`{"urlSource":"https://example.com/doc.pdf"}`)
+
+	for range 2 {
+		resp := do(t, http.MethodPost, analyzeURL(s, "prebuilt-read"), "application/json", body)
+		assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+		assert.Equal(t, "3", resp.Header.Get("Retry-After"))
+	}
+	resp := do(t, http.MethodPost, analyzeURL(s, "prebuilt-read"), "application/json", body)
+	assert.Equal(t, http.StatusAccepted, resp.StatusCode)
+}
+
+func TestServer_Errors(t *testing.T) {
+	s := NewServer(testKey)
+	defer s.Close()
+	s.SetScenario("prebuilt-layout", Scenario{InitiateStatus: http.StatusInternalServerError})
+	s.SetScenario("prebuilt-read", Scenario{Failure: &analysis.Error{Code: "InvalidContent", Message: "The file is corrupted."}})
+	body := []byte(`{"urlSource":"https://example.com/doc.pdf"}`)
+
+	resp := do(t, http.MethodPost, analyzeURL(s, "prebuilt-layout"), "application/json", body)
+	assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+
+	resp = do(t, http.MethodPost, analyzeURL(s, "unknown-model"), "application/json", body)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	resp = do(t, http.MethodPost, analyzeURL(s, "prebuilt-read"), "application/json", []byte(`{}`))
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	resp = do(t, http.MethodPost, analyzeURL(s, "prebuilt-read"), "application/json", body)
+	require.Equal(t, http.StatusAccepted, resp.StatusCode)
+	result := poll(t, resp.Header.Get("Operation-Location"))
+	assert.Equal(t, "failed", result.Status)
+	assert.Equal(t, "InvalidContent", result.Error.Code)
+
+	req, err := http.NewRequest(http.MethodPost, analyzeURL(s, "prebuilt-read"), bytes.NewReader(body))
+	require.NoError(t, err)
+	req.Header.Set("Ocp-Apim-Subscription-Key", "wrong-key")
+	unauthorized, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer func() { _ = unauthorized.Body.Close() }()
+	assert.Equal(t, http.StatusUnauthorized, unauthorized.StatusCode)
+}
+
+func TestServer_ListModels(t *testing.T) {
+	s := NewServer(testKey)
+	defer s.Close()
+
+	resp := do(t, http.MethodGet, s.URL+"/documentintelligence/documentModels?api-version=2024-11-30", "", nil)
+
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var list struct {
+		Value []struct {
+			ModelID string `json:"modelId"`
+		} `json:"value"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&list))
+	var ids []string
+	for _, m := range list.Value {
+		ids = append(ids, m.ModelID)
+	}
+	assert.Equal(t, []string{"prebuilt-contract", "prebuilt-invoice", "prebuilt-layout", "prebuilt-read"}, ids)
+
+	resp = do(t, http.MethodGet, s.URL+"/documentintelligence/documentModels/prebuilt-read?api-version=2024-11-30", "", nil)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+func TestServer_LoadFixtures(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "custom-model.json"), []byte(`{"status":"succeeded","analyzeResult":{"modelId":"custom-model","content":"custom"}}`), 0o600))
+	s := NewServer(testKey)
+	defer s.Close()
+
+	require.NoError(t, s.LoadFixtures(dir))
+
+	resp := do(t, http.MethodPost, analyzeURL(s, "custom-model"), "application/json", []byte(`{"urlSource":"https://example.com/doc.pdf"}`))
+	require.Equal(t, http.StatusAccepted, resp.StatusCode)
+	result := poll(t, resp.Header.Get("Operation-Location"))
+	assert.Equal(t, "custom", result.AnalyzeResult.Content)
+}
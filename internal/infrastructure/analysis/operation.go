@@ -0,0 +1,30 @@
+package analysis
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Operations of the requests sent by the repository.
+const (
+	OperationInitiate = "initiate"
+	OperationPoll     = "poll"
+	OperationOther    = "other"
+)
+
+// RequestOperation tells analyze requests (POST .../documentModels/{model}:analyze)
+// from polling requests (GET .../analyzeResults/{id}), so that HTTPClient
+// decorators can instrument them separately. The model ID is only known for
+// analyze requests.
+func RequestOperation(req *http.Request) (operation, modelID string) {
+	path := req.URL.Path
+	switch {
+	case req.Method == http.MethodPost && strings.HasSuffix(path, ":analyze"):
+		path = strings.TrimSuffix(path, ":analyze")
+		return OperationInitiate, path[strings.LastIndex(path, "/")+1:]
+	case req.Method == http.MethodGet && strings.Contains(path, "/analyzeResults/"):
+		return OperationPoll, ""
+	default:
+		return OperationOther, ""
+	}
+}
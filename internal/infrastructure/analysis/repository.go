@@ -6,10 +6,14 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
+	"net/url"
+	"path"
 	"time"
 
 	"github.com/linzhengen/azure-document-intelligence-mcp/internal/domain/analysis"
+	"github.com/linzhengen/azure-document-intelligence-mcp/internal/logging"
 )
 
 const (
@@ -49,16 +53,36 @@ func NewRepositoryWithClient(endpoint, apiKey string, httpClient HTTPClient) ana
 	}
 }
 
+// callInfo collects what is logged about an analysis call.
+type callInfo struct {
+	apimRequestID string
+	msRequestID   string
+	operationID   string
+	polls         int
+}
+
 // AnalyzeDocument analyzes the specified document URL.
 func (r *repository) AnalyzeDocument(ctx context.Context, modelID string, options analysis.AnalyzeDocumentOptions) (*analysis.AnalyzeOperationResult, error) {
+	start := time.Now()
+	var call callInfo
+	result, err := r.analyzeDocument(ctx, modelID, options, &call)
+	logCall(ctx, modelID, options, &call, time.Since(start), err)
+	return result, err
+}
+
+func (r *repository) analyzeDocument(ctx context.Context, modelID string, options analysis.AnalyzeDocumentOptions, call *callInfo) (*analysis.AnalyzeOperationResult, error) {
+	if options.Region != "" {
+		return nil, fmt.Errorf("region %q requested, but no endpoints are tagged with regions", options.Region)
+	}
+
 	// 1. Send analysis request
-	operationLocation, err := r.initiateAnalysis(ctx, modelID, options)
+	operationLocation, err := r.initiateAnalysis(ctx, modelID, options, call)
 	if err != nil {
-		return nil, fmt.Errorf("failed to initiate analysis: %w", err)
+		return nil, &initiateError{err: err}
 	}
 
 	// 2. Poll for the result
-	result, err := r.pollForResult(ctx, operationLocation)
+	result, err := r.pollForResult(ctx, operationLocation, call)
 	if err != nil {
 		return nil, fmt.Errorf("failed to poll for result: %w", err)
 	}
@@ -66,8 +90,65 @@ func (r *repository) AnalyzeDocument(ctx context.Context, modelID string, option
 	return result, nil
 }
 
-func (r *repository) initiateAnalysis(ctx context.Context, modelID string, options analysis.AnalyzeDocumentOptions) (string, error) {
+// initiateError is a failure to start an analysis: the service has not
+// accepted the document, so it can be sent elsewhere.
+type initiateError struct {
+	err error
+}
+
+func (e *initiateError) Error() string { return "failed to initiate analysis: " + e.err.Error() }
+func (e *initiateError) Unwrap() error { return e.err }
+
+// StatusError is an unexpected HTTP status returned by the service.
+type StatusError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("unexpected status code: %d, body: %s", e.StatusCode, e.Body)
+}
+
+// logCall writes one record per analysis call, correlated with the Azure request IDs.
+func logCall(ctx context.Context, modelID string, options analysis.AnalyzeDocumentOptions, call *callInfo, duration time.Duration, err error) {
+	sourceType := "content"
+	if options.DocURL != "" {
+		sourceType = "url"
+	}
+	attrs := []slog.Attr{
+		slog.String("modelId", modelID),
+		slog.String("sourceType", sourceType),
+		slog.Int("documentSize", len(options.Content)),
+		slog.String("apimRequestId", call.apimRequestID),
+		slog.String("msRequestId", call.msRequestID),
+		slog.String("operationId", call.operationID),
+		slog.Int("polls", call.polls),
+		slog.Duration("duration", duration),
+	}
+	logger := logging.FromContext(ctx)
+	if err != nil {
+		attrs = append(attrs, slog.String("outcome", "failed"), slog.String("error", err.Error()))
+		logger.LogAttrs(ctx, slog.LevelError, "document analysis failed", attrs...)
+		return
+	}
+	attrs = append(attrs, slog.String("outcome", "succeeded"))
+	logger.LogAttrs(ctx, slog.LevelInfo, "document analysis completed", attrs...)
+}
+
+// operationID returns the last path segment of an Operation-Location URL.
+func operationID(operationLocation string) string {
+	u, err := url.Parse(operationLocation)
+	if err != nil {
+		return ""
+	}
+	return path.Base(u.Path)
+}
+
+func (r *repository) initiateAnalysis(ctx context.Context, modelID string, options analysis.AnalyzeDocumentOptions, call *callInfo) (string, error) {
 	requestURL := fmt.Sprintf("%s/documentintelligence/documentModels/%s:analyze?api-version=%s", r.endpoint, modelID, apiVersion)
+	if options.Pages != "" {
+		requestURL += "&pages=" + url.QueryEscape(options.Pages)
+	}
 
 	var requestBody io.Reader
 	var contentType string
@@ -99,21 +180,24 @@ func (r *repository) initiateAnalysis(ctx context.Context, modelID string, optio
 		return "", fmt.Errorf("failed to send request: %w", err)
 	}
 	defer func() { _ = resp.Body.Close() }()
+	call.apimRequestID = resp.Header.Get("apim-request-id")
+	call.msRequestID = resp.Header.Get("x-ms-request-id")
 
 	if resp.StatusCode != http.StatusAccepted {
 		bodyBytes, _ := io.ReadAll(resp.Body)
-		return "", fmt.Errorf("unexpected status code: %d, body: %s", resp.StatusCode, string(bodyBytes))
+		return "", &StatusError{StatusCode: resp.StatusCode, Body: string(bodyBytes)}
 	}
 
 	operationLocation := resp.Header.Get("Operation-Location")
 	if operationLocation == "" {
 		return "", fmt.Errorf("Operation-Location header not found")
 	}
+	call.operationID = operationID(operationLocation)
 
 	return operationLocation, nil
 }
 
-func (r *repository) pollForResult(ctx context.Context, operationLocation string) (*analysis.AnalyzeOperationResult, error) {
+func (r *repository) pollForResult(ctx context.Context, operationLocation string, call *callInfo) (*analysis.AnalyzeOperationResult, error) {
 	var result analysis.AnalyzeOperationResult
 
 	for i := 0; i < maxRetries; i++ {
@@ -123,6 +207,7 @@ func (r *repository) pollForResult(ctx context.Context, operationLocation string
 		}
 		req.Header.Set("Ocp-Apim-Subscription-Key", r.apiKey)
 
+		call.polls++
 		resp, err := r.httpClient.Do(req)
 		if err != nil {
 			return nil, fmt.Errorf("failed to send polling request: %w", err)
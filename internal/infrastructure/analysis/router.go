@@ -0,0 +1,305 @@
+package analysis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/linzhengen/azure-document-intelligence-mcp/internal/domain/analysis"
+	"github.com/linzhengen/azure-document-intelligence-mcp/internal/logging"
+)
+
+// Routing strategies of RouterOptions.Strategy.
+const (
+	// StrategyRoundRobin spreads requests over endpoints in proportion to their weights.
+	StrategyRoundRobin = "round-robin"
+	// StrategyLeastLoaded sends requests to the endpoint with the fewest
+	// analyses in progress relative to its weight.
+	StrategyLeastLoaded = "least-loaded"
+)
+
+// Endpoint is a Document Intelligence resource requests can be routed to.
+type Endpoint struct {
+	URL    string
+	APIKey string
+	// Weight is the relative share of requests sent to the endpoint. Zero means 1.
+	Weight int
+	// Region tags the endpoint for requests restricted to a region.
+	Region string
+	// Models lists the model IDs available on the endpoint. Empty means all.
+	Models []string
+	// MaxConcurrent bounds the analyses in progress on the endpoint. Zero
+	// means RouterOptions.MaxConcurrentPerEndpoint.
+	MaxConcurrent int
+}
+
+// RouterOptions configures how requests are routed.
+type RouterOptions struct {
+	// Strategy is StrategyRoundRobin, the default, or StrategyLeastLoaded.
+	Strategy string
+	// FailureThreshold is the number of consecutive failures opening the
+	// circuit of an endpoint, which then gets no requests for Cooldown.
+	// Defaults to 5 failures and 30 seconds.
+	FailureThreshold int
+	Cooldown         time.Duration
+	// MaxConcurrentPerEndpoint bounds the analyses in progress on endpoints
+	// without MaxConcurrent. Zero means no limit.
+	MaxConcurrentPerEndpoint int
+}
+
+type route struct {
+	Endpoint
+	repo  *repository
+	slots chan struct{} // nil without a concurrency limit
+
+	// Guarded by router.mu.
+	inFlight  int
+	current   int // smooth weighted round-robin state
+	failures  int
+	openUntil time.Time
+}
+
+type router struct {
+	routes []*route
+	opts   RouterOptions
+	now    func() time.Time
+
+	mu sync.Mutex
+}
+
+// NewRouter returns a repository that routes each analysis to one of
+// endpoints, and fails over to the next one when an endpoint throttles
+// (429), fails (5xx), can't be reached or has its circuit open. Failover only
+// happens before the service accepts the document: results are always polled
+// from the endpoint that accepted it.
+func NewRouter(endpoints []Endpoint, httpClient HTTPClient, opts RouterOptions) (analysis.Repository, error) {
+	if len(endpoints) == 0 {
+		return nil, errors.New("no endpoints to route to")
+	}
+	switch opts.Strategy {
+	case "":
+		opts.Strategy = StrategyRoundRobin
+	case StrategyRoundRobin, StrategyLeastLoaded:
+	default:
+		return nil, fmt.Errorf("unsupported routing strategy %q", opts.Strategy)
+	}
+	if opts.FailureThreshold <= 0 {
+		opts.FailureThreshold = 5
+	}
+	if opts.Cooldown <= 0 {
+		opts.Cooldown = 30 * time.Second
+	}
+	r := &router{opts: opts, now: time.Now}
+	for _, e := range endpoints {
+		if e.Weight <= 0 {
+			e.Weight = 1
+		}
+		if e.MaxConcurrent <= 0 {
+			e.MaxConcurrent = opts.MaxConcurrentPerEndpoint
+		}
+		rt := &route{
+			Endpoint: e,
+			repo:     &repository{endpoint: e.URL, apiKey: e.APIKey, httpClient: httpClient},
+		}
+		if e.MaxConcurrent > 0 {
+			rt.slots = make(chan struct{}, e.MaxConcurrent)
+		}
+		r.routes = append(r.routes, rt)
+	}
+	return r, nil
+}
+
+// AnalyzeDocument analyzes the document on the first available endpoint.
+func (r *router) AnalyzeDocument(ctx context.Context, modelID string, options analysis.AnalyzeDocumentOptions) (*analysis.AnalyzeOperationResult, error) {
+	candidates := r.candidates(modelID, options.Region)
+	if len(candidates) == 0 {
+		if options.Region != "" {
+			return nil, fmt.Errorf("no endpoint in region %q serves model %s", options.Region, modelID)
+		}
+		return nil, fmt.Errorf("no endpoint serves model %s", modelID)
+	}
+	options.Region = ""
+
+	var errs []error
+	for _, rt := range r.order(candidates) {
+		if err := r.acquire(ctx, rt); err != nil {
+			if ctx.Err() != nil {
+				return nil, err
+			}
+			errs = append(errs, fmt.Errorf("%s: %w", host(rt.URL), err))
+			continue
+		}
+		result, err := rt.repo.AnalyzeDocument(ctx, modelID, options)
+		retry := err != nil && ctx.Err() == nil && canFailOver(err)
+		r.release(rt, retry)
+		if !retry {
+			return result, err
+		}
+		logging.FromContext(ctx).WarnContext(ctx, "endpoint failed, trying the next one", "endpoint", host(rt.URL), "modelId", modelID, "error", err)
+		errs = append(errs, fmt.Errorf("%s: %w", host(rt.URL), err))
+	}
+	return nil, fmt.Errorf("all endpoints failed: %w", errors.Join(errs...))
+}
+
+// canFailOver reports whether err shows that the service has not accepted
+// the document, and another endpoint may.
+func canFailOver(err error) bool {
+	var initErr *initiateError
+	if !errors.As(err, &initErr) {
+		return false
+	}
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode == http.StatusTooManyRequests || statusErr.StatusCode >= 500
+	}
+	// The request could not be sent, or the response was incomplete.
+	return true
+}
+
+// candidates returns the routes serving modelID in region.
+func (r *router) candidates(modelID, region string) []*route {
+	var routes []*route
+	for _, rt := range r.routes {
+		if region != "" && !strings.EqualFold(rt.Region, region) {
+			continue
+		}
+		if len(rt.Models) > 0 && !slices.Contains(rt.Models, modelID) {
+			continue
+		}
+		routes = append(routes, rt)
+	}
+	return routes
+}
+
+// order returns the routes in the order they should be tried: the one
+// picked by the strategy, preferably among those with a free slot, then the
+// others by decreasing weight. Routes with an open circuit are tried last.
+func (r *router) order(routes []*route) []*route {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.now()
+	var closed []*route
+	for _, rt := range routes {
+		if !now.Before(rt.openUntil) {
+			closed = append(closed, rt)
+		}
+	}
+	var first *route
+	if free := slices.DeleteFunc(slices.Clone(closed), (*route).full); len(free) > 0 {
+		first = r.pick(free)
+	} else if len(closed) > 0 {
+		first = r.pick(closed)
+	}
+	ordered := make([]*route, 0, len(routes))
+	if first != nil {
+		ordered = append(ordered, first)
+	}
+	rest := slices.DeleteFunc(slices.Clone(routes), func(rt *route) bool { return rt == first })
+	slices.SortStableFunc(rest, func(a, b *route) int {
+		aOpen, bOpen := now.Before(a.openUntil), now.Before(b.openUntil)
+		if aOpen != bOpen {
+			if aOpen {
+				return 1
+			}
+			return -1
+		}
+		return b.Weight - a.Weight
+	})
+	return append(ordered, rest...)
+}
+
+// pick chooses a route by the strategy. r.mu must be held.
+func (r *router) pick(routes []*route) *route {
+	if r.opts.Strategy == StrategyLeastLoaded {
+		best := routes[0]
+		for _, rt := range routes[1:] {
+			// Compare inFlight/Weight without dividing.
+			if rt.inFlight*best.Weight < best.inFlight*rt.Weight {
+				best = rt
+			}
+		}
+		return best
+	}
+
+	// Smooth weighted round-robin: every route gains its weight, and the
+	// richest one is picked and pays the total.
+	total := 0
+	var best *route
+	for _, rt := range routes {
+		rt.current += rt.Weight
+		total += rt.Weight
+		if best == nil || rt.current > best.current {
+			best = rt
+		}
+	}
+	best.current -= total
+	return best
+}
+
+// full reports whether rt has as many analyses in progress as it allows.
+// router.mu must be held.
+func (rt *route) full() bool {
+	return rt.slots != nil && rt.inFlight >= cap(rt.slots)
+}
+
+// errCircuitOpen is the error of routes skipped because of their circuit.
+var errCircuitOpen = errors.New("circuit open")
+
+// acquire marks an analysis in progress on rt, waiting for a free slot,
+// unless its circuit is open. Once the cooldown is over, the circuit lets
+// requests through again, and closes on the first success.
+func (r *router) acquire(ctx context.Context, rt *route) error {
+	r.mu.Lock()
+	open := r.now().Before(rt.openUntil)
+	r.mu.Unlock()
+	if open {
+		return errCircuitOpen
+	}
+	if rt.slots != nil {
+		select {
+		case rt.slots <- struct{}{}:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	r.mu.Lock()
+	rt.inFlight++
+	r.mu.Unlock()
+	return nil
+}
+
+// release ends an analysis on rt, recording whether the endpoint failed.
+func (r *router) release(rt *route, failed bool) {
+	if rt.slots != nil {
+		<-rt.slots
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	rt.inFlight--
+	if !failed {
+		rt.failures = 0
+		return
+	}
+	// The failures are kept when the circuit opens, so that a failure after
+	// the cooldown opens it again.
+	rt.failures++
+	if rt.failures >= r.opts.FailureThreshold {
+		rt.openUntil = r.now().Add(r.opts.Cooldown)
+	}
+}
+
+// host returns the host of an endpoint URL, to name it without its path.
+func host(endpoint string) string {
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Host == "" {
+		return endpoint
+	}
+	return u.Host
+}
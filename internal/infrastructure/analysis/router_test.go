@@ -0,0 +1,282 @@
+package analysis
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/linzhengen/azure-document-intelligence-mcp/internal/domain/analysis"
+	"github.com/linzhengen/azure-document-intelligence-mcp/internal/infrastructure/analysis/fake"
+)
+
+// recordingClient counts the requests sent to each host by method.
+type recordingClient struct {
+	next HTTPClient
+
+	mu       sync.Mutex
+	requests map[string]int
+}
+
+func (c *recordingClient) Do(req *http.Request) (*http.Response, error) {
+	c.mu.Lock()
+	if c.requests == nil {
+		c.requests = map[string]int{}
+	}
+	c.requests[req.Method+" "+req.URL.Host]++
+	c.mu.Unlock()
+	return c.next.Do(req)
+}
+
+func (c *recordingClient) count(method, endpoint string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.requests[method+" "+host(endpoint)]
+}
+
+func newFakeServers(t *testing.T, n int) []*fake.Server {
+	t.Helper()
+	servers := make([]*fake.Server, n)
+	for i := range servers {
+		servers[i] = fake.NewServer("key")
+		t.Cleanup(servers[i].Close)
+	}
+	return servers
+}
+
+func fastPolling(t *testing.T) {
+	t.Helper()
+	originalDelay := retryDelay
+	retryDelay = time.Millisecond
+	t.Cleanup(func() { retryDelay = originalDelay })
+}
+
+var urlOptions = analysis.AnalyzeDocumentOptions{DocURL: "https://example.com/doc.pdf"}
+
+func TestRouter_FailsOverAndPinsPolling(t *testing.T) {
+	fastPolling(t)
+	servers := newFakeServers(t, 2)
+	servers[0].SetScenario("prebuilt-read", fake.Scenario{Throttle: 1})
+	servers[1].SetScenario("prebuilt-read", fake.Scenario{Polls: 2})
+	client := &recordingClient{next: http.DefaultClient}
+	repo, err := NewRouter([]Endpoint{
+		{URL: servers[0].URL, APIKey: "key", Weight: 2},
+		{URL: servers[1].URL, APIKey: "key"},
+	}, client, RouterOptions{})
+	require.NoError(t, err)
+
+	result, err := repo.AnalyzeDocument(context.Background(), "prebuilt-read", urlOptions)
+
+	require.NoError(t, err)
+	assert.Equal(t, "succeeded", result.Status)
+	assert.Equal(t, 1, client.count(http.MethodPost, servers[0].URL), "the heavier endpoint is tried first")
+	assert.Equal(t, 0, client.count(http.MethodGet, servers[0].URL))
+	assert.Equal(t, 1, client.count(http.MethodPost, servers[1].URL))
+	assert.Equal(t, 3, client.count(http.MethodGet, servers[1].URL), "the result is polled where it was accepted")
+}
+
+func TestRouter_DoesNotFailOverClientErrors(t *testing.T) {
+	servers := newFakeServers(t, 2)
+	servers[0].SetScenario("prebuilt-read", fake.Scenario{InitiateStatus: http.StatusBadRequest})
+	repo, err := NewRouter([]Endpoint{{URL: servers[0].URL, APIKey: "key"}, {URL: servers[1].URL, APIKey: "key"}}, http.DefaultClient, RouterOptions{})
+	require.NoError(t, err)
+
+	_, err = repo.AnalyzeDocument(context.Background(), "prebuilt-read", urlOptions)
+
+	require.ErrorContains(t, err, "unexpected status code: 400")
+	assert.Empty(t, servers[1].Requests())
+}
+
+func TestRouter_AllEndpointsFail(t *testing.T) {
+	servers := newFakeServers(t, 2)
+	for _, s := range servers {
+		s.SetScenario("prebuilt-read", fake.Scenario{InitiateStatus: http.StatusServiceUnavailable})
+	}
+	repo, err := NewRouter([]Endpoint{{URL: servers[0].URL, APIKey: "key"}, {URL: servers[1].URL, APIKey: "key"}}, http.DefaultClient, RouterOptions{})
+	require.NoError(t, err)
+
+	_, err = repo.AnalyzeDocument(context.Background(), "prebuilt-read", urlOptions)
+
+	require.ErrorContains(t, err, "all endpoints failed")
+	assert.Contains(t, err.Error(), host(servers[0].URL))
+	assert.Contains(t, err.Error(), host(servers[1].URL))
+}
+
+// acceptingClient accepts every analysis at once and counts the analyze
+// requests per endpoint.
+type acceptingClient struct {
+	mu     sync.Mutex
+	posts  map[string]int
+	onPost func(endpoint string)
+}
+
+func (c *acceptingClient) Do(req *http.Request) (*http.Response, error) {
+	if req.Method == http.MethodPost {
+		c.mu.Lock()
+		if c.posts == nil {
+			c.posts = map[string]int{}
+		}
+		c.posts[req.URL.Host]++
+		c.mu.Unlock()
+		if c.onPost != nil {
+			c.onPost(req.URL.Host)
+		}
+		location := (&url.URL{Scheme: "http", Host: req.URL.Host, Path: "/operations/1"}).String()
+		return &http.Response{StatusCode: http.StatusAccepted, Header: http.Header{"Operation-Location": {location}}, Body: http.NoBody}, nil
+	}
+	return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(`{"status":"succeeded"}`))}, nil
+}
+
+func TestRouter_WeightedRoundRobin(t *testing.T) {
+	client := &acceptingClient{}
+	repo, err := NewRouter([]Endpoint{
+		{URL: "http://a.test", Weight: 3},
+		{URL: "http://b.test"},
+	}, client, RouterOptions{Strategy: StrategyRoundRobin})
+	require.NoError(t, err)
+
+	for range 8 {
+		_, err := repo.AnalyzeDocument(context.Background(), "prebuilt-read", urlOptions)
+		require.NoError(t, err)
+	}
+
+	assert.Equal(t, map[string]int{"a.test": 6, "b.test": 2}, client.posts)
+}
+
+func TestRouter_LeastLoaded(t *testing.T) {
+	// While an analysis is in progress on a.test, the next one goes to b.test.
+	release := make(chan struct{})
+	started := make(chan struct{})
+	client := &acceptingClient{onPost: func(endpoint string) {
+		if endpoint == "a.test" {
+			close(started)
+			<-release
+		}
+	}}
+	repo, err := NewRouter([]Endpoint{{URL: "http://a.test"}, {URL: "http://b.test"}}, client, RouterOptions{Strategy: StrategyLeastLoaded})
+	require.NoError(t, err)
+
+	done := make(chan error)
+	go func() {
+		_, err := repo.AnalyzeDocument(context.Background(), "prebuilt-read", urlOptions)
+		done <- err
+	}()
+	<-started
+	_, err = repo.AnalyzeDocument(context.Background(), "prebuilt-read", urlOptions)
+	require.NoError(t, err)
+	close(release)
+	require.NoError(t, <-done)
+
+	assert.Equal(t, map[string]int{"a.test": 1, "b.test": 1}, client.posts)
+}
+
+func TestRouter_MaxConcurrent(t *testing.T) {
+	// a.test takes one analysis at a time: while it's busy, analyses go to
+	// b.test despite its weight, and wait for a slot once both are busy.
+	release := make(chan struct{})
+	started := make(chan string, 3)
+	client := &acceptingClient{onPost: func(endpoint string) {
+		started <- endpoint
+		<-release
+	}}
+	repo, err := NewRouter([]Endpoint{
+		{URL: "http://a.test", Weight: 10, MaxConcurrent: 1},
+		{URL: "http://b.test"},
+	}, client, RouterOptions{MaxConcurrentPerEndpoint: 1})
+	require.NoError(t, err)
+
+	done := make(chan error)
+	for range 3 {
+		go func() {
+			_, err := repo.AnalyzeDocument(context.Background(), "prebuilt-read", urlOptions)
+			done <- err
+		}()
+	}
+	busy := []string{<-started, <-started}
+	assert.ElementsMatch(t, []string{"a.test", "b.test"}, busy)
+	select {
+	case endpoint := <-started:
+		t.Fatalf("a third analysis started on %s", endpoint)
+	case <-time.After(20 * time.Millisecond):
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = repo.AnalyzeDocument(ctx, "prebuilt-read", urlOptions)
+	assert.ErrorIs(t, err, context.Canceled, "waiting for a slot stops with the context")
+
+	close(release)
+	for range 3 {
+		require.NoError(t, <-done)
+	}
+	assert.Equal(t, 3, client.posts["a.test"]+client.posts["b.test"])
+}
+
+func TestRouter_RegionAndModels(t *testing.T) {
+	client := &acceptingClient{}
+	repo, err := NewRouter([]Endpoint{
+		{URL: "http://us.test", Region: "eastus"},
+		{URL: "http://eu.test", Region: "westeurope", Models: []string{"prebuilt-read"}},
+	}, client, RouterOptions{})
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	options := urlOptions
+	options.Region = "WestEurope"
+	for range 3 {
+		_, err := repo.AnalyzeDocument(ctx, "prebuilt-read", options)
+		require.NoError(t, err)
+	}
+	assert.Equal(t, map[string]int{"eu.test": 3}, client.posts)
+
+	_, err = repo.AnalyzeDocument(ctx, "prebuilt-invoice", options)
+	assert.ErrorContains(t, err, `no endpoint in region "WestEurope" serves model prebuilt-invoice`)
+}
+
+func TestRouter_CircuitBreaker(t *testing.T) {
+	servers := newFakeServers(t, 2)
+	servers[0].SetScenario("prebuilt-read", fake.Scenario{InitiateStatus: http.StatusInternalServerError})
+	repo, err := NewRouter([]Endpoint{{URL: servers[0].URL, APIKey: "key", Weight: 10}, {URL: servers[1].URL, APIKey: "key"}},
+		http.DefaultClient, RouterOptions{FailureThreshold: 2, Cooldown: time.Minute})
+	require.NoError(t, err)
+	now := time.Now()
+	repo.(*router).now = func() time.Time { return now }
+	ctx := context.Background()
+	fastPolling(t)
+
+	for range 4 {
+		_, err := repo.AnalyzeDocument(ctx, "prebuilt-read", urlOptions)
+		require.NoError(t, err)
+	}
+	assert.Len(t, servers[0].Requests(), 2, "the circuit opens after two failures")
+
+	now = now.Add(time.Minute)
+	_, err = repo.AnalyzeDocument(ctx, "prebuilt-read", urlOptions)
+	require.NoError(t, err)
+	_, err = repo.AnalyzeDocument(ctx, "prebuilt-read", urlOptions)
+	require.NoError(t, err)
+	assert.Len(t, servers[0].Requests(), 3, "after the cooldown, one failure opens the circuit again")
+}
+
+func TestNewRouter_Invalid(t *testing.T) {
+	_, err := NewRouter(nil, http.DefaultClient, RouterOptions{})
+	assert.Error(t, err)
+	_, err = NewRouter([]Endpoint{{URL: "http://a.test"}}, http.DefaultClient, RouterOptions{Strategy: "random"})
+	assert.ErrorContains(t, err, "unsupported routing strategy")
+}
+
+func TestAnalyzeDocument_RegionWithoutRouter(t *testing.T) {
+	repo := NewRepositoryWithClient("http://test.com", "key", &MockHTTPClient{})
+	options := urlOptions
+	options.Region = "westeurope"
+
+	_, err := repo.AnalyzeDocument(context.Background(), "prebuilt-read", options)
+
+	assert.ErrorContains(t, err, "no endpoints are tagged with regions")
+}
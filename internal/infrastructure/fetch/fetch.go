@@ -0,0 +1,154 @@
+// Package fetch reads documents on behalf of the service: it downloads those
+// at URLs that Azure can't reach, such as hosts behind a VPN, and reads files
+// of this server.
+package fetch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/linzhengen/azure-document-intelligence-mcp/internal/infrastructure/urlpolicy"
+)
+
+// Options configure a Fetcher.
+type Options struct {
+	// MaxBytes limits the size of a document.
+	MaxBytes int64
+	// Timeout limits the duration of a download, including redirects.
+	Timeout time.Duration
+	// MaxRedirects limits the redirects followed.
+	MaxRedirects int
+	// Headers are added to the requests sent to a host, such as an
+	// Authorization or Cookie header. They're keyed by host name, without port.
+	Headers map[string]map[string]string
+	// Policy, if not nil, validates every URL requested, including redirect
+	// targets, and the address connected to.
+	Policy *urlpolicy.Policy
+}
+
+// Fetcher downloads documents.
+type Fetcher struct {
+	client   *http.Client
+	maxBytes int64
+}
+
+// New returns a fetcher with the options.
+func New(opts Options) *Fetcher {
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+	dial := dialer.DialContext
+	if policy := opts.Policy; policy != nil && !policy.AllowPrivateNetworks {
+		// Check the address actually connected to, so that a host resolving
+		// to a public address when validated and to a private one when
+		// connected (DNS rebinding) is refused.
+		checked := &net.Dialer{
+			Timeout: dialer.Timeout,
+			Control: func(_, address string, _ syscall.RawConn) error {
+				addrPort, err := netip.ParseAddrPort(address)
+				if err != nil {
+					return err
+				}
+				return urlpolicy.CheckAddr(addrPort.Addr())
+			},
+		}
+		// The private hosts of the policy may be reached on any address.
+		dial = func(ctx context.Context, network, address string) (net.Conn, error) {
+			if host, _, err := net.SplitHostPort(address); err == nil && policy.AllowsPrivate(host) {
+				return dialer.DialContext(ctx, network, address)
+			}
+			return checked.DialContext(ctx, network, address)
+		}
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = dial
+	transport.Proxy = nil
+
+	return &Fetcher{
+		maxBytes: opts.MaxBytes,
+		client: &http.Client{
+			Timeout:   opts.Timeout,
+			Transport: &headerTransport{next: transport, headers: opts.Headers},
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				if len(via) > opts.MaxRedirects {
+					return fmt.Errorf("stopped after %d redirects", opts.MaxRedirects)
+				}
+				if opts.Policy != nil {
+					return opts.Policy.Validate(req.Context(), req.URL.String())
+				}
+				return nil
+			},
+		},
+	}
+}
+
+// Fetch downloads the document at rawURL and returns its content and media type.
+func (f *Fetcher) Fetch(ctx context.Context, rawURL string) ([]byte, string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to create document request: %w", err)
+	}
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to download document: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("failed to download document: unexpected status code: %d", resp.StatusCode)
+	}
+	if f.maxBytes > 0 && resp.ContentLength > f.maxBytes {
+		return nil, "", fmt.Errorf("document is larger than %d bytes", f.maxBytes)
+	}
+	body := io.Reader(resp.Body)
+	if f.maxBytes > 0 {
+		body = io.LimitReader(resp.Body, f.maxBytes+1)
+	}
+	content, err := io.ReadAll(body)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read document: %w", err)
+	}
+	if f.maxBytes > 0 && int64(len(content)) > f.maxBytes {
+		return nil, "", fmt.Errorf("document is larger than %d bytes", f.maxBytes)
+	}
+	if len(content) == 0 {
+		return nil, "", errors.New("downloaded document is empty")
+	}
+	return content, contentType(resp.Header.Get("Content-Type"), content), nil
+}
+
+// contentType returns the media type of the response, sniffing the content
+// when the server didn't send a specific one.
+func contentType(header string, content []byte) string {
+	mediaType, _, err := mime.ParseMediaType(header)
+	if err != nil || mediaType == "application/octet-stream" || mediaType == "binary/octet-stream" {
+		mediaType, _, _ = mime.ParseMediaType(http.DetectContentType(content))
+	}
+	return mediaType
+}
+
+// headerTransport adds the configured headers of each request's host. It acts
+// on every hop, so headers meant for one host aren't sent to the host of a redirect.
+type headerTransport struct {
+	next    http.RoundTripper
+	headers map[string]map[string]string
+}
+
+func (t *headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	headers := t.headers[strings.ToLower(req.URL.Hostname())]
+	if len(headers) == 0 {
+		return t.next.RoundTrip(req)
+	}
+	req = req.Clone(req.Context())
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	return t.next.RoundTrip(req)
+}
@@ -0,0 +1,122 @@
+package fetch
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/linzhengen/azure-document-intelligence-mcp/internal/infrastructure/urlpolicy"
+)
+
+var pdf = []byte("%PDF-1.7\n1 0 obj\n<<>>\nendobj\n")
+
+func newDocumentServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/doc.pdf", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "application/octet-stream")
+		_, _ = w.Write(pdf)
+	})
+	mux.HandleFunc("/image.png", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png; charset=binary")
+		_, _ = w.Write([]byte("png"))
+	})
+	mux.HandleFunc("/redirect/", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, r.URL.Query().Get("to"), http.StatusFound)
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func hostOf(t *testing.T, server *httptest.Server) string {
+	u, err := url.Parse(server.URL)
+	require.NoError(t, err)
+	return u.Hostname()
+}
+
+func localPolicy() *urlpolicy.Policy {
+	return &urlpolicy.Policy{AllowedSchemes: []string{"http"}, AllowPrivateNetworks: true}
+}
+
+func TestFetcher_Fetch(t *testing.T) {
+	server := newDocumentServer(t)
+	f := New(Options{
+		MaxBytes:     1024,
+		Timeout:      5 * time.Second,
+		MaxRedirects: 2,
+		Headers:      map[string]map[string]string{hostOf(t, server): {"Authorization": "Bearer secret"}},
+		Policy:       localPolicy(),
+	})
+
+	content, contentType, err := f.Fetch(context.Background(), server.URL+"/doc.pdf")
+	require.NoError(t, err)
+	assert.Equal(t, pdf, content)
+	assert.Equal(t, "application/pdf", contentType, "octet-stream is sniffed")
+
+	_, contentType, err = f.Fetch(context.Background(), server.URL+"/image.png")
+	require.NoError(t, err)
+	assert.Equal(t, "image/png", contentType)
+
+	content, _, err = f.Fetch(context.Background(), server.URL+"/redirect/?to=/doc.pdf")
+	require.NoError(t, err)
+	assert.Equal(t, pdf, content)
+}
+
+func TestFetcher_Limits(t *testing.T) {
+	server := newDocumentServer(t)
+	headers := map[string]map[string]string{hostOf(t, server): {"Authorization": "Bearer secret"}}
+
+	_, _, err := New(Options{MaxBytes: 8, Headers: headers, Policy: localPolicy()}).Fetch(context.Background(), server.URL+"/doc.pdf")
+	assert.ErrorContains(t, err, "larger than 8 bytes")
+
+	redirects := server.URL + "/redirect/?to=" + url.QueryEscape("/redirect/?to="+url.QueryEscape("/doc.pdf"))
+	_, _, err = New(Options{MaxRedirects: 1, Headers: headers, Policy: localPolicy()}).Fetch(context.Background(), redirects)
+	assert.ErrorContains(t, err, "stopped after 1 redirects")
+
+	_, _, err = New(Options{Policy: localPolicy()}).Fetch(context.Background(), server.URL+"/doc.pdf")
+	assert.ErrorContains(t, err, "status code: 401")
+}
+
+func TestFetcher_RefusesPrivateAddresses(t *testing.T) {
+	server := newDocumentServer(t)
+	policy := &urlpolicy.Policy{AllowedSchemes: []string{"http"}}
+
+	_, _, err := New(Options{Policy: policy}).Fetch(context.Background(), server.URL+"/image.png")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "loopback address", "the dialer refuses the connection")
+}
+
+func TestFetcher_PrivateHosts(t *testing.T) {
+	server := newDocumentServer(t)
+	policy := &urlpolicy.Policy{AllowedSchemes: []string{"http"}, PrivateHosts: []string{hostOf(t, server)}}
+
+	content, _, err := New(Options{Policy: policy}).Fetch(context.Background(), server.URL+"/image.png")
+	require.NoError(t, err, "the dialer connects to the private hosts of the policy")
+	assert.Equal(t, []byte("png"), content)
+
+	policy.PrivateHosts = []string{"intranet.example.com"}
+	_, _, err = New(Options{Policy: policy}).Fetch(context.Background(), server.URL+"/image.png")
+	assert.ErrorContains(t, err, "loopback address")
+}
+
+func TestFetcher_RedirectValidated(t *testing.T) {
+	server := newDocumentServer(t)
+	policy := localPolicy()
+	policy.DeniedHosts = []string{"denied.example.com"}
+
+	_, _, err := New(Options{MaxRedirects: 3, Policy: policy}).Fetch(context.Background(), server.URL+"/redirect/?to=http://denied.example.com/doc.pdf")
+	require.Error(t, err)
+	assert.True(t, strings.Contains(err.Error(), "is denied"), err.Error())
+}
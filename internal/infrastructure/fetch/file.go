@@ -0,0 +1,100 @@
+package fetch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"os"
+	"path/filepath"
+)
+
+// Files reads documents from directories of this server.
+type Files struct {
+	dirs     []string
+	maxBytes int64
+}
+
+// NewFiles returns a reader of the files in dirs and their subdirectories,
+// of at most maxBytes.
+func NewFiles(dirs []string, maxBytes int64) (*Files, error) {
+	f := &Files{maxBytes: maxBytes}
+	for _, dir := range dirs {
+		resolved, err := resolvePath(dir)
+		if err != nil {
+			return nil, fmt.Errorf("invalid document directory %q: %w", dir, err)
+		}
+		f.dirs = append(f.dirs, resolved)
+	}
+	return f, nil
+}
+
+// Read returns the content and media type of the file at path, which must be
+// absolute. Symbolic links are followed, so that a link can't lead out of the
+// directories.
+func (f *Files) Read(_ context.Context, path string) ([]byte, string, error) {
+	if !filepath.IsAbs(path) {
+		return nil, "", fmt.Errorf("document path %q is not absolute", path)
+	}
+	resolved, err := resolvePath(path)
+	if err != nil || !f.allowed(resolved) {
+		// Don't reveal which files exist outside of the directories.
+		return nil, "", fmt.Errorf("document path %q is not in a document directory", path)
+	}
+
+	file, err := os.Open(resolved)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to open document: %w", err)
+	}
+	defer func() { _ = file.Close() }()
+	info, err := file.Stat()
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to open document: %w", err)
+	}
+	if !info.Mode().IsRegular() {
+		return nil, "", fmt.Errorf("document path %q is not a file", path)
+	}
+	if f.maxBytes > 0 && info.Size() > f.maxBytes {
+		return nil, "", fmt.Errorf("document is larger than %d bytes", f.maxBytes)
+	}
+	return ReadDocument(file, path, f.maxBytes)
+}
+
+// ReadDocument reads a document of at most maxBytes from r, such as a file
+// or the standard input, and returns its content and media type. The media
+// type is guessed from the extension of name, if any, or else the content.
+func ReadDocument(r io.Reader, name string, maxBytes int64) ([]byte, string, error) {
+	if maxBytes > 0 {
+		r = io.LimitReader(r, maxBytes+1)
+	}
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read document: %w", err)
+	}
+	if maxBytes > 0 && int64(len(content)) > maxBytes {
+		return nil, "", fmt.Errorf("document is larger than %d bytes", maxBytes)
+	}
+	if len(content) == 0 {
+		return nil, "", errors.New("document is empty")
+	}
+	return content, contentType(mime.TypeByExtension(filepath.Ext(name)), content), nil
+}
+
+func (f *Files) allowed(path string) bool {
+	for _, dir := range f.dirs {
+		if rel, err := filepath.Rel(dir, path); err == nil && filepath.IsLocal(rel) {
+			return true
+		}
+	}
+	return false
+}
+
+// resolvePath returns the absolute path of path with symbolic links resolved.
+func resolvePath(path string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	return filepath.EvalSymlinks(abs)
+}
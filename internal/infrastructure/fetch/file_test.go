@@ -0,0 +1,77 @@
+package fetch
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFiles_Read(t *testing.T) {
+	ctx := context.Background()
+	dir, outside := t.TempDir(), t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "invoices"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "invoices", "a.pdf"), pdf, 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "scan"), []byte("\x89PNG\r\n\x1a\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "empty.pdf"), nil, 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(outside, "secret.pdf"), pdf, 0o644))
+	require.NoError(t, os.Symlink(filepath.Join(outside, "secret.pdf"), filepath.Join(dir, "link.pdf")))
+
+	files, err := NewFiles([]string{dir}, int64(len(pdf)))
+	require.NoError(t, err)
+
+	content, contentType, err := files.Read(ctx, filepath.Join(dir, "invoices", "a.pdf"))
+	require.NoError(t, err)
+	assert.Equal(t, pdf, content)
+	assert.Equal(t, "application/pdf", contentType)
+
+	_, contentType, err = files.Read(ctx, filepath.Join(dir, "scan"))
+	require.NoError(t, err)
+	assert.Equal(t, "image/png", contentType, "the type of files without extension is sniffed")
+
+	for name, path := range map[string]string{
+		"outside":  filepath.Join(outside, "secret.pdf"),
+		"dot-dot":  filepath.Join(dir, "..", filepath.Base(outside), "secret.pdf"),
+		"link":     filepath.Join(dir, "link.pdf"),
+		"missing":  filepath.Join(dir, "missing.pdf"),
+		"relative": "invoices/a.pdf",
+	} {
+		_, _, err := files.Read(ctx, path)
+		assert.Error(t, err, name)
+	}
+	_, _, err = files.Read(ctx, filepath.Join(dir, "invoices"))
+	assert.ErrorContains(t, err, "not a file")
+	_, _, err = files.Read(ctx, filepath.Join(dir, "empty.pdf"))
+	assert.ErrorContains(t, err, "empty")
+
+	small, err := NewFiles([]string{dir}, 4)
+	require.NoError(t, err)
+	_, _, err = small.Read(ctx, filepath.Join(dir, "invoices", "a.pdf"))
+	assert.ErrorContains(t, err, "larger than 4 bytes")
+}
+
+func TestNewFiles_MissingDirectory(t *testing.T) {
+	_, err := NewFiles([]string{filepath.Join(t.TempDir(), "missing")}, 0)
+	assert.Error(t, err)
+}
+
+func TestReadDocument(t *testing.T) {
+	content, contentType, err := ReadDocument(bytes.NewReader(pdf), "-", 0)
+	require.NoError(t, err)
+	assert.Equal(t, pdf, content)
+	assert.Equal(t, "application/pdf", contentType, "the type of unnamed documents is sniffed")
+
+	_, contentType, err = ReadDocument(strings.NewReader("<p>Invoice</p>"), "invoice.txt", 0)
+	require.NoError(t, err)
+	assert.Equal(t, "text/plain", contentType)
+
+	_, _, err = ReadDocument(bytes.NewReader(pdf), "a.pdf", 4)
+	assert.ErrorContains(t, err, "larger than 4 bytes")
+	_, _, err = ReadDocument(strings.NewReader(""), "-", 0)
+	assert.ErrorContains(t, err, "empty")
+}
@@ -0,0 +1,306 @@
+// Package history stores the results of past analyses in a directory: an
+// index of their metadata as a JSON Lines file, and one JSON file per result.
+package history
+
+import (
+	"bufio"
+	"cmp"
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/linzhengen/azure-document-intelligence-mcp/internal/domain/analysis"
+	"github.com/linzhengen/azure-document-intelligence-mcp/internal/domain/history"
+)
+
+const (
+	indexFile  = "index.jsonl"
+	resultsDir = "results"
+)
+
+// Retention bounds the records kept by a FileStore. Zero fields keep
+// everything.
+type Retention struct {
+	// MaxRecords is the number of records kept. Once it's exceeded, the
+	// oldest tenth is removed at once rather than one record per save, as
+	// removing records rewrites the index.
+	MaxRecords int
+	// MaxAge is how long records are kept.
+	MaxAge time.Duration
+}
+
+// FileStore keeps the index in memory and on disk, and reads results from
+// disk when they're requested, or searched for terms of their vocabulary.
+type FileStore struct {
+	dir       string
+	retention Retention
+
+	mu      sync.RWMutex
+	entries []*entry                                    // oldest first
+	results map[string]*analysis.AnalyzeOperationResult // without a directory only
+}
+
+// entry is a line of the index: a record and the vocabulary of its result,
+// which is missing from the entries of older versions.
+type entry struct {
+	*history.Record
+	Terms []string `json:"terms,omitempty"`
+}
+
+// NewFileStore loads the store in dir, creating it on first save, and
+// removes the records past retention. An empty dir keeps results in memory
+// only.
+func NewFileStore(dir string, retention Retention) (*FileStore, error) {
+	s := &FileStore{dir: dir, retention: retention}
+	if dir == "" {
+		s.results = map[string]*analysis.AnalyzeOperationResult{}
+		return s, nil
+	}
+	f, err := os.Open(filepath.Join(dir, indexFile))
+	if errors.Is(err, fs.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open analysis history: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 16<<20)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var e entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return nil, fmt.Errorf("failed to decode analysis history %s line %d: %w", f.Name(), line, err)
+		}
+		s.entries = append(s.entries, &e)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read analysis history: %w", err)
+	}
+	if err := s.prune(time.Now()); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Save implements history.Store. The result is written before the index, so
+// that indexed records always have one.
+func (s *FileStore) Save(_ context.Context, record *history.Record, result *analysis.AnalyzeOperationResult) error {
+	record.ID = strings.ToLower(rand.Text())
+	stored := *record
+	e := &entry{Record: &stored, Terms: history.Vocabulary(result)}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.dir == "" {
+		s.results[record.ID] = result
+	} else if err := s.write(e, result); err != nil {
+		return err
+	}
+	s.entries = append(s.entries, e)
+	return s.prune(time.Now())
+}
+
+// prune removes the records past retention with their results, and rewrites
+// the index without them. It must be called with mu held or before the
+// store is shared.
+func (s *FileStore) prune(now time.Time) error {
+	n := 0
+	if s.retention.MaxAge > 0 {
+		cutoff := now.Add(-s.retention.MaxAge)
+		for n < len(s.entries) && s.entries[n].Time.Before(cutoff) {
+			n++
+		}
+	}
+	if limit := s.retention.MaxRecords; limit > 0 && len(s.entries)-n > limit {
+		n = len(s.entries) - (limit - limit/10)
+	}
+	if n == 0 {
+		return nil
+	}
+	removed := s.entries[:n]
+	s.entries = slices.Clone(s.entries[n:])
+	if s.dir == "" {
+		for _, e := range removed {
+			delete(s.results, e.ID)
+		}
+		return nil
+	}
+	// The index is replaced first, so that indexed records always have a
+	// result even if removing results fails.
+	if err := s.writeIndex(); err != nil {
+		return err
+	}
+	for _, e := range removed {
+		if err := os.Remove(s.resultPath(e.ID)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("failed to remove analysis result: %w", err)
+		}
+	}
+	return nil
+}
+
+// writeIndex replaces the index with the entries in memory.
+func (s *FileStore) writeIndex() error {
+	f, err := os.CreateTemp(s.dir, indexFile+".*")
+	if err != nil {
+		return fmt.Errorf("failed to create analysis history: %w", err)
+	}
+	defer func() { _ = os.Remove(f.Name()) }()
+	w := bufio.NewWriter(f)
+	for _, e := range s.entries {
+		line, err := json.Marshal(e)
+		if err != nil {
+			_ = f.Close()
+			return fmt.Errorf("failed to encode analysis record: %w", err)
+		}
+		_, _ = w.Write(append(line, '\n'))
+	}
+	if err := w.Flush(); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("failed to write analysis history: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to write analysis history: %w", err)
+	}
+	if err := os.Rename(f.Name(), filepath.Join(s.dir, indexFile)); err != nil {
+		return fmt.Errorf("failed to replace analysis history: %w", err)
+	}
+	return nil
+}
+
+func (s *FileStore) write(e *entry, result *analysis.AnalyzeOperationResult) error {
+	data, err := json.Marshal(result)
+	if err != nil {
+		return fmt.Errorf("failed to encode analysis result: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Join(s.dir, resultsDir), 0o700); err != nil {
+		return fmt.Errorf("failed to create analysis history directory: %w", err)
+	}
+	if err := os.WriteFile(s.resultPath(e.ID), data, 0o600); err != nil {
+		return fmt.Errorf("failed to write analysis result: %w", err)
+	}
+
+	line, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("failed to encode analysis record: %w", err)
+	}
+	f, err := os.OpenFile(filepath.Join(s.dir, indexFile), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open analysis history: %w", err)
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("failed to write analysis history: %w", err)
+	}
+	return f.Close()
+}
+
+func (s *FileStore) resultPath(id string) string {
+	return filepath.Join(s.dir, resultsDir, id+".json")
+}
+
+// Get implements history.Store.
+func (s *FileStore) Get(_ context.Context, id string) (*history.Record, *analysis.AnalyzeOperationResult, error) {
+	s.mu.RLock()
+	i := slices.IndexFunc(s.entries, func(e *entry) bool { return e.ID == id })
+	var record history.Record
+	if i >= 0 {
+		record = *s.entries[i].Record
+	}
+	s.mu.RUnlock()
+	if i < 0 {
+		return nil, nil, fmt.Errorf("%w: %s", history.ErrNotFound, id)
+	}
+
+	result, err := s.result(id)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &record, result, nil
+}
+
+func (s *FileStore) result(id string) (*analysis.AnalyzeOperationResult, error) {
+	if s.dir == "" {
+		s.mu.RLock()
+		defer s.mu.RUnlock()
+		return s.results[id], nil
+	}
+	data, err := os.ReadFile(s.resultPath(id))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read analysis result: %w", err)
+	}
+	var result analysis.AnalyzeOperationResult
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, fmt.Errorf("failed to decode analysis result %s: %w", id, err)
+	}
+	return &result, nil
+}
+
+// List implements history.Store.
+func (s *FileStore) List(_ context.Context, filter history.Filter) ([]*history.Record, error) {
+	return s.selected(filter, filter.Limit, nil), nil
+}
+
+// selected returns copies of the records selected by filter whose
+// vocabulary may match terms, if any, newest first, up to limit if it's
+// positive.
+func (s *FileStore) selected(filter history.Filter, limit int, terms []string) []*history.Record {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var records []*history.Record
+	for _, e := range slices.Backward(s.entries) {
+		if limit > 0 && len(records) == limit {
+			break
+		}
+		if !filter.Matches(e.Record) {
+			continue
+		}
+		// Entries without a vocabulary are searched in their results.
+		if len(terms) > 0 && e.Terms != nil && !history.MayMatch(e.Terms, terms) {
+			continue
+		}
+		record := *e.Record
+		records = append(records, &record)
+	}
+	return records
+}
+
+// Search implements history.Store by reading the selected results whose
+// vocabulary has every term.
+func (s *FileStore) Search(ctx context.Context, query string, filter history.Filter) ([]*history.Hit, error) {
+	terms := history.Terms(query)
+	if len(terms) == 0 {
+		return nil, errors.New("empty search query")
+	}
+	var hits []*history.Hit
+	for _, record := range s.selected(filter, 0, terms) {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		result, err := s.result(record.ID)
+		if err != nil {
+			return nil, err
+		}
+		if score, snippets := history.Match(result, terms); score > 0 {
+			hits = append(hits, &history.Hit{Record: record, Score: score, Snippets: snippets})
+		}
+	}
+	// Stable, so that equal scores stay newest first.
+	slices.SortStableFunc(hits, func(a, b *history.Hit) int { return cmp.Compare(b.Score, a.Score) })
+	if filter.Limit > 0 && len(hits) > filter.Limit {
+		hits = hits[:filter.Limit]
+	}
+	return hits, nil
+}
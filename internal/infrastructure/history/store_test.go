@@ -0,0 +1,187 @@
+package history
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/linzhengen/azure-document-intelligence-mcp/internal/domain/analysis"
+	"github.com/linzhengen/azure-document-intelligence-mcp/internal/domain/history"
+)
+
+type saved struct {
+	*history.Record
+	result *analysis.AnalyzeOperationResult
+}
+
+func save(t *testing.T, s *FileStore, modelID, client, content string, at time.Time) saved {
+	t.Helper()
+	record := &history.Record{Time: at, ModelID: modelID, Client: client, Source: "https://example.com/" + modelID + ".pdf"}
+	result := &analysis.AnalyzeOperationResult{
+		Status:        "succeeded",
+		AnalyzeResult: &analysis.AnalyzeResult{ModelID: modelID, Content: content},
+	}
+	require.NoError(t, s.Save(context.Background(), record, result))
+	require.NotEmpty(t, record.ID)
+	return saved{record, result}
+}
+
+func saveRecords(t *testing.T, s *FileStore, now time.Time) []saved {
+	t.Helper()
+	return []saved{
+		save(t, s, "prebuilt-read", "claude", "Lease agreement between Contoso and Fabrikam", now.Add(-2*time.Hour)),
+		save(t, s, "prebuilt-invoice", "claude", "Invoice from Contoso, Contoso Ltd.", now.Add(-time.Hour)),
+		save(t, s, "prebuilt-read", "cursor", "Meeting notes", now),
+	}
+}
+
+func TestFileStore(t *testing.T) {
+	ctx := context.Background()
+	dir := filepath.Join(t.TempDir(), "history")
+	now := time.Date(2024, 5, 2, 10, 0, 0, 0, time.UTC)
+	s, err := NewFileStore(dir, Retention{})
+	require.NoError(t, err)
+	saved := saveRecords(t, s, now)
+
+	// Reload from disk.
+	s, err = NewFileStore(dir, Retention{})
+	require.NoError(t, err)
+
+	got, result, err := s.Get(ctx, saved[1].ID)
+	require.NoError(t, err)
+	assert.Equal(t, saved[1].Record, got)
+	assert.Equal(t, saved[1].result, result)
+
+	_, _, err = s.Get(ctx, "unknown")
+	assert.ErrorIs(t, err, history.ErrNotFound)
+
+	list, err := s.List(ctx, history.Filter{})
+	require.NoError(t, err)
+	require.Len(t, list, 3)
+	assert.Equal(t, []string{saved[2].ID, saved[1].ID, saved[0].ID}, ids(list), "newest first")
+
+	list, err = s.List(ctx, history.Filter{ModelID: "prebuilt-read", Limit: 1})
+	require.NoError(t, err)
+	assert.Equal(t, []string{saved[2].ID}, ids(list))
+	list, err = s.List(ctx, history.Filter{Client: "claude", Since: now.Add(-90 * time.Minute)})
+	require.NoError(t, err)
+	assert.Equal(t, []string{saved[1].ID}, ids(list))
+
+	hits, err := s.Search(ctx, "contoso", history.Filter{})
+	require.NoError(t, err)
+	require.Len(t, hits, 2)
+	assert.Equal(t, saved[1].ID, hits[0].ID, "the most occurrences first")
+	assert.Equal(t, 2, hits[0].Score)
+	assert.Equal(t, saved[0].ID, hits[1].ID)
+
+	hits, err = s.Search(ctx, "Contoso fabrikam", history.Filter{})
+	require.NoError(t, err)
+	require.Len(t, hits, 1)
+	assert.Equal(t, []string{"Lease agreement between Contoso and Fabrikam"}, hits[0].Snippets)
+
+	_, err = s.Search(ctx, "  ", history.Filter{})
+	assert.Error(t, err)
+}
+
+func TestFileStore_InMemory(t *testing.T) {
+	ctx := context.Background()
+	s, err := NewFileStore("", Retention{})
+	require.NoError(t, err)
+	saved := saveRecords(t, s, time.Now())
+
+	got, result, err := s.Get(ctx, saved[0].ID)
+	require.NoError(t, err)
+	assert.Equal(t, saved[0].Record, got)
+	assert.Same(t, saved[0].result, result)
+	hits, err := s.Search(ctx, "notes", history.Filter{})
+	require.NoError(t, err)
+	assert.Len(t, hits, 1)
+}
+
+func TestFileStore_SearchIndex(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	s, err := NewFileStore(dir, Retention{})
+	require.NoError(t, err)
+	saved := saveRecords(t, s, time.Now())
+
+	// Results whose vocabulary can't match aren't read.
+	require.NoError(t, os.Remove(s.resultPath(saved[2].ID)))
+	hits, err := s.Search(ctx, "contoso", history.Filter{})
+	require.NoError(t, err)
+	assert.Len(t, hits, 2)
+
+	// Entries saved without a vocabulary are searched in their results.
+	index := filepath.Join(dir, indexFile)
+	data, err := os.ReadFile(index)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(index, regexp.MustCompile(`,"terms":\[[^]]*\]`).ReplaceAll(data, nil), 0o600))
+	s, err = NewFileStore(dir, Retention{})
+	require.NoError(t, err)
+	hits, err = s.Search(ctx, "fabrikam", history.Filter{Client: "claude"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{saved[0].ID}, hitIDs(hits))
+}
+
+func TestFileStore_Retention(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	now := time.Now()
+	s, err := NewFileStore(dir, Retention{MaxRecords: 20})
+	require.NoError(t, err)
+	var all []saved
+	for i := range 21 {
+		all = append(all, save(t, s, "prebuilt-read", "claude", "Meeting notes", now.Add(time.Duration(i-21)*time.Hour)))
+	}
+
+	list, err := s.List(ctx, history.Filter{})
+	require.NoError(t, err)
+	assert.Len(t, list, 18, "the oldest tenth is removed at once")
+	_, _, err = s.Get(ctx, all[2].ID)
+	assert.ErrorIs(t, err, history.ErrNotFound)
+	assert.NoFileExists(t, s.resultPath(all[2].ID))
+	assert.FileExists(t, s.resultPath(all[3].ID))
+
+	// Reloading removes the records past their age.
+	s, err = NewFileStore(dir, Retention{MaxAge: 5 * time.Hour})
+	require.NoError(t, err)
+	list, err = s.List(ctx, history.Filter{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{all[20].ID, all[19].ID, all[18].ID, all[17].ID}, ids(list))
+	assert.NoFileExists(t, s.resultPath(all[16].ID))
+	s, err = NewFileStore(dir, Retention{})
+	require.NoError(t, err)
+	list, err = s.List(ctx, history.Filter{})
+	require.NoError(t, err)
+	assert.Len(t, list, 4, "the index is rewritten")
+
+	memory, err := NewFileStore("", Retention{MaxRecords: 2})
+	require.NoError(t, err)
+	saved := saveRecords(t, memory, now)
+	list, err = memory.List(ctx, history.Filter{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{saved[2].ID, saved[1].ID}, ids(list))
+	assert.NotContains(t, memory.results, saved[0].ID)
+}
+
+func hitIDs(hits []*history.Hit) []string {
+	var ids []string
+	for _, h := range hits {
+		ids = append(ids, h.ID)
+	}
+	return ids
+}
+
+func ids(records []*history.Record) []string {
+	var ids []string
+	for _, r := range records {
+		ids = append(ids, r.ID)
+	}
+	return ids
+}
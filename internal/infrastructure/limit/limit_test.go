@@ -0,0 +1,143 @@
+package limit
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type admission struct {
+	key     string
+	release func()
+}
+
+// enqueue starts an analysis of key, which is reported on admitted once it
+// has a slot, and waits until it's queued.
+func enqueue(t *testing.T, q *FairQueue, key string, admitted chan<- admission) {
+	t.Helper()
+	depth := q.Depth()
+	go func() {
+		release, err := q.Acquire(context.Background(), key)
+		if err == nil {
+			admitted <- admission{key: key, release: release}
+		}
+	}()
+	require.Eventually(t, func() bool { return q.Depth() > depth }, time.Second, time.Millisecond)
+}
+
+func TestFairQueue_TakesTurnsAcrossKeys(t *testing.T) {
+	q := NewFairQueue(1)
+	release, err := q.Acquire(context.Background(), "a")
+	require.NoError(t, err)
+
+	admitted := make(chan admission)
+	for _, key := range []string{"a", "a", "a", "b", "c"} {
+		enqueue(t, q, key, admitted)
+	}
+	assert.Equal(t, 5, q.Depth())
+
+	var order []string
+	for range 5 {
+		release()
+		next := <-admitted
+		order = append(order, next.key)
+		release = next.release
+	}
+	release()
+
+	assert.Equal(t, []string{"a", "b", "c", "a", "a"}, order)
+	assert.Equal(t, 0, q.Depth())
+}
+
+func TestFairQueue_Canceled(t *testing.T) {
+	q := NewFairQueue(1)
+	release, err := q.Acquire(context.Background(), "a")
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errs := make(chan error)
+	go func() {
+		_, err := q.Acquire(ctx, "b")
+		errs <- err
+	}()
+	require.Eventually(t, func() bool { return q.Depth() == 1 }, time.Second, time.Millisecond)
+	cancel()
+
+	assert.ErrorIs(t, <-errs, context.Canceled)
+	assert.Equal(t, 0, q.Depth())
+	release()
+	release() // releasing twice frees one slot only
+	_, err = q.Acquire(context.Background(), "c")
+	require.NoError(t, err)
+	assert.Equal(t, 1, q.active)
+}
+
+func TestFairQueue_Unlimited(t *testing.T) {
+	q := NewFairQueue(0)
+	for range 100 {
+		_, err := q.Acquire(context.Background(), "a")
+		require.NoError(t, err)
+	}
+	assert.Equal(t, 0, q.Depth())
+}
+
+func TestTokenBucket(t *testing.T) {
+	now := time.Now()
+	b := NewTokenBucket(2, 3)
+	b.now = func() time.Time { return now }
+	b.last = now
+
+	for range 3 {
+		assert.Zero(t, b.reserve(), "the burst is available at once")
+	}
+	assert.Equal(t, 500*time.Millisecond, b.reserve())
+	assert.Equal(t, time.Second, b.reserve(), "waiters queue behind each other")
+
+	now = now.Add(1500 * time.Millisecond)
+	assert.Zero(t, b.reserve(), "the debt is paid off")
+	assert.Equal(t, 500*time.Millisecond, b.reserve())
+}
+
+func TestTokenBucket_WaitCanceled(t *testing.T) {
+	b := NewTokenBucket(0.001, 1)
+	_, err := b.Wait(context.Background())
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = b.Wait(ctx)
+
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.InDelta(t, 0, b.tokens, 0.01, "the token of a canceled wait is given back")
+}
+
+type countingClient struct{ requests int }
+
+func (c *countingClient) Do(*http.Request) (*http.Response, error) {
+	c.requests++
+	return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+}
+
+func TestRateLimitClient(t *testing.T) {
+	next := &countingClient{}
+	client := RateLimitClient(next, Rates{Initiate: 1000, Poll: 0}).(*rateLimitClient)
+
+	post, err := http.NewRequest(http.MethodPost, "http://a.test/documentintelligence/documentModels/prebuilt-read:analyze", nil)
+	require.NoError(t, err)
+	poll, err := http.NewRequest(http.MethodGet, "http://a.test/documentintelligence/documentModels/prebuilt-read/analyzeResults/1", nil)
+	require.NoError(t, err)
+	other, err := http.NewRequest(http.MethodPost, "http://b.test/documentintelligence/documentModels/prebuilt-read:analyze", nil)
+	require.NoError(t, err)
+	for _, req := range []*http.Request{post, post, poll, other} {
+		_, err := client.Do(req)
+		require.NoError(t, err)
+	}
+
+	assert.Equal(t, 4, next.requests)
+	assert.Len(t, client.buckets, 2, "analyze requests are limited per host, polling is unlimited")
+	assert.Nil(t, client.bucket("a.test", "poll"))
+}
@@ -0,0 +1,135 @@
+// Package limit keeps the load sent to Document Intelligence within the
+// limits of its pricing tier: a fair queue bounds the analyses in progress,
+// and token buckets bound the rate of analyze and polling requests.
+package limit
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/linzhengen/azure-document-intelligence-mcp/internal/logging"
+)
+
+// FairQueue bounds the number of analyses in progress. When all slots are
+// taken, waiting analyses are admitted in turn across keys, such as MCP
+// sessions, and in arrival order within a key, so that a session sending a
+// batch doesn't starve the others.
+type FairQueue struct {
+	capacity int
+
+	mu      sync.Mutex
+	active  int
+	waiting map[string][]*waiter
+	keys    []string // keys with waiters, in turn order
+	next    int      // index in keys of the next key to admit
+	depth   int
+}
+
+type waiter struct {
+	ready    chan struct{}
+	admitted bool
+}
+
+// NewFairQueue returns a queue admitting up to capacity analyses at a time.
+// A capacity of zero or less admits everything at once.
+func NewFairQueue(capacity int) *FairQueue {
+	return &FairQueue{capacity: capacity, waiting: map[string][]*waiter{}}
+}
+
+// Acquire waits for a slot for an analysis of key, until ctx is done. The
+// returned function frees the slot and must be called once the analysis ends.
+func (q *FairQueue) Acquire(ctx context.Context, key string) (release func(), err error) {
+	q.mu.Lock()
+	if q.capacity <= 0 || (q.active < q.capacity && q.depth == 0) {
+		q.active++
+		q.mu.Unlock()
+		return q.releaseFunc(), nil
+	}
+	w := &waiter{ready: make(chan struct{})}
+	if len(q.waiting[key]) == 0 {
+		q.keys = append(q.keys, key)
+	}
+	q.waiting[key] = append(q.waiting[key], w)
+	q.depth++
+	depth, active := q.depth, q.active
+	q.mu.Unlock()
+
+	logger := logging.FromContext(ctx)
+	logger.InfoContext(ctx, "analysis queued", "queueDepth", depth, "inProgress", active)
+	start := time.Now()
+	select {
+	case <-w.ready:
+		logger.DebugContext(ctx, "analysis admitted", "waited", time.Since(start))
+		return q.releaseFunc(), nil
+	case <-ctx.Done():
+		q.mu.Lock()
+		defer q.mu.Unlock()
+		if w.admitted {
+			// Admitted while giving up: pass the slot on.
+			q.active--
+			q.admit()
+		} else {
+			q.remove(key, w)
+		}
+		return nil, ctx.Err()
+	}
+}
+
+// Depth returns the number of analyses waiting for a slot.
+func (q *FairQueue) Depth() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.depth
+}
+
+func (q *FairQueue) releaseFunc() func() {
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			q.mu.Lock()
+			defer q.mu.Unlock()
+			q.active--
+			q.admit()
+		})
+	}
+}
+
+// admit hands the free slots to the waiters, one key at a time. q.mu must be held.
+func (q *FairQueue) admit() {
+	for q.active < q.capacity && len(q.keys) > 0 {
+		if q.next >= len(q.keys) {
+			q.next = 0
+		}
+		key := q.keys[q.next]
+		w := q.waiting[key][0]
+		q.waiting[key] = q.waiting[key][1:]
+		if len(q.waiting[key]) == 0 {
+			// The next key moves into this index.
+			delete(q.waiting, key)
+			q.keys = slices.Delete(q.keys, q.next, q.next+1)
+		} else {
+			q.next++
+		}
+		q.depth--
+		q.active++
+		w.admitted = true
+		close(w.ready)
+	}
+}
+
+// remove drops a waiter that gave up. q.mu must be held.
+func (q *FairQueue) remove(key string, w *waiter) {
+	q.waiting[key] = slices.DeleteFunc(q.waiting[key], func(o *waiter) bool { return o == w })
+	q.depth--
+	if len(q.waiting[key]) > 0 {
+		return
+	}
+	delete(q.waiting, key)
+	i := slices.Index(q.keys, key)
+	q.keys = slices.Delete(q.keys, i, i+1)
+	if i < q.next {
+		q.next--
+	}
+}
@@ -0,0 +1,134 @@
+package limit
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"sync"
+	"time"
+
+	analysisinfra "github.com/linzhengen/azure-document-intelligence-mcp/internal/infrastructure/analysis"
+	"github.com/linzhengen/azure-document-intelligence-mcp/internal/logging"
+)
+
+// TokenBucket is a rate limiter allowing rate events per second on average,
+// and bursts of up to burst events.
+type TokenBucket struct {
+	rate  float64
+	burst float64
+	now   func() time.Time
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+// NewTokenBucket returns a full bucket. A burst of zero or less is set to
+// the rate, rounded up.
+func NewTokenBucket(rate float64, burst int) *TokenBucket {
+	if burst <= 0 {
+		burst = int(math.Ceil(rate))
+	}
+	b := &TokenBucket{rate: rate, burst: float64(burst), now: time.Now}
+	b.tokens, b.last = b.burst, b.now()
+	return b
+}
+
+// Wait takes a token, waiting until one is available or ctx is done, and
+// returns how long it waited.
+func (b *TokenBucket) Wait(ctx context.Context) (time.Duration, error) {
+	delay := b.reserve()
+	if delay <= 0 {
+		return 0, nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return delay, nil
+	case <-ctx.Done():
+		b.mu.Lock()
+		b.tokens++
+		b.mu.Unlock()
+		return 0, ctx.Err()
+	}
+}
+
+// reserve takes a token, possibly going into debt, and returns the time
+// until the debt is paid off.
+func (b *TokenBucket) reserve() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := b.now()
+	b.tokens = min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+	b.tokens--
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+// Rates are the requests per second allowed to each endpoint. Zero means no limit.
+type Rates struct {
+	Initiate float64
+	Poll     float64
+}
+
+// RateLimitClient returns an HTTPClient that delays analyze and polling
+// requests to keep each endpoint host within rates. Other requests aren't
+// limited.
+func RateLimitClient(next analysisinfra.HTTPClient, rates Rates) analysisinfra.HTTPClient {
+	return &rateLimitClient{next: next, rates: rates, buckets: map[bucketKey]*TokenBucket{}}
+}
+
+type bucketKey struct {
+	host      string
+	operation string
+}
+
+type rateLimitClient struct {
+	next  analysisinfra.HTTPClient
+	rates Rates
+
+	mu      sync.Mutex
+	buckets map[bucketKey]*TokenBucket
+}
+
+func (c *rateLimitClient) Do(req *http.Request) (*http.Response, error) {
+	operation, _ := analysisinfra.RequestOperation(req)
+	if bucket := c.bucket(req.URL.Host, operation); bucket != nil {
+		ctx := req.Context()
+		waited, err := bucket.Wait(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if waited > 0 {
+			logging.FromContext(ctx).DebugContext(ctx, "request rate limited", "operation", operation, "endpoint", req.URL.Host, "waited", waited)
+		}
+	}
+	return c.next.Do(req)
+}
+
+// bucket returns the bucket of the operation on host, or nil if it's not limited.
+func (c *rateLimitClient) bucket(host, operation string) *TokenBucket {
+	var rate float64
+	switch operation {
+	case analysisinfra.OperationInitiate:
+		rate = c.rates.Initiate
+	case analysisinfra.OperationPoll:
+		rate = c.rates.Poll
+	}
+	if rate <= 0 {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	key := bucketKey{host: host, operation: operation}
+	b, ok := c.buckets[key]
+	if !ok {
+		b = NewTokenBucket(rate, 0)
+		c.buckets[key] = b
+	}
+	return b
+}
@@ -0,0 +1,214 @@
+// Package metrics instruments the Document Intelligence client with
+// Prometheus metrics, so that throttling and page consumption can be alerted on.
+//
+// Metrics wraps the two boundaries of the analysis infrastructure: the
+// HTTPClient used to talk to Azure, which sees every initiate and polling
+// request, and the analysis.Repository, which sees whole analyses.
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/linzhengen/azure-document-intelligence-mcp/internal/domain/analysis"
+	analysisinfra "github.com/linzhengen/azure-document-intelligence-mcp/internal/infrastructure/analysis"
+)
+
+const namespace = "docintel"
+
+// Outcomes of an analysis.
+const (
+	OutcomeSucceeded = "succeeded"
+	OutcomeFailed    = "failed"
+	OutcomeCanceled  = "canceled"
+)
+
+// Metrics holds the collectors of the server, registered on their own registry.
+type Metrics struct {
+	registry *prometheus.Registry
+
+	analyses        *prometheus.CounterVec
+	pages           *prometheus.CounterVec
+	bytesUploaded   *prometheus.CounterVec
+	analysisLatency *prometheus.HistogramVec
+	initiateLatency *prometheus.HistogramVec
+	pollIterations  *prometheus.HistogramVec
+	azureRequests   *prometheus.CounterVec
+	localAnalyses   *prometheus.CounterVec
+	sharedAnalyses  *prometheus.CounterVec
+	inFlight        prometheus.Gauge
+}
+
+// New creates the collectors and registers them, along with the Go runtime
+// and process collectors, on a new registry.
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		analyses: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "analyses_total",
+			Help:      "Document analyses by model and outcome.",
+		}, []string{"model", "outcome"}),
+		pages: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "pages_processed_total",
+			Help:      "Pages of successfully analyzed documents, by model.",
+		}, []string{"model"}),
+		bytesUploaded: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "uploaded_bytes_total",
+			Help:      "Bytes of document content sent to the service, by model.",
+		}, []string{"model"}),
+		analysisLatency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "analysis_duration_seconds",
+			Help:      "Total duration of document analyses, including polling, by model.",
+			Buckets:   []float64{1, 2.5, 5, 10, 20, 30, 60, 120, 300},
+		}, []string{"model"}),
+		initiateLatency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "initiate_duration_seconds",
+			Help:      "Duration of analyze requests, until the service accepted or rejected the document, by model.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"model"}),
+		pollIterations: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "poll_iterations",
+			Help:      "Polling requests per analysis, by model.",
+			Buckets:   []float64{1, 2, 3, 5, 8, 10},
+		}, []string{"model"}),
+		azureRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "azure_http_requests_total",
+			Help:      "HTTP requests to Azure by operation (initiate, poll or other) and status code. Throttled requests have code 429.",
+		}, []string{"operation", "code"}),
+		localAnalyses: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "local_analyses_total",
+			Help:      "Analyses answered from the text layer of PDFs without calling the service, by model.",
+		}, []string{"model"}),
+		sharedAnalyses: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "shared_analyses_total",
+			Help:      "Requests answered by an identical analysis already in progress, by model.",
+		}, []string{"model"}),
+		inFlight: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "analyses_in_flight",
+			Help:      "Analyses currently in progress.",
+		}),
+	}
+	m.registry.MustRegister(
+		m.analyses, m.pages, m.bytesUploaded, m.analysisLatency, m.initiateLatency,
+		m.pollIterations, m.azureRequests, m.localAnalyses, m.sharedAnalyses, m.inFlight,
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	return m
+}
+
+// Handler serves the metrics in the Prometheus exposition format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// LocalAnalysis records an analysis with the model answered from the text
+// layer of a PDF.
+func (m *Metrics) LocalAnalysis(modelID string) {
+	m.localAnalyses.WithLabelValues(modelID).Inc()
+}
+
+// SharedAnalysis records a request with the model answered by an identical
+// analysis already in progress.
+func (m *Metrics) SharedAnalysis(modelID string) {
+	m.sharedAnalyses.WithLabelValues(modelID).Inc()
+}
+
+// WatchQueue reports the number of analyses waiting for a slot, as returned
+// by depth, in the queue_depth gauge.
+func (m *Metrics) WatchQueue(depth func() int) {
+	m.registry.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "queue_depth",
+		Help:      "Analyses waiting for a concurrency slot.",
+	}, func() float64 { return float64(depth()) }))
+}
+
+// WrapClient returns an HTTPClient that records the status code of every
+// request to Azure and the latency of analyze requests.
+func (m *Metrics) WrapClient(next analysisinfra.HTTPClient) analysisinfra.HTTPClient {
+	return &client{next: next, metrics: m}
+}
+
+// WrapRepository returns a repository that records every analysis.
+func (m *Metrics) WrapRepository(next analysis.Repository) analysis.Repository {
+	return &repository{next: next, metrics: m}
+}
+
+type client struct {
+	next    analysisinfra.HTTPClient
+	metrics *Metrics
+}
+
+func (c *client) Do(req *http.Request) (*http.Response, error) {
+	operation, modelID := analysisinfra.RequestOperation(req)
+	if operation == analysisinfra.OperationPoll {
+		if polls, ok := req.Context().Value(pollsKey{}).(*int); ok {
+			*polls++
+		}
+	}
+	start := time.Now()
+	resp, err := c.next.Do(req)
+	if operation == analysisinfra.OperationInitiate {
+		c.metrics.initiateLatency.WithLabelValues(modelID).Observe(time.Since(start).Seconds())
+	}
+	code := "error"
+	if err == nil {
+		code = strconv.Itoa(resp.StatusCode)
+	}
+	c.metrics.azureRequests.WithLabelValues(operation, code).Inc()
+	return resp, err
+}
+
+// pollsKey carries a poll counter from the repository to the client, so that
+// polling requests are attributed to the analysis that sent them.
+type pollsKey struct{}
+
+type repository struct {
+	next    analysis.Repository
+	metrics *Metrics
+}
+
+func (r *repository) AnalyzeDocument(ctx context.Context, modelID string, options analysis.AnalyzeDocumentOptions) (*analysis.AnalyzeOperationResult, error) {
+	m := r.metrics
+	m.inFlight.Inc()
+	defer m.inFlight.Dec()
+	m.bytesUploaded.WithLabelValues(modelID).Add(float64(len(options.Content)))
+
+	var polls int
+	start := time.Now()
+	result, err := r.next.AnalyzeDocument(context.WithValue(ctx, pollsKey{}, &polls), modelID, options)
+	m.analysisLatency.WithLabelValues(modelID).Observe(time.Since(start).Seconds())
+	if polls > 0 {
+		m.pollIterations.WithLabelValues(modelID).Observe(float64(polls))
+	}
+
+	switch {
+	case err != nil && ctx.Err() != nil:
+		m.analyses.WithLabelValues(modelID, OutcomeCanceled).Inc()
+	case err != nil:
+		m.analyses.WithLabelValues(modelID, OutcomeFailed).Inc()
+	default:
+		m.analyses.WithLabelValues(modelID, OutcomeSucceeded).Inc()
+		if result != nil && result.AnalyzeResult != nil {
+			m.pages.WithLabelValues(modelID).Add(float64(len(result.AnalyzeResult.Pages)))
+		}
+	}
+	return result, err
+}
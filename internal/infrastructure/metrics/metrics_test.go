@@ -0,0 +1,77 @@
+package metrics
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/linzhengen/azure-document-intelligence-mcp/internal/domain/analysis"
+	analysisinfra "github.com/linzhengen/azure-document-intelligence-mcp/internal/infrastructure/analysis"
+	"github.com/linzhengen/azure-document-intelligence-mcp/internal/infrastructure/analysis/fake"
+)
+
+func newInstrumentedRepository(m *Metrics, server *fake.Server) analysis.Repository {
+	repo := analysisinfra.NewRepositoryWithClient(server.URL, "dummy-key", m.WrapClient(server.Client()))
+	return m.WrapRepository(repo)
+}
+
+func TestMetrics_Analysis(t *testing.T) {
+	ctx := context.Background()
+	server := fake.NewServer("dummy-key")
+	defer server.Close()
+	m := New()
+	repo := newInstrumentedRepository(m, server)
+
+	options := analysis.AnalyzeDocumentOptions{Content: []byte("%PDF-1.7"), ContentType: "application/pdf"}
+	result, err := repo.AnalyzeDocument(ctx, "prebuilt-read", options)
+	require.NoError(t, err)
+
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.analyses.WithLabelValues("prebuilt-read", OutcomeSucceeded)))
+	assert.Equal(t, float64(len(result.AnalyzeResult.Pages)), testutil.ToFloat64(m.pages.WithLabelValues("prebuilt-read")))
+	assert.Equal(t, 8.0, testutil.ToFloat64(m.bytesUploaded.WithLabelValues("prebuilt-read")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.azureRequests.WithLabelValues("initiate", "202")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.azureRequests.WithLabelValues("poll", "200")))
+	assert.Equal(t, 1, testutil.CollectAndCount(m.initiateLatency))
+	assert.Equal(t, 1, testutil.CollectAndCount(m.pollIterations))
+	assert.Equal(t, 0.0, testutil.ToFloat64(m.inFlight))
+}
+
+func TestMetrics_Throttled(t *testing.T) {
+	ctx := context.Background()
+	server := fake.NewServer("dummy-key")
+	defer server.Close()
+	server.SetScenario("prebuilt-read", fake.Scenario{Throttle: 1})
+	m := New()
+	repo := newInstrumentedRepository(m, server)
+
+	_, err := repo.AnalyzeDocument(ctx, "prebuilt-read", analysis.AnalyzeDocumentOptions{DocURL: "http://test.com/doc.pdf"})
+	require.Error(t, err)
+
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.analyses.WithLabelValues("prebuilt-read", OutcomeFailed)))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.azureRequests.WithLabelValues("initiate", "429")))
+	assert.Equal(t, 0, testutil.CollectAndCount(m.pollIterations))
+}
+
+func TestMetrics_Handler(t *testing.T) {
+	m := New()
+	m.LocalAnalysis("prebuilt-read")
+	m.SharedAnalysis("prebuilt-read")
+	m.WatchQueue(func() int { return 3 })
+
+	rec := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	body, err := io.ReadAll(rec.Body)
+	require.NoError(t, err)
+	assert.Contains(t, string(body), `docintel_local_analyses_total{model="prebuilt-read"} 1`)
+	assert.Contains(t, string(body), `docintel_shared_analyses_total{model="prebuilt-read"} 1`)
+	assert.Contains(t, string(body), "docintel_analyses_in_flight 0")
+	assert.Contains(t, string(body), "docintel_queue_depth 3")
+}
@@ -0,0 +1,102 @@
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testPDF returns a letter-size PDF with a page per element of pages, whose
+// Latin-1 lines are written in 12pt Helvetica from the top-left margin, 20pt apart.
+func testPDF(t *testing.T, pages ...[]string) []byte {
+	t.Helper()
+	var objects []string
+	kids := make([]string, len(pages))
+	for i, lines := range pages {
+		page, content := 4+2*i, 5+2*i
+		kids[i] = fmt.Sprintf("%d 0 R", page)
+		var stream strings.Builder
+		stream.WriteString("BT /F1 12 Tf 72 720 Td")
+		for j, line := range lines {
+			if j > 0 {
+				stream.WriteString(" 0 -20 Td")
+			}
+			// Strings are in the WinAnsi encoding of the font, Latin-1 here.
+			var encoded []byte
+			for _, r := range strings.NewReplacer(`\`, `\\`, "(", `\(`, ")", `\)`).Replace(line) {
+				encoded = append(encoded, byte(r))
+			}
+			fmt.Fprintf(&stream, " (%s) Tj", encoded)
+		}
+		stream.WriteString(" ET")
+		objects = append(objects,
+			fmt.Sprintf("<< /Type /Page /Parent 2 0 R /Resources << /Font << /F1 3 0 R >> >> /Contents %d 0 R >>", content),
+			fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", stream.Len(), stream.String()),
+		)
+	}
+	widths := strings.TrimSpace(strings.Repeat("500 ", 126-32+1))
+	objects = append([]string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d /MediaBox [0 0 612 792] >>", strings.Join(kids, " "), len(pages)),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding /FirstChar 32 /LastChar 126 /Widths [" + widths + "] >>",
+	}, objects...)
+
+	var b bytes.Buffer
+	b.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = b.Len()
+		fmt.Fprintf(&b, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	xref := b.Len()
+	fmt.Fprintf(&b, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&b, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&b, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	return b.Bytes()
+}
+
+func TestIsPDF(t *testing.T) {
+	assert.True(t, IsPDF([]byte("%PDF-1.7\n")))
+	assert.True(t, IsPDF([]byte("\xef\xbb\xbf%PDF-1.4")))
+	assert.False(t, IsPDF([]byte("\x89PNG\r\n")))
+	assert.False(t, IsPDF(nil))
+}
+
+func TestDocument_Text(t *testing.T) {
+	doc, err := Open(testPDF(t, []string{"Hello  world", "(second) line"}, nil))
+	require.NoError(t, err)
+	n, err := doc.NumPages()
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+
+	page, err := doc.Text(1)
+	require.NoError(t, err)
+	assert.Equal(t, 1, page.Number)
+	assert.InDelta(t, 8.5, page.Width, 1e-9, "the media box is inherited from the page tree")
+	assert.InDelta(t, 11, page.Height, 1e-9)
+	require.Len(t, page.Lines, 2)
+	assert.Equal(t, "Hello world", page.Lines[0].Text, "whitespace is collapsed")
+	assert.Equal(t, "(second) line", page.Lines[1].Text)
+
+	// 12 glyphs 6pt wide from 1in, with a 12pt font on a baseline at 720pt.
+	want := []float64{1, 62.4 / 72, 2, 62.4 / 72, 2, 74.4 / 72, 1, 74.4 / 72}
+	assert.InDeltaSlice(t, want, page.Lines[0].Polygon, 1e-9)
+
+	page, err = doc.Text(2)
+	require.NoError(t, err)
+	assert.Empty(t, page.Lines)
+
+	_, err = doc.Text(3)
+	assert.Error(t, err)
+}
+
+func TestOpen_Malformed(t *testing.T) {
+	_, err := Open([]byte("%PDF-1.4\ngarbage"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,175 @@
+package pdf
+
+import (
+	"context"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/linzhengen/azure-document-intelligence-mcp/internal/domain/analysis"
+	"github.com/linzhengen/azure-document-intelligence-mcp/internal/logging"
+)
+
+const (
+	// readModel is the model whose results can be produced from a text layer.
+	readModel = "prebuilt-read"
+	// minPageText is the number of letters and digits a page needs for its
+	// text layer to count, so that scans with a stamp or page number in
+	// text are still sent to the service.
+	minPageText = 16
+)
+
+// TextLayer returns a repository that answers prebuilt-read analyses of
+// uploaded born-digital PDFs from their text layer, without calling next,
+// when at least minCoverage of the requested pages have text. The result
+// holds the content, pages, lines and their spans, and is marked with
+// analysis.SourceLocal. Other analyses, and PDFs that can't be read, are
+// passed to next. onLocal, if not nil, is called for every local result.
+func TextLayer(next analysis.Repository, minCoverage float64, onLocal func(modelID string)) analysis.Repository {
+	return &textLayer{next: next, minCoverage: minCoverage, onLocal: onLocal}
+}
+
+type textLayer struct {
+	next        analysis.Repository
+	minCoverage float64
+	onLocal     func(modelID string)
+}
+
+func (t *textLayer) AnalyzeDocument(ctx context.Context, modelID string, options analysis.AnalyzeDocumentOptions) (*analysis.AnalyzeOperationResult, error) {
+	if modelID != readModel || !IsPDF(options.Content) {
+		return t.next.AnalyzeDocument(ctx, modelID, options)
+	}
+	logger := logging.FromContext(ctx)
+	pages, err := readText(ctx, options)
+	if err != nil {
+		logger.DebugContext(ctx, "failed to read PDF text layer", "error", err)
+		return t.next.AnalyzeDocument(ctx, modelID, options)
+	}
+	coverage := textCoverage(pages)
+	if coverage < t.minCoverage {
+		logger.DebugContext(ctx, "PDF text layer too sparse", "pages", len(pages), "coverage", coverage)
+		return t.next.AnalyzeDocument(ctx, modelID, options)
+	}
+	logger.InfoContext(ctx, "read PDF text layer without calling the service", "modelId", modelID, "pages", len(pages), "coverage", coverage)
+	if t.onLocal != nil {
+		t.onLocal(modelID)
+	}
+	now := time.Now().UTC().Format(time.RFC3339)
+	return &analysis.AnalyzeOperationResult{
+		Status:              "succeeded",
+		CreatedDateTime:     now,
+		LastUpdatedDateTime: now,
+		AnalyzeResult:       newResult(modelID, pages),
+		Source:              analysis.SourceLocal,
+	}, nil
+}
+
+// readText reads the text layer of the pages selected by options.
+func readText(ctx context.Context, options analysis.AnalyzeDocumentOptions) ([]*Page, error) {
+	doc, err := Open(options.Content)
+	if err != nil {
+		return nil, err
+	}
+	total, err := doc.NumPages()
+	if err != nil {
+		return nil, err
+	}
+	numbers, err := analysis.PageNumbers(options.Pages, total)
+	if err != nil {
+		return nil, err
+	}
+	pages := make([]*Page, 0, len(numbers))
+	for _, n := range numbers {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		page, err := doc.Text(n)
+		if err != nil {
+			return nil, err
+		}
+		pages = append(pages, page)
+	}
+	return pages, nil
+}
+
+// textCoverage returns the share of pages with enough text, and 0 without pages.
+func textCoverage(pages []*Page) float64 {
+	if len(pages) == 0 {
+		return 0
+	}
+	covered := 0
+	for _, p := range pages {
+		n := 0
+		for _, line := range p.Lines {
+			for _, r := range line.Text {
+				if unicode.IsLetter(r) || unicode.IsDigit(r) {
+					n++
+				}
+			}
+		}
+		if n >= minPageText {
+			covered++
+		}
+	}
+	return float64(covered) / float64(len(pages))
+}
+
+// newResult returns the result the read model gives for pages: their lines
+// joined by line breaks as content, with offsets in code points.
+func newResult(modelID string, pages []*Page) *analysis.AnalyzeResult {
+	result := &analysis.AnalyzeResult{
+		ModelID:         modelID,
+		StringIndexType: "unicodeCodePoint",
+		Pages:           make([]analysis.Page, 0, len(pages)),
+	}
+	var content []byte
+	var offset int32
+	for _, p := range pages {
+		angle, unit := float32(0), "inch"
+		page := analysis.Page{
+			PageNumber: int32(p.Number),
+			Angle:      &angle,
+			Width:      float32Ptr(p.Width),
+			Height:     float32Ptr(p.Height),
+			Unit:       &unit,
+			Spans:      []analysis.Span{},
+		}
+		start := offset
+		for _, line := range p.Lines {
+			if len(content) > 0 {
+				content = append(content, '\n')
+				offset++
+			}
+			if len(page.Lines) == 0 {
+				start = offset
+			}
+			span := analysis.Span{Offset: offset, Length: int32(utf8.RuneCountInString(line.Text))}
+			content = append(content, line.Text...)
+			offset += span.Length
+			page.Lines = append(page.Lines, &analysis.Line{
+				Content: line.Text,
+				Polygon: float32s(line.Polygon),
+				Spans:   []analysis.Span{span},
+			})
+		}
+		if len(page.Lines) > 0 {
+			page.Spans = []analysis.Span{{Offset: start, Length: offset - start}}
+		}
+		result.Pages = append(result.Pages, page)
+	}
+	result.Content = string(content)
+	return result
+}
+
+func float32Ptr(f float64) *float32 {
+	v := float32(f)
+	return &v
+}
+
+func float32s(values []float64) []float32 {
+	out := make([]float32, len(values))
+	for i, v := range values {
+		out[i] = float32(v)
+	}
+	return out
+}
@@ -0,0 +1,84 @@
+package pdf
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/linzhengen/azure-document-intelligence-mcp/internal/domain/analysis"
+)
+
+// countingRepository counts the analyses it's asked for.
+type countingRepository struct {
+	calls int
+}
+
+func (r *countingRepository) AnalyzeDocument(context.Context, string, analysis.AnalyzeDocumentOptions) (*analysis.AnalyzeOperationResult, error) {
+	r.calls++
+	return &analysis.AnalyzeOperationResult{Status: "succeeded"}, nil
+}
+
+func TestTextLayer(t *testing.T) {
+	ctx := context.Background()
+	next := &countingRepository{}
+	var local []string
+	repo := TextLayer(next, 0.9, func(modelID string) { local = append(local, modelID) })
+	doc := testPDF(t,
+		[]string{"Lease agreement", "between Contoso and Fabrikam"},
+		[]string{"Signed on 2024-05-02 in Zürich"},
+	)
+
+	result, err := repo.AnalyzeDocument(ctx, "prebuilt-read", analysis.AnalyzeDocumentOptions{Content: doc, ContentType: "application/pdf"})
+	require.NoError(t, err)
+	assert.Zero(t, next.calls)
+	assert.Equal(t, analysis.SourceLocal, result.Source)
+	assert.Equal(t, "succeeded", result.Status)
+
+	r := result.AnalyzeResult
+	assert.Equal(t, "prebuilt-read", r.ModelID)
+	assert.Equal(t, "unicodeCodePoint", r.StringIndexType)
+	assert.Equal(t, "Lease agreement\nbetween Contoso and Fabrikam\nSigned on 2024-05-02 in Zürich", r.Content)
+	require.Len(t, r.Pages, 2)
+	assert.Equal(t, int32(2), r.Pages[1].PageNumber)
+	assert.Equal(t, "inch", *r.Pages[1].Unit)
+	assert.Equal(t, []analysis.Span{{Offset: 0, Length: 44}}, r.Pages[0].Spans)
+	assert.Equal(t, []analysis.Span{{Offset: 45, Length: 30}}, r.Pages[1].Spans, "offsets count code points")
+	require.Len(t, r.Pages[0].Lines, 2)
+	assert.Equal(t, "between Contoso and Fabrikam", r.Pages[0].Lines[1].Content)
+	assert.Equal(t, []analysis.Span{{Offset: 16, Length: 28}}, r.Pages[0].Lines[1].Spans)
+	assert.Len(t, r.Pages[0].Lines[1].Polygon, 8)
+
+	result, err = repo.AnalyzeDocument(ctx, "prebuilt-read", analysis.AnalyzeDocumentOptions{Content: doc, Pages: "2"})
+	require.NoError(t, err)
+	assert.Equal(t, "Signed on 2024-05-02 in Zürich", result.AnalyzeResult.Content, "only the requested pages are read")
+	assert.Equal(t, []analysis.Span{{Offset: 0, Length: 30}}, result.AnalyzeResult.Pages[0].Spans)
+	assert.Zero(t, next.calls)
+	assert.Equal(t, []string{"prebuilt-read", "prebuilt-read"}, local)
+}
+
+func TestTextLayer_CallsNext(t *testing.T) {
+	doc := testPDF(t, []string{"Lease agreement between Contoso and Fabrikam"})
+	tests := []struct {
+		name    string
+		modelID string
+		options analysis.AnalyzeDocumentOptions
+	}{
+		{"other model", "prebuilt-layout", analysis.AnalyzeDocumentOptions{Content: doc}},
+		{"URL", "prebuilt-read", analysis.AnalyzeDocumentOptions{DocURL: "https://example.com/a.pdf"}},
+		{"image", "prebuilt-read", analysis.AnalyzeDocumentOptions{Content: []byte("\x89PNG\r\n"), ContentType: "image/png"}},
+		{"malformed", "prebuilt-read", analysis.AnalyzeDocumentOptions{Content: []byte("%PDF-1.4\ngarbage")}},
+		{"scan", "prebuilt-read", analysis.AnalyzeDocumentOptions{Content: testPDF(t, []string{"Lease agreement between Contoso"}, []string{"p. 2"})}},
+		{"no page", "prebuilt-read", analysis.AnalyzeDocumentOptions{Content: doc, Pages: "3"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next := &countingRepository{}
+			result, err := TextLayer(next, 0.9, nil).AnalyzeDocument(context.Background(), tt.modelID, tt.options)
+			require.NoError(t, err)
+			assert.Equal(t, 1, next.calls)
+			assert.Empty(t, result.Source)
+		})
+	}
+}
@@ -0,0 +1,320 @@
+package pdf
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+
+	"github.com/linzhengen/azure-document-intelligence-mcp/internal/domain/analysis"
+	"github.com/linzhengen/azure-document-intelligence-mcp/internal/logging"
+)
+
+func init() {
+	// Keep pdfcpu from writing its configuration to the user configuration directory.
+	model.ConfigPath = "disable"
+}
+
+// SplitLimits are the limits of the service per request, such as 2000 pages
+// and 500 MB, or 2 pages and 4 MB on the free tier.
+type SplitLimits struct {
+	// MaxPages and MaxBytes bound the pages and size of the document of each
+	// request. Zero means no limit.
+	MaxPages int
+	MaxBytes int64
+	// Concurrency bounds the parts of an uploaded PDF analyzed at once. Zero
+	// means one at a time.
+	Concurrency int
+}
+
+// Split returns a repository that analyzes documents over the limits in
+// parts with next, in parallel, and merges their results into one.
+//
+// Uploaded PDFs are split into PDFs of consecutive pages, halved again while
+// they're too large. Documents at a URL, whose length isn't known, are split
+// into page ranges when the range given by the caller has too many pages.
+// The ranges are analyzed one after the other, so that those past the end of
+// the document aren't requested. Other documents are passed to next as they
+// are.
+func Split(next analysis.Repository, limits SplitLimits) analysis.Repository {
+	return &splitter{next: next, limits: limits}
+}
+
+type splitter struct {
+	next   analysis.Repository
+	limits SplitLimits
+}
+
+// part is a request for some pages of a document.
+type part struct {
+	options analysis.AnalyzeDocumentOptions
+	// first and last are the numbers of the pages of the part in the document.
+	first, last int
+	// pageOffset is added to the page numbers of the result of a split PDF.
+	pageOffset int32
+}
+
+func (p *part) pages() string {
+	if p.first == p.last {
+		return strconv.Itoa(p.first)
+	}
+	return strconv.Itoa(p.first) + "-" + strconv.Itoa(p.last)
+}
+
+func (s *splitter) AnalyzeDocument(ctx context.Context, modelID string, options analysis.AnalyzeDocumentOptions) (*analysis.AnalyzeOperationResult, error) {
+	logger := logging.FromContext(ctx)
+	parts, err := s.split(options)
+	if err != nil {
+		// Let the service report what's wrong with the document.
+		logger.DebugContext(ctx, "failed to split document", "error", err)
+		return s.next.AnalyzeDocument(ctx, modelID, options)
+	}
+	if len(parts) == 0 {
+		return s.next.AnalyzeDocument(ctx, modelID, options)
+	}
+	logger.InfoContext(ctx, "analyzing document in parts", "modelId", modelID, "parts", len(parts))
+
+	var results []*analysis.AnalyzeOperationResult
+	if len(options.Content) == 0 {
+		parts, results, err = s.analyzeRanges(ctx, modelID, parts)
+	} else {
+		results, err = s.analyzeParts(ctx, modelID, parts)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(options.Content) == 0 && len(results) == 1 {
+		return results[0], nil
+	}
+	merged := &analysis.AnalyzeOperationResult{Status: "succeeded"}
+	resultParts := make([]analysis.Part, len(parts))
+	for i, r := range results {
+		if r.AnalyzeResult == nil {
+			return nil, fmt.Errorf("analysis of pages %s returned no result", parts[i].pages())
+		}
+		resultParts[i] = analysis.Part{Result: r.AnalyzeResult, PageOffset: parts[i].pageOffset}
+		if merged.CreatedDateTime == "" || r.CreatedDateTime < merged.CreatedDateTime {
+			merged.CreatedDateTime = r.CreatedDateTime
+		}
+		merged.LastUpdatedDateTime = max(merged.LastUpdatedDateTime, r.LastUpdatedDateTime)
+	}
+	if merged.AnalyzeResult, err = analysis.Merge(resultParts); err != nil {
+		return nil, err
+	}
+	return merged, nil
+}
+
+// analyzeParts analyzes the parts in parallel, stopping at the first failure.
+func (s *splitter) analyzeParts(ctx context.Context, modelID string, parts []*part) ([]*analysis.AnalyzeOperationResult, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make([]*analysis.AnalyzeOperationResult, len(parts))
+	slots := make(chan struct{}, max(s.limits.Concurrency, 1))
+	var wg sync.WaitGroup
+	var once sync.Once
+	var firstErr error
+	for i, p := range parts {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			select {
+			case slots <- struct{}{}:
+				defer func() { <-slots }()
+			case <-ctx.Done():
+				return
+			}
+			result, err := s.next.AnalyzeDocument(ctx, modelID, p.options)
+			if err != nil {
+				once.Do(func() {
+					firstErr = fmt.Errorf("failed to analyze pages %s: %w", p.pages(), err)
+					cancel()
+				})
+				return
+			}
+			results[i] = result
+		}()
+	}
+	wg.Wait()
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	// The context of the caller may be done before any part failed.
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// analyzeRanges analyzes the page ranges of a document at a URL one after the
+// other, as they may go past the end of the document: once a result has fewer
+// pages than its range, the document is known to end at its last page, and
+// the ranges past it are dropped. A range starting right after the last page
+// is still requested, as the end can't be told from a full result. It
+// returns the parts analyzed and their results.
+func (s *splitter) analyzeRanges(ctx context.Context, modelID string, parts []*part) ([]*part, []*analysis.AnalyzeOperationResult, error) {
+	var results []*analysis.AnalyzeOperationResult
+	for i := 0; i < len(parts); i++ {
+		p := parts[i]
+		result, err := s.next.AnalyzeDocument(ctx, modelID, p.options)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to analyze pages %s: %w", p.pages(), err)
+		}
+		results = append(results, result)
+		if result.AnalyzeResult != nil && len(result.AnalyzeResult.Pages) < p.last-p.first+1 {
+			parts = append(parts[:i+1], trimParts(parts[i+1:], p.first+len(result.AnalyzeResult.Pages)-1)...)
+		}
+	}
+	return parts, results, nil
+}
+
+// split returns the parts of the document of options, or none if it can be
+// sent as it is. The selected pages of a PDF over the byte limit are
+// extracted even when they make a single part.
+func (s *splitter) split(options analysis.AnalyzeDocumentOptions) ([]*part, error) {
+	if len(options.Content) == 0 {
+		return s.splitRange(options)
+	}
+	if !IsPDF(options.Content) {
+		return nil, nil
+	}
+	doc, err := Open(options.Content)
+	if err != nil {
+		return nil, err
+	}
+	total, err := doc.NumPages()
+	if err != nil {
+		return nil, err
+	}
+	ranges := [][2]int{{1, total}}
+	if options.Pages != "" {
+		if ranges, err = analysis.PageRanges(options.Pages); err != nil {
+			return nil, err
+		}
+	}
+	// Pages past the end are ignored, as the service does.
+	count := 0
+	for i := range ranges {
+		ranges[i][1] = min(ranges[i][1], total)
+		count += max(ranges[i][1]-ranges[i][0]+1, 0)
+	}
+
+	perPart := s.limits.MaxPages
+	if perPart <= 0 {
+		perPart = count
+	}
+	size := int64(len(options.Content))
+	if s.limits.MaxBytes > 0 && size > s.limits.MaxBytes && total > 0 {
+		// Guess from the average page size; parts still too large are halved.
+		perPart = min(perPart, max(1, int(s.limits.MaxBytes/(size/int64(total)+1))))
+	}
+	if count <= perPart && (s.limits.MaxBytes <= 0 || size <= s.limits.MaxBytes) {
+		return nil, nil
+	}
+	return s.extract(options, chunks(ranges, perPart))
+}
+
+// splitRange returns the page ranges of a document at a URL. The range made
+// up for a whole document, capped at PageLimit, isn't split, as whole
+// documents aren't.
+func (s *splitter) splitRange(options analysis.AnalyzeDocumentOptions) ([]*part, error) {
+	if options.Pages == "" || options.PageLimit > 0 || s.limits.MaxPages <= 0 {
+		return nil, nil
+	}
+	ranges, err := analysis.PageRanges(options.Pages)
+	if err != nil {
+		return nil, err
+	}
+	if count, _ := analysis.CountPages(options.Pages); count <= s.limits.MaxPages {
+		return nil, nil
+	}
+	var parts []*part
+	for _, c := range chunks(ranges, s.limits.MaxPages) {
+		p := &part{options: options, first: c[0], last: c[1]}
+		p.options.Pages = p.pages()
+		parts = append(parts, p)
+	}
+	return parts, nil
+}
+
+// trimParts returns the parts that aren't past end, the last page of the
+// document, shortening those that reach past it.
+func trimParts(parts []*part, end int) []*part {
+	var kept []*part
+	for _, p := range parts {
+		if p.first > end {
+			continue
+		}
+		if p.last > end {
+			p.last = end
+			p.options.Pages = p.pages()
+		}
+		kept = append(kept, p)
+	}
+	return kept
+}
+
+// extract writes the chunks of pages of the PDF of options to PDFs of their
+// own, halving those over the byte limit.
+func (s *splitter) extract(options analysis.AnalyzeDocumentOptions, pageChunks [][2]int) (parts []*part, err error) {
+	defer recoverError(&err)
+	conf := model.NewDefaultConfiguration()
+	conf.ValidationMode = model.ValidationRelaxed
+	pdfCtx, err := api.ReadValidateAndOptimize(bytes.NewReader(options.Content), conf)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read PDF: %w", err)
+	}
+
+	for len(pageChunks) > 0 {
+		first, last := pageChunks[0][0], pageChunks[0][1]
+		pageChunks = pageChunks[1:]
+		content, err := extractPages(pdfCtx, first, last)
+		if err != nil {
+			return nil, err
+		}
+		if s.limits.MaxBytes > 0 && int64(len(content)) > s.limits.MaxBytes && first < last {
+			mid := first + (last-first)/2
+			pageChunks = append([][2]int{{first, mid}, {mid + 1, last}}, pageChunks...)
+			continue
+		}
+		p := &part{options: options, first: first, last: last, pageOffset: int32(first - 1)}
+		p.options.Content = content
+		p.options.Pages = ""
+		parts = append(parts, p)
+	}
+	return parts, nil
+}
+
+// extractPages returns a PDF of the pages first to last of pdfCtx.
+func extractPages(pdfCtx *model.Context, first, last int) ([]byte, error) {
+	numbers := make([]int, 0, last-first+1)
+	for n := first; n <= last; n++ {
+		numbers = append(numbers, n)
+	}
+	dest, err := pdfcpu.ExtractPages(pdfCtx, numbers, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract pages %d-%d: %w", first, last, err)
+	}
+	var b bytes.Buffer
+	if err := api.WriteContext(dest, &b); err != nil {
+		return nil, fmt.Errorf("failed to write pages %d-%d: %w", first, last, err)
+	}
+	return b.Bytes(), nil
+}
+
+// chunks splits ranges of pages, as first and last page, into ranges of at
+// most n pages. Empty ranges are dropped.
+func chunks(ranges [][2]int, n int) [][2]int {
+	var split [][2]int
+	for _, r := range ranges {
+		for first := r[0]; first <= r[1]; first += n {
+			split = append(split, [2]int{first, min(first+n-1, r[1])})
+		}
+	}
+	return split
+}
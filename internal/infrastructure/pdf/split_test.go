@@ -0,0 +1,256 @@
+package pdf
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/linzhengen/azure-document-intelligence-mcp/internal/domain/analysis"
+	analysisinfra "github.com/linzhengen/azure-document-intelligence-mcp/internal/infrastructure/analysis"
+	"github.com/linzhengen/azure-document-intelligence-mcp/internal/infrastructure/analysis/fake"
+)
+
+// repositoryFunc is an analysis.Repository calling itself.
+type repositoryFunc func(ctx context.Context, modelID string, options analysis.AnalyzeDocumentOptions) (*analysis.AnalyzeOperationResult, error)
+
+func (f repositoryFunc) AnalyzeDocument(ctx context.Context, modelID string, options analysis.AnalyzeDocumentOptions) (*analysis.AnalyzeOperationResult, error) {
+	return f(ctx, modelID, options)
+}
+
+// readingService reads uploaded PDFs from their text layer, as the service
+// would, and records the requests.
+type readingService struct {
+	mu       sync.Mutex
+	requests []analysis.AnalyzeDocumentOptions
+}
+
+func (s *readingService) AnalyzeDocument(ctx context.Context, modelID string, options analysis.AnalyzeDocumentOptions) (*analysis.AnalyzeOperationResult, error) {
+	s.mu.Lock()
+	s.requests = append(s.requests, options)
+	s.mu.Unlock()
+	pages, err := readText(ctx, options)
+	if err != nil {
+		return nil, err
+	}
+	return &analysis.AnalyzeOperationResult{Status: "succeeded", AnalyzeResult: newResult(modelID, pages)}, nil
+}
+
+// numberedPDF returns a PDF of n pages reading "Page <number>".
+func numberedPDF(t *testing.T, n int) []byte {
+	t.Helper()
+	pages := make([][]string, n)
+	for i := range pages {
+		pages[i] = []string{fmt.Sprintf("Page %d", i+1)}
+	}
+	return testPDF(t, pages...)
+}
+
+func pageNumbers(r *analysis.AnalyzeResult) []int32 {
+	var numbers []int32
+	for _, p := range r.Pages {
+		numbers = append(numbers, p.PageNumber)
+	}
+	return numbers
+}
+
+func TestSplit_Content(t *testing.T) {
+	ctx := context.Background()
+	doc := numberedPDF(t, 5)
+
+	tests := []struct {
+		name   string
+		limits SplitLimits
+		pages  string
+		want   []int32
+		parts  int
+	}{
+		{"pages", SplitLimits{MaxPages: 2, Concurrency: 2}, "", []int32{1, 2, 3, 4, 5}, 3},
+		{"page range", SplitLimits{MaxPages: 2}, "2-5", []int32{2, 3, 4, 5}, 2},
+		// A page takes about 860 bytes on its own and two about 970.
+		{"bytes", SplitLimits{MaxBytes: 900}, "", []int32{1, 2, 3, 4, 5}, 5},
+		{"selected pages over the byte limit", SplitLimits{MaxBytes: int64(len(doc)) - 1}, "4", []int32{4}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := &readingService{}
+			result, err := Split(service, tt.limits).AnalyzeDocument(ctx, "prebuilt-read", analysis.AnalyzeDocumentOptions{Content: doc, ContentType: "application/pdf", Pages: tt.pages})
+			require.NoError(t, err)
+
+			require.Len(t, service.requests, tt.parts)
+			for _, r := range service.requests {
+				assert.Empty(t, r.Pages, "parts are documents of their own")
+				assert.Equal(t, "application/pdf", r.ContentType)
+				if tt.limits.MaxBytes > 0 {
+					assert.LessOrEqual(t, int64(len(r.Content)), tt.limits.MaxBytes)
+				}
+			}
+			assert.Equal(t, "succeeded", result.Status)
+			assert.Equal(t, tt.want, pageNumbers(result.AnalyzeResult))
+			var lines []string
+			for i, p := range result.AnalyzeResult.Pages {
+				lines = append(lines, fmt.Sprintf("Page %d", tt.want[i]))
+				line := p.Lines[0]
+				span := line.Spans[0]
+				assert.Equal(t, line.Content, result.AnalyzeResult.Content[span.Offset:span.Offset+span.Length], "spans point into the merged content")
+			}
+			assert.Equal(t, strings.Join(lines, "\n"), result.AnalyzeResult.Content)
+		})
+	}
+}
+
+func TestSplit_URL(t *testing.T) {
+	ctx := context.Background()
+	var mu sync.Mutex
+	var requested []string
+	next := repositoryFunc(func(ctx context.Context, modelID string, options analysis.AnalyzeDocumentOptions) (*analysis.AnalyzeOperationResult, error) {
+		mu.Lock()
+		requested = append(requested, options.Pages)
+		mu.Unlock()
+		numbers, err := analysis.PageNumbers(options.Pages, 100)
+		if err != nil {
+			return nil, err
+		}
+		result := &analysis.AnalyzeResult{StringIndexType: "textElements"}
+		for _, n := range numbers {
+			result.Pages = append(result.Pages, analysis.Page{PageNumber: int32(n)})
+		}
+		return &analysis.AnalyzeOperationResult{Status: "succeeded", AnalyzeResult: result}, nil
+	})
+	repo := Split(next, SplitLimits{MaxPages: 2, Concurrency: 4})
+
+	result, err := repo.AnalyzeDocument(ctx, "prebuilt-layout", analysis.AnalyzeDocumentOptions{DocURL: "https://example.com/a.pdf", Pages: "1-3,7-8"})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"1-2", "3", "7-8"}, requested)
+	assert.Equal(t, []int32{1, 2, 3, 7, 8}, pageNumbers(result.AnalyzeResult), "pages of a range keep their number")
+
+	requested = nil
+	_, err = repo.AnalyzeDocument(ctx, "prebuilt-layout", analysis.AnalyzeDocumentOptions{DocURL: "https://example.com/a.pdf"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{""}, requested, "the length of a whole document is unknown")
+
+	requested = nil
+	_, err = repo.AnalyzeDocument(ctx, "prebuilt-layout", analysis.AnalyzeDocumentOptions{DocURL: "https://example.com/a.pdf", Pages: "4-5"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"4-5"}, requested)
+}
+
+func TestSplit_ShortURLDocument(t *testing.T) {
+	ctx := context.Background()
+	var mu sync.Mutex
+	var requested []string
+	// The document has 2500 pages; the service ignores the pages past its end.
+	next := repositoryFunc(func(ctx context.Context, modelID string, options analysis.AnalyzeDocumentOptions) (*analysis.AnalyzeOperationResult, error) {
+		mu.Lock()
+		requested = append(requested, options.Pages)
+		mu.Unlock()
+		numbers, err := analysis.PageNumbers(options.Pages, 2500)
+		if err != nil {
+			return nil, err
+		}
+		if len(numbers) == 0 {
+			return nil, errors.New("InvalidParameter: pages out of range")
+		}
+		result := &analysis.AnalyzeResult{}
+		for _, n := range numbers {
+			result.Pages = append(result.Pages, analysis.Page{PageNumber: int32(n)})
+		}
+		return &analysis.AnalyzeOperationResult{Status: "succeeded", AnalyzeResult: result}, nil
+	})
+	repo := Split(next, SplitLimits{MaxPages: 2000, Concurrency: 4})
+
+	result, err := repo.AnalyzeDocument(ctx, "prebuilt-read", analysis.AnalyzeDocumentOptions{DocURL: "https://example.com/a.pdf", Pages: "1-10000"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1-2000", "2001-4000"}, requested, "the parts past the end aren't requested")
+	assert.Len(t, result.AnalyzeResult.Pages, 2500)
+
+	requested = nil
+	result, err = repo.AnalyzeDocument(ctx, "prebuilt-read", analysis.AnalyzeDocumentOptions{DocURL: "https://example.com/a.pdf", Pages: "1-10,2400-6000"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1-10", "2400-4399"}, requested)
+	assert.Len(t, result.AnalyzeResult.Pages, 111)
+
+	requested = nil
+	single := repositoryFunc(func(ctx context.Context, modelID string, options analysis.AnalyzeDocumentOptions) (*analysis.AnalyzeOperationResult, error) {
+		requested = append(requested, options.Pages)
+		return &analysis.AnalyzeOperationResult{Status: "succeeded", AnalyzeResult: &analysis.AnalyzeResult{Pages: []analysis.Page{{PageNumber: 1}}}}, nil
+	})
+	result, err = Split(single, SplitLimits{MaxPages: 2000}).AnalyzeDocument(ctx, "prebuilt-read", analysis.AnalyzeDocumentOptions{DocURL: "https://example.com/a.pdf", Pages: "1-10000"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1-2000"}, requested, "a one-page document is analyzed once")
+	assert.Equal(t, []int32{1}, pageNumbers(result.AnalyzeResult))
+
+	requested = nil
+	_, err = Split(single, SplitLimits{MaxPages: 2000}).AnalyzeDocument(ctx, "prebuilt-read", analysis.AnalyzeDocumentOptions{DocURL: "https://example.com/a.pdf", Pages: "1-10000", PageLimit: 10000})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1-10000"}, requested, "the range made up for a whole document isn't split")
+}
+
+func TestSplit_FakeBackend(t *testing.T) {
+	server := fake.NewServer("dummy-key")
+	defer server.Close()
+	repo := Split(analysisinfra.NewRepositoryWithClient(server.URL, "dummy-key", server.Client()), SplitLimits{MaxPages: 2000})
+
+	result, err := repo.AnalyzeDocument(context.Background(), "prebuilt-read", analysis.AnalyzeDocumentOptions{DocURL: "https://example.com/a.pdf", Pages: "1-10000"})
+	require.NoError(t, err)
+	assert.Len(t, result.AnalyzeResult.Pages, 1)
+	requests := server.Requests()
+	require.Len(t, requests, 1, "the one-page document is analyzed once")
+	assert.Contains(t, requests[0].Query, "pages=1-2000")
+}
+
+func TestSplit_PassesThrough(t *testing.T) {
+	for name, content := range map[string][]byte{
+		"small PDF": numberedPDF(t, 2),
+		"image":     []byte("\x89PNG\r\n"),
+		"malformed": []byte("%PDF-1.4\ngarbage"),
+	} {
+		t.Run(name, func(t *testing.T) {
+			next := &countingRepository{}
+			_, err := Split(next, SplitLimits{MaxPages: 2, MaxBytes: 1 << 20}).AnalyzeDocument(context.Background(), "prebuilt-read", analysis.AnalyzeDocumentOptions{Content: content})
+			require.NoError(t, err)
+			assert.Equal(t, 1, next.calls)
+		})
+	}
+}
+
+func TestSplit_Failure(t *testing.T) {
+	var mu sync.Mutex
+	var inFlight, maxInFlight int
+	next := repositoryFunc(func(ctx context.Context, modelID string, options analysis.AnalyzeDocumentOptions) (*analysis.AnalyzeOperationResult, error) {
+		mu.Lock()
+		inFlight++
+		maxInFlight = max(maxInFlight, inFlight)
+		mu.Unlock()
+		defer func() {
+			mu.Lock()
+			inFlight--
+			mu.Unlock()
+		}()
+		pages, err := readText(ctx, options)
+		if err != nil {
+			return nil, err
+		}
+		result := newResult(modelID, pages)
+		if strings.HasPrefix(result.Content, "Page 5") {
+			return nil, errors.New("throttled")
+		}
+		select {
+		case <-time.After(20 * time.Millisecond):
+			return &analysis.AnalyzeOperationResult{Status: "succeeded", AnalyzeResult: result}, nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	})
+
+	_, err := Split(next, SplitLimits{MaxPages: 2, Concurrency: 2}).AnalyzeDocument(context.Background(), "prebuilt-read", analysis.AnalyzeDocumentOptions{Content: numberedPDF(t, 10), ContentType: "application/pdf"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "pages 5-6: throttled")
+	assert.LessOrEqual(t, maxInFlight, 2)
+}
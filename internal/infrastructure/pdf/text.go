@@ -0,0 +1,167 @@
+// Package pdf reads PDF documents on this server, to spare the service the
+// documents it isn't needed for.
+package pdf
+
+import (
+	"bytes"
+	"cmp"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"unicode"
+
+	pdfreader "github.com/ledongthuc/pdf"
+)
+
+// pointsPerInch converts PDF user space units to inches, the unit of the
+// service for PDF pages.
+const pointsPerInch = 72
+
+// IsPDF reports whether data holds a PDF file, which starts with a %PDF-
+// header within its first kilobyte.
+func IsPDF(data []byte) bool {
+	return bytes.Contains(data[:min(len(data), 1024)], []byte("%PDF-"))
+}
+
+// Document is an open PDF file.
+type Document struct {
+	r *pdfreader.Reader
+}
+
+// Open reads the structure of the PDF file in data.
+func Open(data []byte) (doc *Document, err error) {
+	defer recoverError(&err)
+	r, err := pdfreader.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read PDF: %w", err)
+	}
+	return &Document{r: r}, nil
+}
+
+// NumPages returns the number of pages of the document.
+func (d *Document) NumPages() (n int, err error) {
+	defer recoverError(&err)
+	return d.r.NumPage(), nil
+}
+
+// Page is the text layer of a page. Lengths are in inches, and coordinates
+// start from the top-left corner of the page.
+type Page struct {
+	Number int
+	Width  float64
+	Height float64
+	Lines  []Line
+}
+
+// Line is a line of text of a page.
+type Line struct {
+	Text string
+	// Polygon bounds the line as x, y coordinates of its top-left, top-right,
+	// bottom-right and bottom-left corners.
+	Polygon []float64
+}
+
+// Text returns the text layer of the page with the given 1-based number.
+// Lines are in reading order: from top to bottom, and left to right within
+// a line.
+func (d *Document) Text(number int) (page *Page, err error) {
+	defer recoverError(&err)
+	p := d.r.Page(number)
+	if p.V.IsNull() {
+		return nil, fmt.Errorf("PDF has no page %d", number)
+	}
+
+	// The media box is [llx lly urx ury], in points. Letter size is assumed
+	// when it's missing.
+	box := [4]float64{0, 0, 612, 792}
+	if mediaBox := inherited(p.V, "MediaBox"); mediaBox.Len() == 4 {
+		for i := range box {
+			box[i] = mediaBox.Index(i).Float64()
+		}
+	}
+	page = &Page{
+		Number: number,
+		Width:  (box[2] - box[0]) / pointsPerInch,
+		Height: (box[3] - box[1]) / pointsPerInch,
+	}
+	for _, glyphs := range groupLines(p.Content().Text) {
+		if line, ok := newLine(glyphs, box[0], box[3]); ok {
+			page.Lines = append(page.Lines, line)
+		}
+	}
+	return page, nil
+}
+
+// inherited returns the attribute key of a page, which may be set on one of
+// its ancestors in the page tree.
+func inherited(v pdfreader.Value, key string) pdfreader.Value {
+	for ; !v.IsNull(); v = v.Key("Parent") {
+		if attr := v.Key(key); !attr.IsNull() {
+			return attr
+		}
+	}
+	return pdfreader.Value{}
+}
+
+// groupLines groups the glyphs of a page by baseline, from the top of the
+// page, each line sorted from left to right.
+func groupLines(glyphs []pdfreader.Text) [][]pdfreader.Text {
+	glyphs = slices.Clone(glyphs)
+	slices.SortStableFunc(glyphs, func(a, b pdfreader.Text) int { return cmp.Compare(b.Y, a.Y) })
+
+	var lines [][]pdfreader.Text
+	var baseline, size float64
+	for _, g := range glyphs {
+		// Glyphs within a third of the font size of the baseline, such as
+		// those of another font, belong to the same line.
+		if n := len(lines); n > 0 && math.Abs(baseline-g.Y) <= max(size, g.FontSize, 1)/3 {
+			lines[n-1] = append(lines[n-1], g)
+			continue
+		}
+		lines = append(lines, []pdfreader.Text{g})
+		baseline, size = g.Y, g.FontSize
+	}
+	for _, line := range lines {
+		slices.SortStableFunc(line, func(a, b pdfreader.Text) int { return cmp.Compare(a.X, b.X) })
+	}
+	return lines
+}
+
+// newLine returns the line of glyphs, sorted from left to right, with its
+// polygon relative to the top-left corner (left, top) of the page, in points.
+// Spaces are inserted where glyphs are apart, and whitespace is collapsed.
+// It returns false for a blank line.
+func newLine(glyphs []pdfreader.Text, left, top float64) (Line, bool) {
+	var text strings.Builder
+	minX, maxX := math.Inf(1), math.Inf(-1)
+	ascent, descent := math.Inf(-1), math.Inf(1)
+	var prev *pdfreader.Text
+	for i := range glyphs {
+		g := &glyphs[i]
+		if prev != nil && g.X-(prev.X+prev.W) > g.FontSize/4 {
+			text.WriteByte(' ')
+		}
+		text.WriteString(g.S)
+		prev = g
+
+		minX, maxX = min(minX, g.X), max(maxX, g.X+g.W)
+		ascent, descent = max(ascent, g.Y+0.8*g.FontSize), min(descent, g.Y-0.2*g.FontSize)
+	}
+	content := strings.Join(strings.FieldsFunc(text.String(), unicode.IsSpace), " ")
+	if content == "" {
+		return Line{}, false
+	}
+
+	x0, x1 := (minX-left)/pointsPerInch, (maxX-left)/pointsPerInch
+	y0, y1 := (top-ascent)/pointsPerInch, (top-descent)/pointsPerInch
+	return Line{Text: content, Polygon: []float64{x0, y0, x1, y0, x1, y1, x0, y1}}, true
+}
+
+// recoverError turns a panic of the PDF reader, which panics on malformed
+// files, into an error.
+func recoverError(err *error) {
+	if r := recover(); r != nil {
+		*err = fmt.Errorf("malformed PDF: %v", r)
+	}
+}
@@ -0,0 +1,100 @@
+// Package tracing exports OpenTelemetry traces over OTLP/HTTP and traces the
+// requests the analysis repository sends to Azure.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+
+	analysisinfra "github.com/linzhengen/azure-document-intelligence-mcp/internal/infrastructure/analysis"
+	"github.com/linzhengen/azure-document-intelligence-mcp/internal/redact"
+)
+
+// InstrumentationName names the tracer of the server.
+const InstrumentationName = "github.com/linzhengen/azure-document-intelligence-mcp"
+
+// Setup exports the spans of the global tracer provider to the OTLP/HTTP
+// collector at endpoint, a base URL such as http://localhost:4318 to which
+// /v1/traces is appended, and installs the W3C trace context propagator.
+// The returned function flushes pending spans and stops the exporter.
+func Setup(ctx context.Context, endpoint, serviceName, serviceVersion string) (func(context.Context) error, error) {
+	tracesURL, err := url.JoinPath(endpoint, "v1/traces")
+	if err != nil {
+		return nil, fmt.Errorf("invalid OTLP endpoint %q: %w", endpoint, err)
+	}
+	exporter, err := otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(tracesURL))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OTLP exporter: %w", err)
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(
+			semconv.ServiceName(serviceName),
+			semconv.ServiceVersion(serviceVersion),
+		)),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	return provider.Shutdown, nil
+}
+
+// WrapClient returns an HTTPClient that records a client span for every
+// request to Azure and propagates the trace context in its headers. Transport
+// errors, which may hold signed URLs, are recorded after redactor removed
+// their secrets.
+func WrapClient(next analysisinfra.HTTPClient, redactor *redact.Redactor) analysisinfra.HTTPClient {
+	return &client{next: next, redactor: redactor}
+}
+
+type client struct {
+	next     analysisinfra.HTTPClient
+	redactor *redact.Redactor
+}
+
+func (c *client) Do(req *http.Request) (*http.Response, error) {
+	operation, modelID := analysisinfra.RequestOperation(req)
+	name := "Document Intelligence " + operation
+	attrs := []attribute.KeyValue{
+		semconv.HTTPRequestMethodKey.String(req.Method),
+		semconv.ServerAddress(req.URL.Hostname()),
+		semconv.URLPath(req.URL.Path),
+	}
+	if modelID != "" {
+		attrs = append(attrs, attribute.String("docintel.model_id", modelID))
+	}
+	ctx, span := otel.Tracer(InstrumentationName).Start(req.Context(), name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attrs...),
+	)
+	defer span.End()
+
+	req = req.Clone(ctx)
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+	resp, err := c.next.Do(req)
+	if err != nil {
+		redacted := c.redactor.Error(err)
+		span.RecordError(redacted)
+		span.SetStatus(codes.Error, redacted.Error())
+		return nil, err
+	}
+	span.SetAttributes(semconv.HTTPResponseStatusCode(resp.StatusCode))
+	if id := resp.Header.Get("apim-request-id"); id != "" {
+		span.SetAttributes(attribute.String("docintel.apim_request_id", id))
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		span.SetStatus(codes.Error, resp.Status)
+	}
+	return resp, nil
+}
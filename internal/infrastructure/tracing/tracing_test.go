@@ -0,0 +1,173 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/linzhengen/azure-document-intelligence-mcp/internal/domain/analysis"
+	analysisinfra "github.com/linzhengen/azure-document-intelligence-mcp/internal/infrastructure/analysis"
+	"github.com/linzhengen/azure-document-intelligence-mcp/internal/infrastructure/analysis/fake"
+	"github.com/linzhengen/azure-document-intelligence-mcp/internal/redact"
+)
+
+// collector stands in for an OTLP/HTTP collector and keeps the spans it receives.
+type collector struct {
+	mu    sync.Mutex
+	spans map[string][]byte // span name to trace ID
+}
+
+func (c *collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/v1/traces" {
+		http.NotFound(w, r)
+		return
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var req coltracepb.ExportTraceServiceRequest
+	if err := proto.Unmarshal(body, &req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, rs := range req.ResourceSpans {
+		for _, ss := range rs.ScopeSpans {
+			for _, span := range ss.Spans {
+				c.spans[span.Name] = span.TraceId
+			}
+		}
+	}
+	w.Header().Set("Content-Type", "application/x-protobuf")
+	data, _ := proto.Marshal(&coltracepb.ExportTraceServiceResponse{})
+	_, _ = w.Write(data)
+}
+
+func TestSetup_ExportsClientSpans(t *testing.T) {
+	ctx := context.Background()
+	c := &collector{spans: make(map[string][]byte)}
+	collectorServer := httptest.NewServer(c)
+	defer collectorServer.Close()
+
+	provider, propagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	defer func() {
+		otel.SetTracerProvider(provider)
+		otel.SetTextMapPropagator(propagator)
+	}()
+	shutdown, err := Setup(ctx, collectorServer.URL, "test", "0.0.0")
+	require.NoError(t, err)
+
+	server := fake.NewServer("dummy-key")
+	defer server.Close()
+	var traceparent string
+	client := &headerRecorder{next: server.Client(), header: "traceparent", value: &traceparent}
+	repo := analysisinfra.NewRepositoryWithClient(server.URL, "dummy-key", WrapClient(client, nil))
+
+	ctx, span := otel.Tracer(InstrumentationName).Start(ctx, "tools/call analyze_document")
+	_, err = repo.AnalyzeDocument(ctx, "prebuilt-read", analysis.AnalyzeDocumentOptions{DocURL: "http://test.com/doc.pdf"})
+	span.End()
+	require.NoError(t, err)
+	require.NoError(t, shutdown(context.Background()))
+
+	traceID := span.SpanContext().TraceID()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, name := range []string{"tools/call analyze_document", "Document Intelligence initiate", "Document Intelligence poll"} {
+		require.Contains(t, c.spans, name)
+		assert.Equal(t, traceID[:], c.spans[name], name)
+	}
+	assert.Contains(t, traceparent, traceID.String())
+}
+
+func TestWrapClient_PropagatesContext(t *testing.T) {
+	propagator := otel.GetTextMapPropagator()
+	defer otel.SetTextMapPropagator(propagator)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	var traceparent string
+	client := WrapClient(&headerRecorder{
+		next: &http.Client{Transport: roundTripper(func(*http.Request) (*http.Response, error) {
+			return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+		})},
+		header: "traceparent",
+		value:  &traceparent,
+	}, nil)
+	parent := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{1},
+		SpanID:     trace.SpanID{1},
+		TraceFlags: trace.FlagsSampled,
+		Remote:     true,
+	})
+	ctx := trace.ContextWithRemoteSpanContext(context.Background(), parent)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://test.com/documentintelligence/documentModels/prebuilt-read/analyzeResults/1", nil)
+	require.NoError(t, err)
+
+	resp, err := client.Do(req)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, traceparent, parent.TraceID().String())
+	assert.Empty(t, req.Header.Get("traceparent"), "the caller's request must not be modified")
+}
+
+func TestWrapClient_RedactsErrors(t *testing.T) {
+	provider := otel.GetTracerProvider()
+	defer otel.SetTracerProvider(provider)
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+
+	redactor, err := redact.New(nil, nil)
+	require.NoError(t, err)
+	transportErr := errors.New("dial failed for https://files.blob.core.windows.net/a.pdf?sv=2024-05-04&sig=s3cr3t")
+	client := WrapClient(&http.Client{Transport: roundTripper(func(*http.Request) (*http.Response, error) {
+		return nil, transportErr
+	})}, redactor)
+	req, err := http.NewRequest(http.MethodGet, "http://test.com/documentintelligence/documentModels/prebuilt-read/analyzeResults/1", nil)
+	require.NoError(t, err)
+
+	_, err = client.Do(req)
+	require.ErrorIs(t, err, transportErr)
+	spans := recorder.Ended()
+	require.Len(t, spans, 1)
+	assert.Equal(t, codes.Error, spans[0].Status().Code)
+	assert.Contains(t, spans[0].Status().Description, "sig="+redact.Placeholder)
+	assert.NotContains(t, spans[0].Status().Description, "s3cr3t")
+	require.Len(t, spans[0].Events(), 1)
+	for _, attr := range spans[0].Events()[0].Attributes {
+		assert.NotContains(t, attr.Value.Emit(), "s3cr3t", attr.Key)
+	}
+}
+
+type headerRecorder struct {
+	next   analysisinfra.HTTPClient
+	header string
+	value  *string
+}
+
+func (h *headerRecorder) Do(req *http.Request) (*http.Response, error) {
+	if v := req.Header.Get(h.header); v != "" {
+		*h.value = v
+	}
+	return h.next.Do(req)
+}
+
+type roundTripper func(*http.Request) (*http.Response, error)
+
+func (f roundTripper) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
@@ -0,0 +1,200 @@
+// Package urlpolicy decides which document URLs may be analyzed, so that the
+// server can't be used to reach internal services (server-side request forgery).
+//
+// A URL is refused if its scheme or host isn't allowed, if it carries
+// credentials, or if its host is, or resolves to, an address in a private,
+// loopback, link-local, metadata or otherwise non-public range.
+package urlpolicy
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/netip"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// deniedPrefixes are the non-public ranges not covered by the netip predicates.
+var deniedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),       // "this" network
+	netip.MustParsePrefix("100.64.0.0/10"),   // carrier-grade NAT
+	netip.MustParsePrefix("192.0.0.0/24"),    // IETF protocol assignments
+	netip.MustParsePrefix("192.0.2.0/24"),    // documentation
+	netip.MustParsePrefix("198.18.0.0/15"),   // benchmarking
+	netip.MustParsePrefix("198.51.100.0/24"), // documentation
+	netip.MustParsePrefix("203.0.113.0/24"),  // documentation
+	netip.MustParsePrefix("240.0.0.0/4"),     // reserved, including broadcast
+	netip.MustParsePrefix("::/96"),           // IPv4-compatible, deprecated
+	netip.MustParsePrefix("::ffff:0:0:0/96"), // IPv4-translated
+	netip.MustParsePrefix("64:ff9b::/96"),    // NAT64, which embeds IPv4 addresses
+	netip.MustParsePrefix("64:ff9b:1::/48"),  // local-use NAT64
+	netip.MustParsePrefix("2001:db8::/32"),   // documentation
+	netip.MustParsePrefix("2002::/16"),       // 6to4, which embeds IPv4 addresses
+	netip.MustParsePrefix("100::/64"),        // discard-only
+}
+
+// DefaultDeniedHosts are metadata and local host names refused in addition to the configured ones.
+var DefaultDeniedHosts = []string{
+	"localhost",
+	"*.localhost",
+	"*.local",
+	"*.internal",
+	"metadata",
+	"instance-data",
+}
+
+// Resolver looks up the addresses of a host. It's implemented by *net.Resolver.
+type Resolver interface {
+	LookupNetIP(ctx context.Context, network, host string) ([]netip.Addr, error)
+}
+
+// Policy validates document URLs.
+type Policy struct {
+	// AllowedSchemes lists the accepted URL schemes. Empty means https only.
+	AllowedSchemes []string
+	// AllowedHosts, if not empty, lists the only accepted hosts. An entry
+	// "*.example.com" matches the subdomains of example.com.
+	AllowedHosts []string
+	// DeniedHosts lists refused hosts, with the same syntax as AllowedHosts,
+	// in addition to DefaultDeniedHosts. It takes precedence over AllowedHosts.
+	DeniedHosts []string
+	// AllowPrivateNetworks accepts non-public addresses, for local development.
+	AllowPrivateNetworks bool
+	// PrivateHosts lists hosts, with the same syntax as AllowedHosts, that are
+	// accepted even though they're local or resolve to non-public addresses,
+	// such as intranet or VPN hosts. They're neither refused by
+	// DefaultDeniedHosts nor by AllowedHosts, but DeniedHosts still applies.
+	PrivateHosts []string
+	// Resolver resolves host names. Nil means net.DefaultResolver.
+	Resolver Resolver
+}
+
+// Error reports a refused URL.
+type Error struct {
+	URL    string
+	Reason string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("documentUrl %q is not allowed: %s", e.URL, e.Reason)
+}
+
+// Validate returns an *Error if rawURL may not be analyzed. It resolves the
+// host, so the result only holds at the time of the call; use CheckAddr when
+// connecting to guard against DNS rebinding.
+func (p *Policy) Validate(ctx context.Context, rawURL string) error {
+	refuse := func(format string, args ...any) error {
+		return &Error{URL: rawURL, Reason: fmt.Sprintf(format, args...)}
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return refuse("invalid URL")
+	}
+	schemes := p.AllowedSchemes
+	if len(schemes) == 0 {
+		schemes = []string{"https"}
+	}
+	if !slices.Contains(schemes, strings.ToLower(u.Scheme)) {
+		return refuse("scheme %q is not allowed", u.Scheme)
+	}
+	if u.User != nil {
+		return refuse("URLs with credentials are not allowed")
+	}
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	if host == "" {
+		return refuse("missing host")
+	}
+	private := matchHost(host, p.PrivateHosts)
+	if matchHost(host, p.DeniedHosts) || (!private && matchHost(host, DefaultDeniedHosts)) {
+		return refuse("host %q is denied", host)
+	}
+	if len(p.AllowedHosts) > 0 && !private && !matchHost(host, p.AllowedHosts) {
+		return refuse("host %q is not in the allowlist", host)
+	}
+	if p.AllowPrivateNetworks || private {
+		return nil
+	}
+
+	if addr, err := netip.ParseAddr(host); err == nil {
+		if err := CheckAddr(addr); err != nil {
+			return refuse("%v", err)
+		}
+		return nil
+	}
+	if ambiguousIPv4(host) {
+		return refuse("host %q looks like a non-canonical IP address", host)
+	}
+	resolver := p.Resolver
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+	addrs, err := resolver.LookupNetIP(ctx, "ip", host)
+	if err != nil || len(addrs) == 0 {
+		return refuse("host %q could not be resolved", host)
+	}
+	for _, addr := range addrs {
+		if err := CheckAddr(addr); err != nil {
+			return refuse("host %q resolves to %v", host, err)
+		}
+	}
+	return nil
+}
+
+// AllowsPrivate reports whether host may be connected to on a non-public
+// address, because private networks are allowed or it's one of PrivateHosts.
+func (p *Policy) AllowsPrivate(host string) bool {
+	return p.AllowPrivateNetworks || matchHost(strings.TrimSuffix(strings.ToLower(host), "."), p.PrivateHosts)
+}
+
+// CheckAddr returns an error if addr isn't a public unicast address.
+// IPv4-mapped IPv6 addresses are checked as the IPv4 address they map.
+func CheckAddr(addr netip.Addr) error {
+	addr = addr.Unmap()
+	switch {
+	case addr.IsLoopback():
+		return fmt.Errorf("loopback address %s", addr)
+	case addr.IsPrivate():
+		return fmt.Errorf("private address %s", addr)
+	case addr.IsLinkLocalUnicast():
+		return fmt.Errorf("link-local address %s", addr)
+	case addr.IsUnspecified():
+		return fmt.Errorf("unspecified address %s", addr)
+	case addr.IsMulticast(), addr.IsLinkLocalMulticast(), addr.IsInterfaceLocalMulticast():
+		return fmt.Errorf("multicast address %s", addr)
+	}
+	for _, prefix := range deniedPrefixes {
+		if prefix.Contains(addr) {
+			return fmt.Errorf("reserved address %s", addr)
+		}
+	}
+	return nil
+}
+
+// matchHost reports whether host matches one of patterns. A pattern
+// "*.example.com" matches the subdomains of example.com but not example.com.
+func matchHost(host string, patterns []string) bool {
+	for _, pattern := range patterns {
+		pattern = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(pattern)), ".")
+		if suffix, ok := strings.CutPrefix(pattern, "*"); ok {
+			if strings.HasSuffix(host, suffix) {
+				return true
+			}
+		} else if host == pattern {
+			return true
+		}
+	}
+	return false
+}
+
+// ambiguousIPv4 reports whether host would be read as an IPv4 address by some
+// resolvers, such as "2130706433", "0x7f.1" or "127.1". No top-level domain is numeric.
+func ambiguousIPv4(host string) bool {
+	last := host[strings.LastIndex(host, ".")+1:]
+	if strings.HasPrefix(last, "0x") {
+		return true
+	}
+	return last != "" && strings.Trim(last, "0123456789") == ""
+}
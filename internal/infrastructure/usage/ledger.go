@@ -0,0 +1,104 @@
+// Package usage stores the usage ledger as a JSON Lines file.
+package usage
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/linzhengen/azure-document-intelligence-mcp/internal/domain/usage"
+)
+
+// FileLedger keeps usage entries in memory and appends each one to a JSON
+// Lines file, so that budgets survive restarts.
+type FileLedger struct {
+	path string
+
+	mu      sync.Mutex
+	entries []usage.Entry
+}
+
+// NewFileLedger loads the ledger at path, creating it on first append.
+// An empty path keeps entries in memory only.
+func NewFileLedger(path string) (*FileLedger, error) {
+	l := &FileLedger{path: path}
+	if path == "" {
+		return l, nil
+	}
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return l, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open usage ledger: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var entry usage.Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("failed to decode usage ledger %s line %d: %w", path, line, err)
+		}
+		l.entries = append(l.entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read usage ledger: %w", err)
+	}
+	return l, nil
+}
+
+// Append implements usage.Ledger.
+func (l *FileLedger) Append(_ context.Context, entry usage.Entry) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.path != "" {
+		if err := l.write(entry); err != nil {
+			return err
+		}
+	}
+	l.entries = append(l.entries, entry)
+	return nil
+}
+
+func (l *FileLedger) write(entry usage.Entry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to encode usage entry: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(l.path), 0o755); err != nil {
+		return fmt.Errorf("failed to create usage ledger directory: %w", err)
+	}
+	f, err := os.OpenFile(l.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open usage ledger: %w", err)
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("failed to write usage ledger: %w", err)
+	}
+	return f.Close()
+}
+
+// Entries implements usage.Ledger.
+func (l *FileLedger) Entries(_ context.Context, since time.Time) ([]usage.Entry, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var entries []usage.Entry
+	for _, e := range l.entries {
+		if !e.Time.Before(since) {
+			entries = append(entries, e)
+		}
+	}
+	return entries, nil
+}
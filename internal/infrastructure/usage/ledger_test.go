@@ -0,0 +1,56 @@
+package usage
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/linzhengen/azure-document-intelligence-mcp/internal/domain/usage"
+)
+
+func TestFileLedger_Persists(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "state", "usage.jsonl")
+	now := time.Date(2024, 5, 2, 10, 0, 0, 0, time.UTC)
+
+	ledger, err := NewFileLedger(path)
+	require.NoError(t, err)
+	require.NoError(t, ledger.Append(ctx, usage.Entry{Time: now.Add(-24 * time.Hour), ModelID: "prebuilt-read", Pages: 2}))
+	require.NoError(t, ledger.Append(ctx, usage.Entry{Time: now, ModelID: "prebuilt-layout", Client: "claude", Session: "s1", Pages: 3}))
+
+	reopened, err := NewFileLedger(path)
+	require.NoError(t, err)
+	all, err := reopened.Entries(ctx, time.Time{})
+	require.NoError(t, err)
+	assert.Len(t, all, 2)
+
+	today, err := reopened.Entries(ctx, usage.Day(now))
+	require.NoError(t, err)
+	require.Len(t, today, 1)
+	assert.Equal(t, usage.Entry{Time: now, ModelID: "prebuilt-layout", Client: "claude", Session: "s1", Pages: 3}, today[0])
+}
+
+func TestFileLedger_InMemory(t *testing.T) {
+	ctx := context.Background()
+	ledger, err := NewFileLedger("")
+	require.NoError(t, err)
+	require.NoError(t, ledger.Append(ctx, usage.Entry{Time: time.Now(), ModelID: "prebuilt-read", Pages: 1}))
+
+	entries, err := ledger.Entries(ctx, time.Time{})
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+}
+
+func TestNewFileLedger_Corrupt(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "usage.jsonl")
+	require.NoError(t, os.WriteFile(path, []byte("{\"pages\":1}\nnot json\n"), 0o644))
+
+	_, err := NewFileLedger(path)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "line 2")
+}
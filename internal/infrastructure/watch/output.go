@@ -0,0 +1,94 @@
+package watch
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/linzhengen/azure-document-intelligence-mcp/internal/domain/analysis"
+)
+
+// Output writes the results of analyzed files to a directory. The results of
+// a file invoice.pdf whose content hash starts with 3f2a... are written as:
+//   - invoice.3f2a....json         the full result
+//   - invoice.3f2a....md           the extracted content
+//   - invoice.3f2a....table-1.csv  the first table, and so on
+//
+// The JSON file is written last, so that its presence marks complete results.
+type Output struct {
+	dir string
+}
+
+// NewOutput returns an output writing to dir, which is created if needed.
+func NewOutput(dir string) (*Output, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create output directory: %w", err)
+	}
+	return &Output{dir: dir}, nil
+}
+
+// Exists reports whether the results of the file name with the content hash
+// have been written.
+func (o *Output) Exists(name, hash string) bool {
+	_, err := os.Stat(o.path(name, hash, ".json"))
+	return err == nil
+}
+
+// Write writes the results of the file name with the content hash.
+func (o *Output) Write(name, hash string, result *analysis.AnalyzeOperationResult) error {
+	if r := result.AnalyzeResult; r != nil {
+		for i, table := range r.Tables {
+			var buf bytes.Buffer
+			w := csv.NewWriter(&buf)
+			if err := w.WriteAll(table.Grid()); err != nil {
+				return fmt.Errorf("failed to write table: %w", err)
+			}
+			if err := writeFile(o.path(name, hash, fmt.Sprintf(".table-%d.csv", i+1)), buf.Bytes()); err != nil {
+				return err
+			}
+		}
+		if err := writeFile(o.path(name, hash, ".md"), []byte(r.Content)); err != nil {
+			return err
+		}
+	}
+	data, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal result: %w", err)
+	}
+	return writeFile(o.path(name, hash, ".json"), data)
+}
+
+func (o *Output) path(name, hash, suffix string) string {
+	base := filepath.Base(name)
+	return filepath.Join(o.dir, strings.TrimSuffix(base, filepath.Ext(base))+"."+hash+suffix)
+}
+
+// writeFile writes data to path through a temporary file, so that readers
+// never see a partial file.
+func writeFile(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to write result: %w", err)
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+	// Temporary files are only readable by their owner.
+	if err := tmp.Chmod(0o644); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed to write result: %w", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed to write result: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write result: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to write result: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package watch
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/linzhengen/azure-document-intelligence-mcp/internal/domain/analysis"
+)
+
+func TestOutput(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "results")
+	output, err := NewOutput(dir)
+	require.NoError(t, err)
+	result := &analysis.AnalyzeOperationResult{
+		Status: "succeeded",
+		AnalyzeResult: &analysis.AnalyzeResult{
+			ModelID: "prebuilt-layout",
+			Content: "# Invoice\nTotal: 42",
+			Tables: []*analysis.Table{{RowCount: 1, ColumnCount: 2, Cells: []analysis.Cell{
+				{RowIndex: 0, ColumnIndex: 0, Content: "Total"},
+				{RowIndex: 0, ColumnIndex: 1, Content: "42, EUR"},
+			}}},
+		},
+	}
+
+	assert.False(t, output.Exists("/srv/scans/invoice.pdf", "3f2a"))
+	require.NoError(t, output.Write("/srv/scans/invoice.pdf", "3f2a", result))
+	assert.True(t, output.Exists("/srv/scans/invoice.pdf", "3f2a"))
+	assert.False(t, output.Exists("/srv/scans/invoice.pdf", "9b1c"), "other content of the same file")
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	assert.Equal(t, []string{"invoice.3f2a.json", "invoice.3f2a.md", "invoice.3f2a.table-1.csv"}, names, "temporary files are removed")
+
+	data, err := os.ReadFile(filepath.Join(dir, "invoice.3f2a.json"))
+	require.NoError(t, err)
+	var written analysis.AnalyzeOperationResult
+	require.NoError(t, json.Unmarshal(data, &written))
+	assert.Equal(t, "prebuilt-layout", written.AnalyzeResult.ModelID)
+	data, err = os.ReadFile(filepath.Join(dir, "invoice.3f2a.md"))
+	require.NoError(t, err)
+	assert.Equal(t, "# Invoice\nTotal: 42", string(data))
+	data, err = os.ReadFile(filepath.Join(dir, "invoice.3f2a.table-1.csv"))
+	require.NoError(t, err)
+	assert.Equal(t, "Total,\"42, EUR\"\n", string(data))
+}
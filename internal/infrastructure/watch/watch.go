@@ -0,0 +1,228 @@
+// Package watch reports the files of directories as they're created or
+// changed, and writes the results of their analyses to a directory.
+package watch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+
+	"github.com/linzhengen/azure-document-intelligence-mcp/internal/logging"
+)
+
+// Default intervals of Options.
+const (
+	defaultSettle       = 2 * time.Second
+	defaultPollInterval = 10 * time.Second
+)
+
+// Options configure Run.
+type Options struct {
+	// PollInterval, if positive, scans the directories at this interval
+	// instead of waiting for file system events, which network shares may
+	// not deliver. Directories are also scanned, every 10 seconds, when
+	// events aren't available.
+	PollInterval time.Duration
+	// Settle is how long a file must stay unchanged before it's reported, so
+	// that files still being written aren't. Defaults to 2 seconds.
+	Settle time.Duration
+}
+
+// fileState identifies a version of a file.
+type fileState struct {
+	size    int64
+	modTime time.Time
+}
+
+// watcher tracks the files of the directories.
+type watcher struct {
+	dirs   []string
+	settle time.Duration
+	handle func(path string)
+	// reported holds the state of the files when they were last reported,
+	// scanned their state at the last scan, and pending the time files
+	// were last seen changing, until they're reported.
+	reported map[string]fileState
+	scanned  map[string]fileState
+	pending  map[string]time.Time
+}
+
+// Run calls handle with the path of every file of dirs, and then of every
+// file created or changed in them, until ctx is done. Subdirectories and
+// hidden files, such as those starting with a dot or a tilde, are ignored.
+// handle is called from a single goroutine; files changing meanwhile are
+// reported once it returns.
+func Run(ctx context.Context, dirs []string, opts Options, handle func(path string)) error {
+	if len(dirs) == 0 {
+		return errors.New("no directories to watch")
+	}
+	w := &watcher{
+		dirs:     dirs,
+		settle:   opts.Settle,
+		handle:   handle,
+		reported: make(map[string]fileState),
+		scanned:  make(map[string]fileState),
+		pending:  make(map[string]time.Time),
+	}
+	if w.settle <= 0 {
+		w.settle = defaultSettle
+	}
+	logger := logging.FromContext(ctx)
+	if err := w.scan(time.Now()); err != nil {
+		return err
+	}
+
+	interval := opts.PollInterval
+	if interval <= 0 {
+		events, err := newEventWatcher(dirs)
+		if err == nil {
+			defer func() { _ = events.Close() }()
+			logger.InfoContext(ctx, "watching directories", "dirs", dirs)
+			return w.runEvents(ctx, events)
+		}
+		logger.WarnContext(ctx, "file system events unavailable, scanning directories instead", "error", err)
+		interval = defaultPollInterval
+	}
+	logger.InfoContext(ctx, "scanning directories", "dirs", dirs, "interval", interval)
+	return w.runScans(ctx, interval)
+}
+
+func newEventWatcher(dirs []string) (*fsnotify.Watcher, error) {
+	events, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	for _, dir := range dirs {
+		if err := events.Add(dir); err != nil {
+			_ = events.Close()
+			return nil, fmt.Errorf("failed to watch %s: %w", dir, err)
+		}
+	}
+	return events, nil
+}
+
+// runEvents reports the files of events once they've settled.
+func (w *watcher) runEvents(ctx context.Context, events *fsnotify.Watcher) error {
+	ticker := time.NewTicker(max(w.settle/4, 10*time.Millisecond))
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event, ok := <-events.Events:
+			if !ok {
+				return errors.New("file system events stopped")
+			}
+			if ignored(event.Name) {
+				continue
+			}
+			if event.Has(fsnotify.Remove) || event.Has(fsnotify.Rename) {
+				delete(w.pending, event.Name)
+				delete(w.reported, event.Name)
+				continue
+			}
+			if event.Has(fsnotify.Create) || event.Has(fsnotify.Write) {
+				w.pending[event.Name] = time.Now()
+			}
+		case err, ok := <-events.Errors:
+			if !ok {
+				return errors.New("file system events stopped")
+			}
+			// Events may have been lost: look for changes.
+			logging.FromContext(ctx).WarnContext(ctx, "file system events failed, scanning directories", "error", err)
+			if err := w.scan(time.Now()); err != nil {
+				return err
+			}
+		case now := <-ticker.C:
+			w.flush(ctx, now)
+		}
+	}
+}
+
+// runScans reports the files found changed by scans once they've settled.
+// A file is only reported after a scan that found it unchanged.
+func (w *watcher) runScans(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		w.flush(ctx, time.Now())
+		select {
+		case <-ctx.Done():
+			return nil
+		case now := <-ticker.C:
+			if err := w.scan(now); err != nil {
+				logging.FromContext(ctx).WarnContext(ctx, "failed to scan directories", "error", err)
+			}
+		}
+	}
+}
+
+// scan marks the files that changed since the last scan as pending.
+func (w *watcher) scan(now time.Time) error {
+	var errs []error
+	seen := make(map[string]bool, len(w.scanned))
+	for _, dir := range w.dirs {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to read %s: %w", dir, err))
+			continue
+		}
+		for _, e := range entries {
+			path := filepath.Join(dir, e.Name())
+			if !e.Type().IsRegular() || ignored(path) {
+				continue
+			}
+			info, err := e.Info()
+			if err != nil {
+				continue
+			}
+			seen[path] = true
+			state := fileState{info.Size(), info.ModTime()}
+			if prev, ok := w.scanned[path]; !ok || prev != state {
+				w.scanned[path] = state
+				w.pending[path] = now
+			}
+		}
+	}
+	for path := range w.scanned {
+		if !seen[path] {
+			delete(w.scanned, path)
+			delete(w.reported, path)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// flush reports the pending files that haven't changed for the settle
+// time, unless they're as they were last reported.
+func (w *watcher) flush(ctx context.Context, now time.Time) {
+	for path, changed := range w.pending {
+		if now.Sub(changed) < w.settle || ctx.Err() != nil {
+			continue
+		}
+		delete(w.pending, path)
+		info, err := os.Stat(path)
+		if err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		state := fileState{info.Size(), info.ModTime()}
+		if prev, ok := w.reported[path]; ok && prev == state {
+			continue
+		}
+		w.reported[path] = state
+		w.handle(path)
+	}
+}
+
+// ignored reports whether path is a hidden or temporary file, such as the
+// lock files of office applications.
+func ignored(path string) bool {
+	name := filepath.Base(path)
+	return strings.HasPrefix(name, ".") || strings.HasPrefix(name, "~")
+}
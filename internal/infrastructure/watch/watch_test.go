@@ -0,0 +1,75 @@
+package watch
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recorder records the files reported by Run.
+type recorder struct {
+	mu    sync.Mutex
+	paths []string
+}
+
+func (r *recorder) handle(path string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.paths = append(r.paths, path)
+}
+
+func (r *recorder) reported() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.paths...)
+}
+
+func TestRun(t *testing.T) {
+	for name, opts := range map[string]Options{
+		"events": {Settle: 50 * time.Millisecond},
+		"scans":  {Settle: 50 * time.Millisecond, PollInterval: 20 * time.Millisecond},
+	} {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			existing := filepath.Join(dir, "existing.pdf")
+			require.NoError(t, os.WriteFile(existing, []byte("%PDF-1.7"), 0o644))
+			require.NoError(t, os.Mkdir(filepath.Join(dir, "subdir"), 0o755))
+
+			ctx, cancel := context.WithCancel(context.Background())
+			r := &recorder{}
+			done := make(chan error, 1)
+			go func() { done <- Run(ctx, []string{dir}, opts, r.handle) }()
+
+			require.Eventually(t, func() bool { return len(r.reported()) == 1 }, 2*time.Second, 10*time.Millisecond, "files present at start are reported")
+			assert.Equal(t, []string{existing}, r.reported())
+
+			added := filepath.Join(dir, "scan.pdf")
+			require.NoError(t, os.WriteFile(filepath.Join(dir, ".scan.pdf.tmp"), []byte("partial"), 0o644))
+			require.NoError(t, os.WriteFile(filepath.Join(dir, "~$report.docx"), []byte("lock"), 0o644))
+			require.NoError(t, os.WriteFile(added, []byte("%PDF-1.7 scan"), 0o644))
+			require.Eventually(t, func() bool { return len(r.reported()) == 2 }, 2*time.Second, 10*time.Millisecond)
+			assert.Equal(t, added, r.reported()[1])
+
+			require.NoError(t, os.WriteFile(existing, []byte("%PDF-1.7 changed"), 0o644))
+			require.Eventually(t, func() bool { return len(r.reported()) == 3 }, 2*time.Second, 10*time.Millisecond, "changed files are reported again")
+			assert.Equal(t, existing, r.reported()[2])
+
+			time.Sleep(200 * time.Millisecond)
+			assert.Len(t, r.reported(), 3, "hidden files and unchanged files aren't reported")
+
+			cancel()
+			assert.NoError(t, <-done)
+		})
+	}
+}
+
+func TestRun_MissingDirectory(t *testing.T) {
+	err := Run(context.Background(), []string{filepath.Join(t.TempDir(), "missing")}, Options{}, func(string) {})
+	assert.Error(t, err)
+}
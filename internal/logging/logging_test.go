@@ -0,0 +1,38 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFromContext(t *testing.T) {
+	assert.Same(t, slog.Default(), FromContext(context.Background()))
+
+	logger := New(&bytes.Buffer{}, slog.LevelInfo)
+	assert.Same(t, logger, FromContext(NewContext(context.Background(), logger)))
+}
+
+func TestFanout(t *testing.T) {
+	var debug, warn bytes.Buffer
+	logger := slog.New(Fanout(
+		slog.NewJSONHandler(&debug, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		slog.NewJSONHandler(&warn, &slog.HandlerOptions{Level: slog.LevelWarn}),
+	)).With("modelId", "prebuilt-read")
+
+	logger.Info("analysis completed", "polls", 2)
+	logger.Warn("analysis failed")
+
+	assert.Equal(t, 2, bytes.Count(debug.Bytes(), []byte("\n")))
+	assert.Equal(t, 1, bytes.Count(warn.Bytes(), []byte("\n")))
+
+	var record map[string]any
+	require.NoError(t, json.Unmarshal(warn.Bytes(), &record))
+	assert.Equal(t, "analysis failed", record["msg"])
+	assert.Equal(t, "prebuilt-read", record["modelId"])
+}
@@ -0,0 +1,226 @@
+// Package redact removes secrets, such as API keys, bearer tokens and SAS
+// signatures, from errors, log records and tool outputs before they leave the
+// server.
+package redact
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+)
+
+// Placeholder replaces redacted secrets.
+const Placeholder = "[REDACTED]"
+
+// signedParamPattern matches signed URL parameters: Azure SAS, AWS and GCS
+// signatures, OAuth tokens and keys. The separator may be JSON-escaped as \u0026.
+const signedParamPattern = `(?i)((?:[?&]|\\u0026)(?:sig|signature|x-amz-signature|x-amz-credential|x-amz-security-token|x-goog-signature|x-goog-credential|access_token|token|code|key|api-key|apikey|subscription-key)=)[^&#\s"'\\]+`
+
+// builtinPatterns match secrets in URLs, headers and messages. Their first
+// group, if any, is kept; the rest of the match is replaced.
+var builtinPatterns = []string{
+	signedParamPattern,
+	// Bearer and basic credentials.
+	`(?i)(\b(?:bearer|basic)\s+)[a-z0-9\-._~+/]+=*`,
+	// Key headers and key-value pairs such as "Ocp-Apim-Subscription-Key: ...", "api_key=...".
+	`(?i)((?:ocp-apim-subscription-key|x-api-key|api[-_]?key|client[-_]?secret|password|authorization)["']?\s*[:=]\s*["']?(?:(?:bearer|basic)\s+)?)[^\s"',&;\\]+`,
+	// Storage account keys in connection strings.
+	`(?i)(AccountKey=)[^;\s"'\\]+`,
+}
+
+var (
+	// urlRE matches absolute URLs, whose signed parameters are redacted even
+	// in content.
+	urlRE         = regexp.MustCompile(`(?i)\bhttps?://[^\s"'<>]+`)
+	signedParamRE = regexp.MustCompile(signedParamPattern)
+)
+
+// Redactor removes secrets from text. A nil *Redactor leaves text unchanged.
+type Redactor struct {
+	secrets []string
+	builtin []*regexp.Regexp
+	rules   []*regexp.Regexp
+}
+
+// New returns a redactor removing the given literal secrets, the built-in
+// patterns and the additional regular expressions in patterns. As with the
+// built-in patterns, the first group of a pattern is kept.
+func New(secrets []string, patterns []string) (*Redactor, error) {
+	r := &Redactor{}
+	for _, s := range secrets {
+		// Short values would redact common words.
+		if len(s) >= 8 {
+			r.secrets = append(r.secrets, s)
+		}
+	}
+	for _, p := range builtinPatterns {
+		r.builtin = append(r.builtin, regexp.MustCompile(p))
+	}
+	for _, p := range patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redaction pattern %q: %w", p, err)
+		}
+		r.rules = append(r.rules, re)
+	}
+	return r, nil
+}
+
+// String returns s, a message, URL or response body produced by the server,
+// with its secrets replaced by Placeholder.
+func (r *Redactor) String(s string) string {
+	if r == nil {
+		return s
+	}
+	s = r.literal(s)
+	s = replace(s, r.builtin)
+	return replace(s, r.rules)
+}
+
+// Content returns s, text the server didn't produce such as the content
+// extracted from a document, with its secrets replaced by Placeholder. Only
+// the literal secrets, the configured patterns and the signed parameters of
+// URLs are redacted: the other built-in patterns would also match ordinary
+// text, such as "Basic salary" or "Password: ______".
+func (r *Redactor) Content(s string) string {
+	if r == nil {
+		return s
+	}
+	s = r.literal(s)
+	s = urlRE.ReplaceAllStringFunc(s, func(u string) string {
+		return signedParamRE.ReplaceAllString(u, "${1}"+Placeholder)
+	})
+	return replace(s, r.rules)
+}
+
+// literal returns s with the literal secrets replaced.
+func (r *Redactor) literal(s string) string {
+	for _, secret := range r.secrets {
+		s = strings.ReplaceAll(s, secret, Placeholder)
+	}
+	return s
+}
+
+// replace returns s with the matches of rules replaced, keeping their first group.
+func replace(s string, rules []*regexp.Regexp) string {
+	for _, re := range rules {
+		if re.NumSubexp() > 0 {
+			s = re.ReplaceAllString(s, "${1}"+Placeholder)
+		} else {
+			s = re.ReplaceAllLiteralString(s, Placeholder)
+		}
+	}
+	return s
+}
+
+// Error returns err with a redacted message. The result still wraps err, so
+// that errors.Is and errors.As keep working.
+func (r *Redactor) Error(err error) error {
+	if r == nil || err == nil {
+		return err
+	}
+	msg := r.String(err.Error())
+	if msg == err.Error() {
+		return err
+	}
+	return &redactedError{msg: msg, err: err}
+}
+
+type redactedError struct {
+	msg string
+	err error
+}
+
+func (e *redactedError) Error() string { return e.msg }
+func (e *redactedError) Unwrap() error { return e.err }
+
+// Value returns v, a value decoded from JSON such as a tool result, with the
+// secrets of its strings redacted as Content does. Maps and slices are copied
+// rather than modified.
+func (r *Redactor) Value(v any) any {
+	if r == nil {
+		return v
+	}
+	switch v := v.(type) {
+	case string:
+		return r.Content(v)
+	case map[string]any:
+		m := make(map[string]any, len(v))
+		for k, e := range v {
+			m[k] = r.Value(e)
+		}
+		return m
+	case []any:
+		s := make([]any, len(v))
+		for i, e := range v {
+			s[i] = r.Value(e)
+		}
+		return s
+	default:
+		return v
+	}
+}
+
+// Handler returns a slog.Handler that redacts the messages and attributes of
+// records before passing them to next.
+func (r *Redactor) Handler(next slog.Handler) slog.Handler {
+	if r == nil {
+		return next
+	}
+	return &handler{next: next, redactor: r}
+}
+
+type handler struct {
+	next     slog.Handler
+	redactor *Redactor
+}
+
+func (h *handler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *handler) Handle(ctx context.Context, record slog.Record) error {
+	redacted := slog.NewRecord(record.Time, record.Level, h.redactor.String(record.Message), record.PC)
+	record.Attrs(func(a slog.Attr) bool {
+		redacted.AddAttrs(h.attr(a))
+		return true
+	})
+	return h.next.Handle(ctx, redacted)
+}
+
+func (h *handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	redacted := make([]slog.Attr, len(attrs))
+	for i, a := range attrs {
+		redacted[i] = h.attr(a)
+	}
+	return &handler{next: h.next.WithAttrs(redacted), redactor: h.redactor}
+}
+
+func (h *handler) WithGroup(name string) slog.Handler {
+	return &handler{next: h.next.WithGroup(name), redactor: h.redactor}
+}
+
+func (h *handler) attr(a slog.Attr) slog.Attr {
+	v := a.Value.Resolve()
+	switch v.Kind() {
+	case slog.KindString:
+		return slog.String(a.Key, h.redactor.String(v.String()))
+	case slog.KindGroup:
+		attrs := v.Group()
+		redacted := make([]slog.Attr, len(attrs))
+		for i, ga := range attrs {
+			redacted[i] = h.attr(ga)
+		}
+		return slog.Attr{Key: a.Key, Value: slog.GroupValue(redacted...)}
+	case slog.KindAny:
+		if err, ok := v.Any().(error); ok {
+			return slog.String(a.Key, h.redactor.String(err.Error()))
+		}
+		if s, ok := v.Any().(fmt.Stringer); ok {
+			return slog.String(a.Key, h.redactor.String(s.String()))
+		}
+	}
+	return slog.Attr{Key: a.Key, Value: v}
+}
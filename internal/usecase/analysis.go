@@ -3,55 +3,469 @@ package usecase
 import (
 	"context"
 	"encoding/base64"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
 
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 
 	"github.com/linzhengen/azure-document-intelligence-mcp/internal/domain/analysis"
+	"github.com/linzhengen/azure-document-intelligence-mcp/internal/domain/history"
+	"github.com/linzhengen/azure-document-intelligence-mcp/internal/domain/pii"
+	"github.com/linzhengen/azure-document-intelligence-mcp/internal/logging"
+	"github.com/linzhengen/azure-document-intelligence-mcp/internal/redact"
 )
 
 // AnalysisParams defines the parameters for the document analysis tool.
 type AnalysisParams struct {
-	ModelID         string `json:"modelId"`
-	DocumentURL     string `json:"documentUrl,omitempty"`
-	DocumentContent string `json:"documentContent,omitempty"` // Base64 encoded content
-	ContentType     string `json:"contentType,omitempty"`     // Required when documentContent is provided
+	ModelID         string `json:"modelId" jsonschema:"Document model ID: prebuilt-read, prebuilt-layout, prebuilt-invoice or prebuilt-contract."`
+	DocumentURL     string `json:"documentUrl,omitempty" jsonschema:"URL of the document to analyze. Mutually exclusive with documentContent."`
+	DocumentContent string `json:"documentContent,omitempty" jsonschema:"Base64 encoded bytes of the document to analyze. Mutually exclusive with documentUrl."`
+	ContentType     string `json:"contentType,omitempty" jsonschema:"MIME type of documentContent, for example application/pdf. Required when documentContent is provided."`
+	FetchMode       string `json:"fetchMode,omitempty" jsonschema:"Who downloads documentUrl: azure (default) passes the URL to the service; server downloads it on this server and uploads the bytes, for URLs the service can't reach."`
+	Pages           string `json:"pages,omitempty" jsonschema:"Pages to analyze, such as 1-3,5. Defaults to all pages, within the page budget."`
+	Region          string `json:"region,omitempty" jsonschema:"Data residency: only analyze the document on endpoints tagged with this region, such as westeurope."`
+	Simplify        bool   `json:"simplify,omitempty" jsonschema:"Return the fields of extracted documents as flattened plain values in simplifiedDocuments."`
+	RedactPII       bool   `json:"redactPii,omitempty" jsonschema:"Replace email addresses, phone numbers, payment card numbers, IBANs, SSNs and other personal data in the result by placeholders such as [EMAIL_1], listed in piiReport."`
+
+	Confidence *ConfidenceParams `json:"confidence,omitempty" jsonschema:"Minimum confidence per element type. Elements below it are listed in reviewReport."`
+}
+
+// ConfidenceParams defines the minimum confidence per element type.
+// A zero threshold disables the check for that element type.
+type ConfidenceParams struct {
+	Word          float32 `json:"word,omitempty" jsonschema:"Minimum confidence of words, between 0 and 1."`
+	SelectionMark float32 `json:"selectionMark,omitempty" jsonschema:"Minimum confidence of selection marks, between 0 and 1."`
+	KeyValuePair  float32 `json:"keyValuePair,omitempty" jsonschema:"Minimum confidence of key-value pairs, between 0 and 1."`
+	Field         float32 `json:"field,omitempty" jsonschema:"Minimum confidence of document fields, between 0 and 1."`
+	Filter        bool    `json:"filter,omitempty" jsonschema:"Remove low-confidence elements from the result instead of only reporting them."`
+}
+
+// AnalysisOutput is the output of the document analysis tool.
+type AnalysisOutput struct {
+	*analysis.AnalyzeOperationResult
+	SimplifiedDocuments []*SimplifiedDocument  `json:"simplifiedDocuments,omitempty" jsonschema:"Extracted documents with flattened fields. Replaces analyzeResult.documents when simplify is requested."`
+	ReviewReport        []*analysis.ReviewItem `json:"reviewReport,omitempty" jsonschema:"Elements below the requested confidence thresholds."`
+	PIIReport           []*analysis.PIIItem    `json:"piiReport,omitempty" jsonschema:"Personal data replaced in the result, with its location."`
+	PageLimitReached    bool                   `json:"pageLimitReached,omitempty" jsonschema:"The whole document was analyzed up to the maximum pages per request, and had at least that many pages: pages past the limit, if any, weren't analyzed."`
+}
+
+// SimplifiedDocument is an extracted document whose fields are flattened
+// into dotted paths mapped to plain values.
+type SimplifiedDocument struct {
+	DocType    string         `json:"docType" jsonschema:"Document type."`
+	Confidence float32        `json:"confidence" jsonschema:"Confidence of correctly extracting the document, between 0 and 1."`
+	Fields     map[string]any `json:"fields" jsonschema:"Field values keyed by dotted path, for example Items.0.Amount."`
+}
+
+// Fetch modes of AnalysisParams.FetchMode.
+const (
+	fetchModeAzure  = "azure"
+	fetchModeServer = "server"
+)
+
+// supportedModels lists the model IDs accepted by the analysis tool.
+var supportedModels = map[string]bool{
+	"prebuilt-read":     true,
+	"prebuilt-layout":   true,
+	"prebuilt-invoice":  true,
+	"prebuilt-contract": true,
+}
+
+// SupportedModels returns the model IDs the analysis tool can accept, sorted.
+func SupportedModels() []string {
+	return slices.Sorted(maps.Keys(supportedModels))
+}
+
+// ResultPublisher makes completed analyses available outside of the tool call.
+type ResultPublisher interface {
+	// Publish stores the result of the session, empty over stdio, and
+	// returns the URI it can be retrieved from.
+	Publish(session string, result *analysis.AnalyzeOperationResult) string
+}
+
+// HandlerOption configures optional behavior of the analysis tool handler.
+type HandlerOption func(*handlerOptions)
+
+type handlerOptions struct {
+	publisher ResultPublisher
+	logger    *slog.Logger
+	usage     *UsageMeter
+	urls      URLValidator
+	fetchURLs URLValidator
+	fetcher   DocumentFetcher
+	files     DocumentReader
+	redactor  *redact.Redactor
+	pii       pii.Detector
+	alwaysPII bool
+	models    map[string]bool
+	queue     AnalysisQueue
+	history   history.Store
+}
+
+// AnalysisQueue bounds the analyses in progress.
+type AnalysisQueue interface {
+	// Acquire waits for a slot for an analysis requested by session. The
+	// returned function frees the slot.
+	Acquire(ctx context.Context, session string) (release func(), err error)
+}
+
+// URLValidator refuses document URLs that must not be analyzed.
+type URLValidator interface {
+	Validate(ctx context.Context, rawURL string) error
+}
+
+// DocumentFetcher downloads documents for the server fetch mode.
+type DocumentFetcher interface {
+	Fetch(ctx context.Context, rawURL string) (content []byte, contentType string, err error)
+}
+
+// WithDocumentFetcher enables fetchMode "server", which downloads documentUrl
+// with fetcher and sends its content instead of the URL.
+func WithDocumentFetcher(fetcher DocumentFetcher) HandlerOption {
+	return func(o *handlerOptions) {
+		o.fetcher = fetcher
+	}
+}
+
+// WithURLValidator refuses documentUrl values rejected by validator before
+// anything is sent to the service.
+func WithURLValidator(validator URLValidator) HandlerOption {
+	return func(o *handlerOptions) {
+		o.urls = validator
+	}
+}
+
+// WithFetchURLValidator refuses the documentUrl values downloaded in the
+// server fetch mode with validator instead of the one of WithURLValidator, so
+// that this server may fetch from hosts the service can't be sent to.
+func WithFetchURLValidator(validator URLValidator) HandlerOption {
+	return func(o *handlerOptions) {
+		o.fetchURLs = validator
+	}
+}
+
+// WithPIIRedaction detects personal data with detector instead of the built-in
+// detectors. If always is set, it's redacted from every result rather than
+// only for calls setting redactPii.
+func WithPIIRedaction(detector pii.Detector, always bool) HandlerOption {
+	return func(o *handlerOptions) {
+		o.pii = detector
+		o.alwaysPII = always
+	}
+}
+
+// WithAllowedModels restricts the accepted model IDs to those of models that
+// are supported.
+func WithAllowedModels(models []string) HandlerOption {
+	return func(o *handlerOptions) {
+		o.models = make(map[string]bool, len(models))
+		for _, m := range models {
+			o.models[m] = supportedModels[m]
+		}
+	}
+}
+
+// WithAnalysisQueue makes every analysis wait for a slot of queue before
+// anything is sent to the service.
+func WithAnalysisQueue(queue AnalysisQueue) HandlerOption {
+	return func(o *handlerOptions) {
+		o.queue = queue
+	}
+}
+
+// WithResultPublisher publishes every completed analysis and links it from the tool result.
+func WithResultPublisher(publisher ResultPublisher) HandlerOption {
+	return func(o *handlerOptions) {
+		o.publisher = publisher
+	}
+}
+
+// WithLogger logs analyses with logger. Records are also forwarded to the
+// calling client as MCP log messages once it has set a log level.
+func WithLogger(logger *slog.Logger) HandlerOption {
+	return func(o *handlerOptions) {
+		o.logger = logger
+	}
 }
 
 // NewAnalysisHandler creates a tool handler for document analysis.
-func NewAnalysisHandler(analyzerRepo analysis.Repository) func(context.Context, *mcp.CallToolRequest, *AnalysisParams) (*mcp.CallToolResult, *analysis.AnalyzeOperationResult, error) {
-	return func(ctx context.Context, req *mcp.CallToolRequest, params *AnalysisParams) (*mcp.CallToolResult, *analysis.AnalyzeOperationResult, error) {
-		if params.ModelID != "prebuilt-read" && params.ModelID != "prebuilt-layout" {
-			return nil, nil, fmt.Errorf("unsupported modelId: %s", params.ModelID)
+func NewAnalysisHandler(analyzerRepo analysis.Repository, opts ...HandlerOption) func(context.Context, *mcp.CallToolRequest, *AnalysisParams) (*mcp.CallToolResult, *AnalysisOutput, error) {
+	a := newAnalyzer(analyzerRepo, opts)
+	return func(ctx context.Context, req *mcp.CallToolRequest, params *AnalysisParams) (*mcp.CallToolResult, *AnalysisOutput, error) {
+		ctx = logging.NewContext(ctx, sessionLogger(a.logger, a.redactor, req))
+		if err := a.checkModel(params.ModelID); err != nil {
+			return nil, nil, err
+		}
+		options, err := a.documentOptions(ctx, params)
+		if err != nil {
+			return nil, nil, err
+		}
+		output, uri, err := a.analyze(ctx, req, params, options)
+		if err != nil {
+			return nil, nil, err
 		}
+		if uri == "" {
+			return nil, output, nil
+		}
+		return linkResult(uri, params.ModelID, output)
+	}
+}
+
+// analyzer analyzes documents for the analysis tools.
+type analyzer struct {
+	handlerOptions
+	repo analysis.Repository
+}
 
-		if (params.DocumentURL == "" && params.DocumentContent == "") || (params.DocumentURL != "" && params.DocumentContent != "") {
-			return nil, nil, errors.New("either documentUrl or documentContent must be provided, but not both")
+func newAnalyzer(repo analysis.Repository, opts []HandlerOption) *analyzer {
+	a := &analyzer{repo: repo}
+	for _, opt := range opts {
+		opt(&a.handlerOptions)
+	}
+	if a.pii == nil {
+		a.pii = pii.Default()
+	}
+	if a.models == nil {
+		a.models = supportedModels
+	}
+	return a
+}
+
+// checkModel refuses model IDs that aren't supported or enabled.
+func (a *analyzer) checkModel(modelID string) error {
+	if !supportedModels[modelID] {
+		return fmt.Errorf("unsupported modelId: %s", modelID)
+	}
+	if !a.models[modelID] {
+		return fmt.Errorf("modelId %s is not enabled on this server", modelID)
+	}
+	return nil
+}
+
+// documentOptions returns the document to analyze given by the URL or
+// content of params, downloading it first in the server fetch mode.
+func (a *analyzer) documentOptions(ctx context.Context, params *AnalysisParams) (analysis.AnalyzeDocumentOptions, error) {
+	var options analysis.AnalyzeDocumentOptions
+	if (params.DocumentURL == "" && params.DocumentContent == "") || (params.DocumentURL != "" && params.DocumentContent != "") {
+		return options, errors.New("either documentUrl or documentContent must be provided, but not both")
+	}
+
+	urls := a.urls
+	if params.FetchMode == fetchModeServer && a.fetchURLs != nil {
+		urls = a.fetchURLs
+	}
+	if params.DocumentURL != "" && urls != nil {
+		if err := urls.Validate(ctx, params.DocumentURL); err != nil {
+			logging.FromContext(ctx).WarnContext(ctx, "refused document URL", "modelId", params.ModelID, "error", err)
+			return options, err
+		}
+	}
+
+	docURL, contentType := params.DocumentURL, params.ContentType
+	var content []byte
+	var err error
+	if params.DocumentContent != "" {
+		if params.ContentType == "" {
+			return options, errors.New("contentType must be provided when using documentContent")
+		}
+		content, err = base64.StdEncoding.DecodeString(params.DocumentContent)
+		if err != nil {
+			return options, fmt.Errorf("failed to decode documentContent: %w", err)
+		}
+	}
+
+	switch params.FetchMode {
+	case "", fetchModeAzure:
+	case fetchModeServer:
+		if params.DocumentURL == "" {
+			return options, errors.New("fetchMode server requires documentUrl")
+		}
+		if a.fetcher == nil {
+			return options, errors.New("fetchMode server is not enabled on this server")
+		}
+		content, contentType, err = fetchDocument(ctx, a.fetcher, params.DocumentURL, params.ContentType)
+		if err != nil {
+			return options, err
+		}
+		docURL = ""
+	default:
+		return options, fmt.Errorf("unsupported fetchMode: %s", params.FetchMode)
+	}
+
+	return analysis.AnalyzeDocumentOptions{
+		DocURL:      docURL,
+		Content:     content,
+		ContentType: contentType,
+		Pages:       params.Pages,
+		Region:      params.Region,
+	}, nil
+}
+
+// analyze analyzes the document of options as requested by params, and
+// returns the output of the tool and the URI the result was published at,
+// if any.
+func (a *analyzer) analyze(ctx context.Context, req *mcp.CallToolRequest, params *AnalysisParams, options analysis.AnalyzeDocumentOptions) (*AnalysisOutput, string, error) {
+	if options.Pages != "" {
+		if _, err := analysis.CountPages(options.Pages); err != nil {
+			return nil, "", err
+		}
+	}
+
+	// Queue before reserving pages, so that waiting analyses don't hold budget.
+	if a.queue != nil {
+		release, err := a.queue.Acquire(ctx, sessionID(req))
+		if err != nil {
+			return nil, "", err
 		}
+		defer release()
+	}
 
-		var content []byte
+	var reserved *reservation
+	if a.usage != nil {
 		var err error
-		if params.DocumentContent != "" {
-			if params.ContentType == "" {
-				return nil, nil, errors.New("contentType must be provided when using documentContent")
-			}
-			content, err = base64.StdEncoding.DecodeString(params.DocumentContent)
-			if err != nil {
-				return nil, nil, fmt.Errorf("failed to decode documentContent: %w", err)
-			}
+		if reserved, err = a.usage.reserve(ctx, options.Pages); err != nil {
+			return nil, "", err
 		}
+		defer reserved.release()
+		options.Pages, options.PageLimit = reserved.pages, reserved.limit
+	}
 
-		options := analysis.AnalyzeDocumentOptions{
-			DocURL:      params.DocumentURL,
-			Content:     content,
-			ContentType: params.ContentType,
+	result, err := a.repo.AnalyzeDocument(ctx, params.ModelID, options)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// Results read locally aren't billed, and shared ones are billed to the
+	// request that received them first.
+	if reserved != nil && result.Source != analysis.SourceLocal && result.Source != analysis.SourceShared {
+		if err := a.usage.record(ctx, req, params.ModelID, result, reserved); err != nil {
+			return nil, "", err
 		}
+	}
 
-		result, err := analyzerRepo.AnalyzeDocument(ctx, params.ModelID, options)
+	// Redact before publishing, so that the resource doesn't reveal what the result hides.
+	var piiReport []*analysis.PIIItem
+	if params.RedactPII || a.alwaysPII {
+		result, piiReport, err = redactPII(result, a.pii)
 		if err != nil {
-			return nil, nil, err
+			return nil, "", err
 		}
-		return nil, result, nil
 	}
+
+	var uri string
+	if a.publisher != nil {
+		uri = a.publisher.Publish(sessionID(req), result)
+	}
+	if a.history != nil {
+		saveRecord(ctx, a.history, req, params.ModelID, options, result)
+	}
+
+	filtered, report := reviewConfidence(result, params.Confidence)
+	output := &AnalysisOutput{AnalyzeOperationResult: filtered}
+	if params.Simplify {
+		output = simplifyOutput(filtered)
+	}
+	output.ReviewReport = report
+	output.PIIReport = piiReport
+	if reserved != nil && reserved.limitReached(result) {
+		output.PageLimitReached = true
+		logging.FromContext(ctx).WarnContext(ctx, "analysis reached the maximum pages per request", "modelId", params.ModelID, "pages", reserved.pages)
+	}
+	return output, uri, nil
+}
+
+// fetchDocument downloads the document at rawURL. An explicit contentType
+// takes precedence over the one the document was served with.
+func fetchDocument(ctx context.Context, fetcher DocumentFetcher, rawURL, contentType string) ([]byte, string, error) {
+	content, fetchedType, err := fetcher.Fetch(ctx, rawURL)
+	if err != nil {
+		return nil, "", err
+	}
+	if contentType == "" {
+		contentType = fetchedType
+	}
+	return content, contentType, nil
+}
+
+// redactPII returns a copy of the result with the personal data found by
+// detector replaced, and the list of replacements.
+func redactPII(result *analysis.AnalyzeOperationResult, detector pii.Detector) (*analysis.AnalyzeOperationResult, []*analysis.PIIItem, error) {
+	if result.AnalyzeResult == nil {
+		return result, nil, nil
+	}
+	redacted, report, err := result.AnalyzeResult.RedactPII(detector)
+	if err != nil {
+		return nil, nil, err
+	}
+	operation := *result
+	operation.AnalyzeResult = redacted
+	return &operation, report, nil
+}
+
+// reviewConfidence reports the elements of the result below the requested
+// thresholds and, if requested, returns a copy of the result without them.
+func reviewConfidence(result *analysis.AnalyzeOperationResult, params *ConfidenceParams) (*analysis.AnalyzeOperationResult, []*analysis.ReviewItem) {
+	if params == nil || result.AnalyzeResult == nil {
+		return result, nil
+	}
+	th := analysis.ConfidenceThresholds{
+		Word:          params.Word,
+		SelectionMark: params.SelectionMark,
+		KeyValuePair:  params.KeyValuePair,
+		Field:         params.Field,
+	}
+	report := result.AnalyzeResult.LowConfidence(th)
+	if !params.Filter {
+		return result, report
+	}
+	operation := *result
+	operation.AnalyzeResult = result.AnalyzeResult.WithoutLowConfidence(th)
+	return &operation, report
+}
+
+// simplifyOutput moves the documents of the result into their flattened form.
+func simplifyOutput(result *analysis.AnalyzeOperationResult) *AnalysisOutput {
+	output := &AnalysisOutput{AnalyzeOperationResult: result}
+	if result.AnalyzeResult == nil || len(result.AnalyzeResult.Documents) == 0 {
+		return output
+	}
+
+	for _, doc := range result.AnalyzeResult.Documents {
+		output.SimplifiedDocuments = append(output.SimplifiedDocuments, &SimplifiedDocument{
+			DocType:    doc.DocType,
+			Confidence: doc.Confidence,
+			Fields:     doc.Flatten(),
+		})
+	}
+
+	// Copy the result so that the published one keeps its documents.
+	operation := *result
+	analyzeResult := *result.AnalyzeResult
+	analyzeResult.Documents = nil
+	operation.AnalyzeResult = &analyzeResult
+	output.AnalyzeOperationResult = &operation
+	return output
+}
+
+// linkResult returns a tool result that carries both the serialized output
+// and a link to the published resource.
+func linkResult(uri, modelID string, output *AnalysisOutput) (*mcp.CallToolResult, *AnalysisOutput, error) {
+	text, err := json.Marshal(output)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to marshal result: %w", err)
+	}
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: string(text)},
+			&mcp.ResourceLink{
+				URI:      uri,
+				Name:     modelID + " analysis",
+				MIMEType: "application/json",
+			},
+		},
+	}, output, nil
 }
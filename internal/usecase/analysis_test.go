@@ -1,12 +1,20 @@
 package usecase
 
 import (
+	"bytes"
 	"context"
 	"encoding/base64"
 	"errors"
+	"fmt"
+	"log/slog"
 	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
 
 	"github.com/linzhengen/azure-document-intelligence-mcp/internal/domain/analysis"
+	"github.com/linzhengen/azure-document-intelligence-mcp/internal/domain/pii"
+	"github.com/linzhengen/azure-document-intelligence-mcp/internal/logging"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -76,6 +84,18 @@ func TestAnalysisHandler_UnsupportedModelID(t *testing.T) {
 	assert.Contains(t, err.Error(), "unsupported modelId")
 }
 
+func TestAnalysisHandler_ModelNotAllowed(t *testing.T) {
+	ctx := context.Background()
+	handler := NewAnalysisHandler(&MockAnalysisRepository{}, WithAllowedModels([]string{"prebuilt-read"}))
+
+	_, _, err := handler(ctx, nil, &AnalysisParams{ModelID: "prebuilt-invoice", DocumentURL: "http://example.com/doc.pdf"})
+	assert.ErrorContains(t, err, "not enabled")
+
+	_, result, err := handler(ctx, nil, &AnalysisParams{ModelID: "prebuilt-read", DocumentURL: "http://example.com/doc.pdf"})
+	require.NoError(t, err)
+	assert.Equal(t, "succeeded", result.Status)
+}
+
 func TestAnalysisHandler_MissingDocumentSource(t *testing.T) {
 	ctx := context.Background()
 	mockRepo := &MockAnalysisRepository{}
@@ -161,3 +181,350 @@ func TestAnalysisHandler_AnalyzerError(t *testing.T) {
 	require.Error(t, err)
 	assert.Equal(t, analyzerErr, err)
 }
+
+type stubPublisher struct {
+	published []*analysis.AnalyzeOperationResult
+}
+
+func (p *stubPublisher) Publish(_ string, result *analysis.AnalyzeOperationResult) string {
+	p.published = append(p.published, result)
+	return "docintel://results/stub"
+}
+
+func TestAnalysisHandler_PublishesResult(t *testing.T) {
+	ctx := context.Background()
+	mockRepo := &MockAnalysisRepository{}
+	publisher := &stubPublisher{}
+	handler := NewAnalysisHandler(mockRepo, WithResultPublisher(publisher))
+
+	params := &AnalysisParams{
+		ModelID:     "prebuilt-read",
+		DocumentURL: "http://example.com/doc.pdf",
+	}
+
+	res, result, err := handler(ctx, nil, params)
+
+	require.NoError(t, err)
+	require.NotNil(t, result)
+	require.Len(t, publisher.published, 1)
+	assert.Same(t, result.AnalyzeOperationResult, publisher.published[0])
+	require.Len(t, res.Content, 2)
+	link, ok := res.Content[1].(*mcp.ResourceLink)
+	require.True(t, ok)
+	assert.Equal(t, "docintel://results/stub", link.URI)
+}
+
+func TestAnalysisHandler_Simplify(t *testing.T) {
+	ctx := context.Background()
+	vendor := "Contoso"
+	amount := 42.5
+	mockRepo := &MockAnalysisRepository{
+		AnalyzeDocumentFunc: func(ctx context.Context, modelID string, options analysis.AnalyzeDocumentOptions) (*analysis.AnalyzeOperationResult, error) {
+			return &analysis.AnalyzeOperationResult{
+				Status: "succeeded",
+				AnalyzeResult: &analysis.AnalyzeResult{
+					Documents: []*analysis.Document{{
+						DocType:    "invoice",
+						Confidence: 0.9,
+						Fields: map[string]*analysis.DocumentField{
+							"VendorName":   {Type: "string", ValueString: &vendor},
+							"InvoiceTotal": {Type: "number", ValueNumber: &amount},
+						},
+					}},
+				},
+			}, nil
+		},
+	}
+	publisher := &stubPublisher{}
+	handler := NewAnalysisHandler(mockRepo, WithResultPublisher(publisher))
+
+	params := &AnalysisParams{
+		ModelID:     "prebuilt-invoice",
+		DocumentURL: "http://example.com/invoice.pdf",
+		Simplify:    true,
+	}
+
+	_, result, err := handler(ctx, nil, params)
+
+	require.NoError(t, err)
+	require.Len(t, result.SimplifiedDocuments, 1)
+	assert.Equal(t, "invoice", result.SimplifiedDocuments[0].DocType)
+	assert.Equal(t, map[string]any{"VendorName": "Contoso", "InvoiceTotal": 42.5}, result.SimplifiedDocuments[0].Fields)
+	assert.Empty(t, result.AnalyzeResult.Documents)
+	// The published result keeps the full documents.
+	assert.Len(t, publisher.published[0].AnalyzeResult.Documents, 1)
+}
+
+func TestAnalysisHandler_ConfidenceReport(t *testing.T) {
+	ctx := context.Background()
+	mockRepo := &MockAnalysisRepository{
+		AnalyzeDocumentFunc: func(ctx context.Context, modelID string, options analysis.AnalyzeDocumentOptions) (*analysis.AnalyzeOperationResult, error) {
+			return &analysis.AnalyzeOperationResult{
+				Status: "succeeded",
+				AnalyzeResult: &analysis.AnalyzeResult{
+					Pages: []analysis.Page{{
+						PageNumber: 2,
+						Words: []*analysis.Word{
+							{Content: "sure", Confidence: 0.99},
+							{Content: "unsure", Confidence: 0.3},
+						},
+					}},
+				},
+			}, nil
+		},
+	}
+	publisher := &stubPublisher{}
+	handler := NewAnalysisHandler(mockRepo, WithResultPublisher(publisher))
+
+	params := &AnalysisParams{
+		ModelID:     "prebuilt-read",
+		DocumentURL: "http://example.com/doc.pdf",
+		Confidence:  &ConfidenceParams{Word: 0.5, Filter: true},
+	}
+
+	_, result, err := handler(ctx, nil, params)
+
+	require.NoError(t, err)
+	require.Len(t, result.ReviewReport, 1)
+	assert.Equal(t, "unsure", result.ReviewReport[0].Value)
+	assert.Equal(t, int32(2), result.ReviewReport[0].PageNumber)
+	require.Len(t, result.AnalyzeResult.Pages[0].Words, 1)
+	assert.Equal(t, "sure", result.AnalyzeResult.Pages[0].Words[0].Content)
+	assert.Len(t, publisher.published[0].AnalyzeResult.Pages[0].Words, 2)
+}
+
+func piiRepository() *MockAnalysisRepository {
+	return &MockAnalysisRepository{
+		AnalyzeDocumentFunc: func(ctx context.Context, modelID string, options analysis.AnalyzeDocumentOptions) (*analysis.AnalyzeOperationResult, error) {
+			return &analysis.AnalyzeOperationResult{
+				Status: "succeeded",
+				AnalyzeResult: &analysis.AnalyzeResult{
+					Content: "Contact jane@contoso.com, employee EMP-123456",
+					Pages:   []analysis.Page{{PageNumber: 1, Spans: []analysis.Span{{Offset: 0, Length: 45}}}},
+				},
+			}, nil
+		},
+	}
+}
+
+func TestAnalysisHandler_RedactPII(t *testing.T) {
+	ctx := context.Background()
+	publisher := &stubPublisher{}
+	handler := NewAnalysisHandler(piiRepository(), WithResultPublisher(publisher))
+
+	params := &AnalysisParams{
+		ModelID:     "prebuilt-read",
+		DocumentURL: "http://example.com/doc.pdf",
+		RedactPII:   true,
+	}
+
+	_, result, err := handler(ctx, nil, params)
+
+	require.NoError(t, err)
+	assert.Equal(t, "Contact [EMAIL_1], employee EMP-123456", result.AnalyzeResult.Content)
+	require.Len(t, result.PIIReport, 1)
+	assert.Equal(t, &analysis.PIIItem{Category: pii.CategoryEmail, Placeholder: "[EMAIL_1]", PageNumber: 1, Span: &analysis.Span{Offset: 8, Length: 9}}, result.PIIReport[0])
+	assert.Equal(t, result.AnalyzeResult.Content, publisher.published[0].AnalyzeResult.Content, "the published result is redacted too")
+
+	params.RedactPII = false
+	_, result, err = handler(ctx, nil, params)
+
+	require.NoError(t, err)
+	assert.Contains(t, result.AnalyzeResult.Content, "jane@contoso.com")
+	assert.Empty(t, result.PIIReport)
+}
+
+func TestAnalysisHandler_RedactPIIAlwaysWithCustomDetector(t *testing.T) {
+	ctx := context.Background()
+	employee, err := pii.NewPattern("EMPLOYEE_ID", `EMP-\d{6}`)
+	require.NoError(t, err)
+	handler := NewAnalysisHandler(piiRepository(), WithPIIRedaction(pii.Combine(pii.Default(), employee), true))
+
+	params := &AnalysisParams{
+		ModelID:     "prebuilt-read",
+		DocumentURL: "http://example.com/doc.pdf",
+	}
+
+	_, result, err := handler(ctx, nil, params)
+
+	require.NoError(t, err)
+	assert.Equal(t, "Contact [EMAIL_1], employee [EMPLOYEE_ID_1]", result.AnalyzeResult.Content)
+	assert.Len(t, result.PIIReport, 2)
+}
+
+func TestNewAnalysisHandler_ForwardsLogs(t *testing.T) {
+	ctx := context.Background()
+	var stderr bytes.Buffer
+	mockRepo := &MockAnalysisRepository{
+		AnalyzeDocumentFunc: func(ctx context.Context, modelID string, options analysis.AnalyzeDocumentOptions) (*analysis.AnalyzeOperationResult, error) {
+			logging.FromContext(ctx).Info("document analysis completed", "modelId", modelID)
+			return &analysis.AnalyzeOperationResult{Status: "succeeded"}, nil
+		},
+	}
+	outputSchema, err := AnalysisOutputSchema()
+	require.NoError(t, err)
+	server := mcp.NewServer(&mcp.Implementation{Name: "test"}, nil)
+	mcp.AddTool(server, &mcp.Tool{Name: "analyze_document", OutputSchema: outputSchema}, NewAnalysisHandler(mockRepo, WithLogger(logging.New(&stderr, slog.LevelInfo))))
+
+	serverTransport, clientTransport := mcp.NewInMemoryTransports()
+	serverSession, err := server.Connect(ctx, serverTransport, nil)
+	require.NoError(t, err)
+	defer func() { _ = serverSession.Close() }()
+	messages := make(chan *mcp.LoggingMessageParams, 1)
+	client := mcp.NewClient(&mcp.Implementation{Name: "client"}, &mcp.ClientOptions{
+		LoggingMessageHandler: func(_ context.Context, req *mcp.LoggingMessageRequest) { messages <- req.Params },
+	})
+	session, err := client.Connect(ctx, clientTransport, nil)
+	require.NoError(t, err)
+	defer func() { _ = session.Close() }()
+	require.NoError(t, session.SetLoggingLevel(ctx, &mcp.SetLoggingLevelParams{Level: "info"}))
+
+	res, err := session.CallTool(ctx, &mcp.CallToolParams{
+		Name:      "analyze_document",
+		Arguments: map[string]any{"modelId": "prebuilt-read", "documentUrl": "https://example.com/doc.pdf"},
+	})
+	require.NoError(t, err)
+	require.False(t, res.IsError, "%v", res.Content)
+
+	assert.Contains(t, stderr.String(), `"modelId":"prebuilt-read"`)
+	select {
+	case msg := <-messages:
+		assert.Equal(t, mcp.LoggingLevel("info"), msg.Level)
+		assert.Equal(t, loggerName, msg.Logger)
+		assert.Contains(t, fmt.Sprint(msg.Data), "document analysis completed")
+	case <-time.After(5 * time.Second):
+		t.Fatal("no log message received")
+	}
+}
+
+// stubURLValidator refuses the URLs in its set.
+type stubURLValidator map[string]bool
+
+func (v stubURLValidator) Validate(_ context.Context, rawURL string) error {
+	if v[rawURL] {
+		return fmt.Errorf("documentUrl %q is not allowed", rawURL)
+	}
+	return nil
+}
+
+func TestNewAnalysisHandler_RefusesURL(t *testing.T) {
+	ctx := context.Background()
+	called := false
+	mockRepo := &MockAnalysisRepository{
+		AnalyzeDocumentFunc: func(ctx context.Context, modelID string, options analysis.AnalyzeDocumentOptions) (*analysis.AnalyzeOperationResult, error) {
+			called = true
+			return &analysis.AnalyzeOperationResult{Status: "succeeded"}, nil
+		},
+	}
+	handler := NewAnalysisHandler(mockRepo, WithURLValidator(stubURLValidator{"https://169.254.169.254/": true}))
+
+	_, _, err := handler(ctx, nil, &AnalysisParams{ModelID: "prebuilt-read", DocumentURL: "https://169.254.169.254/"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "not allowed")
+	assert.False(t, called)
+
+	_, _, err = handler(ctx, nil, &AnalysisParams{ModelID: "prebuilt-read", DocumentURL: "https://example.com/doc.pdf"})
+	require.NoError(t, err)
+	assert.True(t, called)
+}
+
+// stubFetcher serves a fixed document.
+type stubFetcher struct {
+	urls []string
+}
+
+func (f *stubFetcher) Fetch(_ context.Context, rawURL string) ([]byte, string, error) {
+	f.urls = append(f.urls, rawURL)
+	return []byte("%PDF-1.7"), "application/pdf", nil
+}
+
+func TestNewAnalysisHandler_ServerFetchMode(t *testing.T) {
+	ctx := context.Background()
+	var got analysis.AnalyzeDocumentOptions
+	mockRepo := &MockAnalysisRepository{
+		AnalyzeDocumentFunc: func(ctx context.Context, modelID string, options analysis.AnalyzeDocumentOptions) (*analysis.AnalyzeOperationResult, error) {
+			got = options
+			return &analysis.AnalyzeOperationResult{Status: "succeeded"}, nil
+		},
+	}
+	fetcher := &stubFetcher{}
+	params := &AnalysisParams{ModelID: "prebuilt-read", DocumentURL: "http://intranet.example.com/doc.pdf", FetchMode: "server"}
+
+	_, _, err := NewAnalysisHandler(mockRepo)(ctx, nil, params)
+	assert.ErrorContains(t, err, "not enabled")
+
+	_, _, err = NewAnalysisHandler(mockRepo, WithDocumentFetcher(fetcher))(ctx, nil, params)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"http://intranet.example.com/doc.pdf"}, fetcher.urls)
+	assert.Empty(t, got.DocURL)
+	assert.Equal(t, []byte("%PDF-1.7"), got.Content)
+	assert.Equal(t, "application/pdf", got.ContentType)
+
+	_, _, err = NewAnalysisHandler(mockRepo, WithDocumentFetcher(fetcher))(ctx, nil, &AnalysisParams{ModelID: "prebuilt-read", DocumentURL: "http://example.com/a.pdf", FetchMode: "proxy"})
+	assert.ErrorContains(t, err, "unsupported fetchMode")
+}
+
+func TestNewAnalysisHandler_FetchURLValidator(t *testing.T) {
+	ctx := context.Background()
+	mockRepo := &MockAnalysisRepository{
+		AnalyzeDocumentFunc: func(ctx context.Context, modelID string, options analysis.AnalyzeDocumentOptions) (*analysis.AnalyzeOperationResult, error) {
+			return &analysis.AnalyzeOperationResult{Status: "succeeded"}, nil
+		},
+	}
+	const intranet = "http://intranet.example.com/doc.pdf"
+	fetcher := &stubFetcher{}
+	handler := NewAnalysisHandler(mockRepo,
+		WithDocumentFetcher(fetcher),
+		WithURLValidator(stubURLValidator{intranet: true}),
+		WithFetchURLValidator(stubURLValidator{"https://169.254.169.254/": true}),
+	)
+
+	_, _, err := handler(ctx, nil, &AnalysisParams{ModelID: "prebuilt-read", DocumentURL: intranet})
+	assert.ErrorContains(t, err, "not allowed", "URLs sent to the service use the URL validator")
+
+	_, _, err = handler(ctx, nil, &AnalysisParams{ModelID: "prebuilt-read", DocumentURL: intranet, FetchMode: "server"})
+	require.NoError(t, err, "URLs downloaded by the server use the fetch URL validator")
+	assert.Equal(t, []string{intranet}, fetcher.urls)
+
+	_, _, err = handler(ctx, nil, &AnalysisParams{ModelID: "prebuilt-read", DocumentURL: "https://169.254.169.254/", FetchMode: "server"})
+	assert.ErrorContains(t, err, "not allowed")
+	assert.Len(t, fetcher.urls, 1)
+}
+
+// stubQueue records the slots taken and fails once closed.
+type stubQueue struct {
+	closed   bool
+	active   int
+	released int
+}
+
+func (q *stubQueue) Acquire(ctx context.Context, session string) (func(), error) {
+	if q.closed {
+		return nil, context.Canceled
+	}
+	q.active++
+	return func() { q.active--; q.released++ }, nil
+}
+
+func TestNewAnalysisHandler_Queue(t *testing.T) {
+	ctx := context.Background()
+	queue := &stubQueue{}
+	var activeDuringAnalysis int
+	mockRepo := &MockAnalysisRepository{
+		AnalyzeDocumentFunc: func(ctx context.Context, modelID string, options analysis.AnalyzeDocumentOptions) (*analysis.AnalyzeOperationResult, error) {
+			activeDuringAnalysis = queue.active
+			return &analysis.AnalyzeOperationResult{Status: "succeeded"}, nil
+		},
+	}
+	handler := NewAnalysisHandler(mockRepo, WithAnalysisQueue(queue))
+	params := &AnalysisParams{ModelID: "prebuilt-read", DocumentURL: "https://example.com/doc.pdf"}
+
+	_, _, err := handler(ctx, nil, params)
+	require.NoError(t, err)
+	assert.Equal(t, 1, activeDuringAnalysis)
+	assert.Equal(t, 1, queue.released)
+
+	queue.closed = true
+	_, _, err = handler(ctx, nil, params)
+	assert.ErrorIs(t, err, context.Canceled)
+}
@@ -0,0 +1,240 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+
+	"github.com/linzhengen/azure-document-intelligence-mcp/internal/domain/analysis"
+	"github.com/linzhengen/azure-document-intelligence-mcp/internal/logging"
+)
+
+// BatchAnalysisParams defines the parameters for the batch analysis tool.
+// Every document is analyzed with the same model and options.
+type BatchAnalysisParams struct {
+	ModelID   string           `json:"modelId" jsonschema:"Document model ID: prebuilt-read, prebuilt-layout, prebuilt-invoice or prebuilt-contract."`
+	Documents []*BatchDocument `json:"documents" jsonschema:"Documents to analyze, each given by exactly one of documentUrl, documentPath or documentContent."`
+	FetchMode string           `json:"fetchMode,omitempty" jsonschema:"Who downloads the documentUrl of each document: azure (default) or server."`
+	Region    string           `json:"region,omitempty" jsonschema:"Data residency: only analyze the documents on endpoints tagged with this region, such as westeurope."`
+	Simplify  bool             `json:"simplify,omitempty" jsonschema:"Return the fields of extracted documents as flattened plain values in simplifiedDocuments."`
+	RedactPII bool             `json:"redactPii,omitempty" jsonschema:"Replace personal data in the results by placeholders, listed in piiReport."`
+
+	Confidence *ConfidenceParams `json:"confidence,omitempty" jsonschema:"Minimum confidence per element type. Elements below it are listed in reviewReport."`
+}
+
+// BatchDocument is a document of the batch analysis tool.
+type BatchDocument struct {
+	DocumentURL     string `json:"documentUrl,omitempty" jsonschema:"URL of the document."`
+	DocumentPath    string `json:"documentPath,omitempty" jsonschema:"Absolute path of a file on this server, in one of its document directories."`
+	DocumentContent string `json:"documentContent,omitempty" jsonschema:"Base64 encoded bytes of the document."`
+	ContentType     string `json:"contentType,omitempty" jsonschema:"MIME type of the document, for example application/pdf. Required with documentContent; detected from the file for documentPath."`
+	Pages           string `json:"pages,omitempty" jsonschema:"Pages to analyze, such as 1-3,5. Defaults to all pages, within the page budget."`
+}
+
+// BatchAnalysisOutput is the output of the batch analysis tool.
+type BatchAnalysisOutput struct {
+	Succeeded int            `json:"succeeded" jsonschema:"Number of documents analyzed."`
+	Failed    int            `json:"failed" jsonschema:"Number of documents that couldn't be analyzed."`
+	Results   []*BatchResult `json:"results" jsonschema:"Outcome of each document, in the order they were given."`
+}
+
+// BatchResult is the outcome of the analysis of a document of a batch:
+// either its output or an error.
+type BatchResult struct {
+	Document    string          `json:"document" jsonschema:"documentUrl or documentPath of the document, or its position, such as documents[2], for documentContent."`
+	Error       string          `json:"error,omitempty" jsonschema:"Why the document couldn't be analyzed."`
+	ResourceURI string          `json:"resourceUri,omitempty" jsonschema:"URI of the result as a resource."`
+	Output      *AnalysisOutput `json:"output,omitempty" jsonschema:"Status and result of the analysis."`
+}
+
+// BatchLimits bound the work of a call to the batch analysis tool.
+type BatchLimits struct {
+	// Concurrency bounds the documents analyzed at once. Zero means one at a time.
+	Concurrency int
+	// MaxDocuments bounds the documents of a call. Zero means no limit.
+	MaxDocuments int
+}
+
+// DocumentReader reads documents from files of this server.
+type DocumentReader interface {
+	Read(ctx context.Context, path string) (content []byte, contentType string, err error)
+}
+
+// WithDocumentReader enables documentPath, read with reader.
+func WithDocumentReader(reader DocumentReader) HandlerOption {
+	return func(o *handlerOptions) {
+		o.files = reader
+	}
+}
+
+// NewBatchAnalysisHandler creates a tool handler analyzing several documents,
+// each as the analysis tool does, limits.Concurrency at a time. A document
+// that fails doesn't fail the others. Clients that asked for progress are
+// notified as each document completes.
+func NewBatchAnalysisHandler(analyzerRepo analysis.Repository, limits BatchLimits, opts ...HandlerOption) func(context.Context, *mcp.CallToolRequest, *BatchAnalysisParams) (*mcp.CallToolResult, *BatchAnalysisOutput, error) {
+	a := newAnalyzer(analyzerRepo, opts)
+	return func(ctx context.Context, req *mcp.CallToolRequest, params *BatchAnalysisParams) (*mcp.CallToolResult, *BatchAnalysisOutput, error) {
+		ctx = logging.NewContext(ctx, sessionLogger(a.logger, a.redactor, req))
+		if err := a.checkModel(params.ModelID); err != nil {
+			return nil, nil, err
+		}
+		if len(params.Documents) == 0 {
+			return nil, nil, errors.New("documents must not be empty")
+		}
+		if limits.MaxDocuments > 0 && len(params.Documents) > limits.MaxDocuments {
+			return nil, nil, fmt.Errorf("too many documents: %d, at most %d are allowed per call", len(params.Documents), limits.MaxDocuments)
+		}
+
+		output := &BatchAnalysisOutput{Results: make([]*BatchResult, len(params.Documents))}
+		progress := newProgress(req, len(params.Documents))
+		indexes := make(chan int)
+		var wg sync.WaitGroup
+		for range min(max(limits.Concurrency, 1), len(params.Documents)) {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := range indexes {
+					output.Results[i] = a.analyzeBatchDocument(ctx, req, params, i)
+					progress.done(ctx)
+				}
+			}()
+		}
+		for i := range params.Documents {
+			indexes <- i
+		}
+		close(indexes)
+		wg.Wait()
+
+		var links []mcp.Content
+		for _, r := range output.Results {
+			if r.Error != "" {
+				output.Failed++
+				continue
+			}
+			output.Succeeded++
+			if r.ResourceURI != "" {
+				links = append(links, &mcp.ResourceLink{URI: r.ResourceURI, Name: params.ModelID + " analysis of " + r.Document, MIMEType: "application/json"})
+			}
+		}
+		logging.FromContext(ctx).InfoContext(ctx, "analyzed documents", "modelId", params.ModelID, "succeeded", output.Succeeded, "failed", output.Failed)
+
+		if len(links) == 0 {
+			return nil, output, nil
+		}
+		text, err := json.Marshal(output)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to marshal result: %w", err)
+		}
+		return &mcp.CallToolResult{Content: append([]mcp.Content{&mcp.TextContent{Text: string(text)}}, links...)}, output, nil
+	}
+}
+
+// analyzeBatchDocument analyzes the document i of params.
+func (a *analyzer) analyzeBatchDocument(ctx context.Context, req *mcp.CallToolRequest, params *BatchAnalysisParams, i int) *BatchResult {
+	doc := params.Documents[i]
+	result := &BatchResult{Document: fmt.Sprintf("documents[%d]", i)}
+	if doc == nil {
+		result.Error = "document must not be null"
+		return result
+	}
+	if doc.DocumentPath != "" {
+		result.Document = doc.DocumentPath
+	} else if doc.DocumentURL != "" {
+		result.Document = doc.DocumentURL
+	}
+
+	docParams := &AnalysisParams{
+		ModelID:         params.ModelID,
+		DocumentURL:     doc.DocumentURL,
+		DocumentContent: doc.DocumentContent,
+		ContentType:     doc.ContentType,
+		FetchMode:       params.FetchMode,
+		Pages:           doc.Pages,
+		Region:          params.Region,
+		Simplify:        params.Simplify,
+		RedactPII:       params.RedactPII,
+		Confidence:      params.Confidence,
+	}
+	var options analysis.AnalyzeDocumentOptions
+	var err error
+	if doc.DocumentPath != "" {
+		options, err = a.fileOptions(ctx, docParams, doc.DocumentPath)
+	} else {
+		options, err = a.documentOptions(ctx, docParams)
+	}
+	if err == nil {
+		result.Output, result.ResourceURI, err = a.analyze(ctx, req, docParams, options)
+	}
+	if err != nil {
+		logging.FromContext(ctx).WarnContext(ctx, "failed to analyze document of batch", "modelId", params.ModelID, "document", i, "error", err)
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// fileOptions returns the document to analyze given by path, a file of this
+// server, as requested by params.
+func (a *analyzer) fileOptions(ctx context.Context, params *AnalysisParams, path string) (analysis.AnalyzeDocumentOptions, error) {
+	var options analysis.AnalyzeDocumentOptions
+	if params.DocumentURL != "" || params.DocumentContent != "" {
+		return options, errors.New("only one of documentUrl, documentPath or documentContent must be provided")
+	}
+	if a.files == nil {
+		return options, errors.New("documentPath is not enabled on this server")
+	}
+	content, contentType, err := a.files.Read(ctx, path)
+	if err != nil {
+		return options, err
+	}
+	if params.ContentType != "" {
+		contentType = params.ContentType
+	}
+	return analysis.AnalyzeDocumentOptions{
+		Content:     content,
+		ContentType: contentType,
+		Pages:       params.Pages,
+		Region:      params.Region,
+	}, nil
+}
+
+// progress notifies the client of a request of the items completed, if it
+// asked for progress notifications.
+type progress struct {
+	mu    sync.Mutex
+	req   *mcp.CallToolRequest
+	token any
+	total int
+	count int
+}
+
+func newProgress(req *mcp.CallToolRequest, total int) *progress {
+	p := &progress{req: req, total: total}
+	if req != nil && req.Session != nil && req.Params != nil {
+		p.token = req.Params.GetProgressToken()
+	}
+	return p
+}
+
+// done records an item as completed. Notifications are sent in order, so
+// that the progress of each one is greater than the previous.
+func (p *progress) done(ctx context.Context) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.count++
+	if p.token == nil {
+		return
+	}
+	err := p.req.Session.NotifyProgress(ctx, &mcp.ProgressNotificationParams{
+		ProgressToken: p.token,
+		Progress:      float64(p.count),
+		Total:         float64(p.total),
+		Message:       fmt.Sprintf("%d of %d documents analyzed", p.count, p.total),
+	})
+	if err != nil {
+		logging.FromContext(ctx).DebugContext(ctx, "failed to notify progress", "error", err)
+	}
+}
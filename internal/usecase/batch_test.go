@@ -0,0 +1,186 @@
+package usecase
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/linzhengen/azure-document-intelligence-mcp/internal/domain/analysis"
+)
+
+// stubReader serves the files it holds.
+type stubReader map[string][]byte
+
+func (r stubReader) Read(_ context.Context, path string) ([]byte, string, error) {
+	content, ok := r[path]
+	if !ok {
+		return nil, "", errors.New("document path is not in a document directory")
+	}
+	return content, "application/pdf", nil
+}
+
+func TestBatchAnalysisHandler_PartialSuccess(t *testing.T) {
+	ctx := context.Background()
+	var mu sync.Mutex
+	var analyzed []analysis.AnalyzeDocumentOptions
+	mockRepo := &MockAnalysisRepository{
+		AnalyzeDocumentFunc: func(ctx context.Context, modelID string, options analysis.AnalyzeDocumentOptions) (*analysis.AnalyzeOperationResult, error) {
+			mu.Lock()
+			analyzed = append(analyzed, options)
+			mu.Unlock()
+			if options.DocURL == "https://example.com/broken.pdf" {
+				return nil, errors.New("analysis failed: InvalidContent")
+			}
+			return &analysis.AnalyzeOperationResult{Status: "succeeded"}, nil
+		},
+	}
+	publisher := &stubPublisher{}
+	handler := NewBatchAnalysisHandler(mockRepo, BatchLimits{Concurrency: 2},
+		WithDocumentReader(stubReader{"/docs/a.pdf": []byte("%PDF-1.7")}),
+		WithResultPublisher(publisher),
+	)
+
+	res, output, err := handler(ctx, nil, &BatchAnalysisParams{
+		ModelID: "prebuilt-layout",
+		Region:  "westeurope",
+		Documents: []*BatchDocument{
+			{DocumentURL: "https://example.com/a.pdf", Pages: "1-2"},
+			{DocumentPath: "/docs/a.pdf"},
+			{DocumentContent: base64.StdEncoding.EncodeToString([]byte("png")), ContentType: "image/png"},
+			{DocumentURL: "https://example.com/broken.pdf"},
+			{DocumentPath: "/etc/passwd"},
+			{DocumentContent: "bm90IGJhc2U2NA", ContentType: "image/png"},
+			{DocumentURL: "https://example.com/a.pdf", DocumentPath: "/docs/a.pdf"},
+			nil,
+		},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, 3, output.Succeeded)
+	assert.Equal(t, 5, output.Failed)
+	require.Len(t, output.Results, 8)
+	for i, want := range []string{"https://example.com/a.pdf", "/docs/a.pdf", "documents[2]", "https://example.com/broken.pdf", "/etc/passwd", "documents[5]", "/docs/a.pdf", "documents[7]"} {
+		assert.Equal(t, want, output.Results[i].Document)
+	}
+	for _, r := range output.Results[:3] {
+		assert.Empty(t, r.Error)
+		assert.Equal(t, "succeeded", r.Output.Status)
+		assert.NotEmpty(t, r.ResourceURI)
+	}
+	assert.Equal(t, "analysis failed: InvalidContent", output.Results[3].Error)
+	assert.Contains(t, output.Results[4].Error, "not in a document directory")
+	assert.Contains(t, output.Results[5].Error, "failed to decode documentContent")
+	assert.Contains(t, output.Results[6].Error, "only one of")
+	assert.Nil(t, output.Results[3].Output)
+
+	require.Len(t, analyzed, 4)
+	for _, options := range analyzed {
+		assert.Equal(t, "westeurope", options.Region)
+		if options.DocURL == "" {
+			assert.NotEmpty(t, options.Content)
+		}
+	}
+	require.Len(t, res.Content, 4, "the output and a link to each published result")
+	assert.IsType(t, &mcp.ResourceLink{}, res.Content[1])
+}
+
+func TestBatchAnalysisHandler_Errors(t *testing.T) {
+	ctx := context.Background()
+	handler := NewBatchAnalysisHandler(&MockAnalysisRepository{}, BatchLimits{MaxDocuments: 2})
+	docs := []*BatchDocument{{DocumentURL: "https://example.com/a.pdf"}, {DocumentURL: "https://example.com/b.pdf"}, {DocumentURL: "https://example.com/c.pdf"}}
+
+	_, _, err := handler(ctx, nil, &BatchAnalysisParams{ModelID: "prebuilt-read", Documents: docs})
+	assert.EqualError(t, err, "too many documents: 3, at most 2 are allowed per call")
+	_, _, err = handler(ctx, nil, &BatchAnalysisParams{ModelID: "prebuilt-read"})
+	assert.EqualError(t, err, "documents must not be empty")
+	_, _, err = handler(ctx, nil, &BatchAnalysisParams{ModelID: "unsupported-model", Documents: docs[:1]})
+	assert.EqualError(t, err, "unsupported modelId: unsupported-model")
+
+	_, output, err := handler(ctx, nil, &BatchAnalysisParams{ModelID: "prebuilt-read", Documents: []*BatchDocument{{DocumentPath: "/docs/a.pdf"}}})
+	require.NoError(t, err)
+	assert.Equal(t, "documentPath is not enabled on this server", output.Results[0].Error)
+}
+
+func TestBatchAnalysisHandler_Concurrency(t *testing.T) {
+	var mu sync.Mutex
+	var inFlight, maxInFlight int
+	mockRepo := &MockAnalysisRepository{
+		AnalyzeDocumentFunc: func(ctx context.Context, modelID string, options analysis.AnalyzeDocumentOptions) (*analysis.AnalyzeOperationResult, error) {
+			mu.Lock()
+			inFlight++
+			maxInFlight = max(maxInFlight, inFlight)
+			mu.Unlock()
+			time.Sleep(10 * time.Millisecond)
+			mu.Lock()
+			inFlight--
+			mu.Unlock()
+			return &analysis.AnalyzeOperationResult{Status: "succeeded"}, nil
+		},
+	}
+	docs := make([]*BatchDocument, 8)
+	for i := range docs {
+		docs[i] = &BatchDocument{DocumentURL: "https://example.com/doc.pdf"}
+	}
+
+	_, output, err := NewBatchAnalysisHandler(mockRepo, BatchLimits{Concurrency: 3})(context.Background(), nil, &BatchAnalysisParams{ModelID: "prebuilt-read", Documents: docs})
+	require.NoError(t, err)
+	assert.Equal(t, 8, output.Succeeded)
+	assert.Equal(t, 3, maxInFlight)
+}
+
+func TestBatchAnalysisHandler_Progress(t *testing.T) {
+	ctx := context.Background()
+	server := mcp.NewServer(&mcp.Implementation{Name: "test"}, nil)
+	outputSchema, err := BatchAnalysisOutputSchema()
+	require.NoError(t, err)
+	mcp.AddTool(server, &mcp.Tool{Name: "analyze_documents", OutputSchema: outputSchema}, NewBatchAnalysisHandler(&MockAnalysisRepository{}, BatchLimits{Concurrency: 2}))
+
+	var mu sync.Mutex
+	var notifications []*mcp.ProgressNotificationParams
+	client := mcp.NewClient(&mcp.Implementation{Name: "client"}, &mcp.ClientOptions{
+		ProgressNotificationHandler: func(_ context.Context, req *mcp.ProgressNotificationClientRequest) {
+			mu.Lock()
+			defer mu.Unlock()
+			notifications = append(notifications, req.Params)
+		},
+	})
+	serverTransport, clientTransport := mcp.NewInMemoryTransports()
+	serverSession, err := server.Connect(ctx, serverTransport, nil)
+	require.NoError(t, err)
+	defer func() { _ = serverSession.Close() }()
+	session, err := client.Connect(ctx, clientTransport, nil)
+	require.NoError(t, err)
+	defer func() { _ = session.Close() }()
+
+	// SetProgressToken loses the token of params without metadata.
+	params := &mcp.CallToolParams{Meta: mcp.Meta{"progressToken": "batch-1"}, Name: "analyze_documents", Arguments: map[string]any{
+		"modelId": "prebuilt-read",
+		"documents": []map[string]any{
+			{"documentUrl": "https://example.com/a.pdf"},
+			{"documentUrl": "https://example.com/b.pdf"},
+			{"documentContent": "%%%", "contentType": "application/pdf"},
+		},
+	}}
+	res, err := session.CallTool(ctx, params)
+	require.NoError(t, err)
+	require.False(t, res.IsError, "%v", res.Content)
+
+	require.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(notifications) == 3
+	}, time.Second, 10*time.Millisecond)
+	for i, n := range notifications {
+		assert.Equal(t, "batch-1", n.ProgressToken)
+		assert.Equal(t, float64(i+1), n.Progress)
+		assert.Equal(t, float64(3), n.Total)
+	}
+	assert.Equal(t, "3 of 3 documents analyzed", notifications[2].Message)
+}
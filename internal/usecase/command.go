@@ -0,0 +1,100 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/linzhengen/azure-document-intelligence-mcp/internal/domain/analysis"
+	"github.com/linzhengen/azure-document-intelligence-mcp/internal/logging"
+)
+
+// documentModels lists the supported models that extract typed documents,
+// and so can classify them.
+var documentModels = map[string]bool{
+	"prebuilt-invoice":  true,
+	"prebuilt-contract": true,
+}
+
+// Classification is a document found by a model, with its type.
+type Classification struct {
+	DocType    string  `json:"docType"`
+	Confidence float32 `json:"confidence"`
+	Pages      []int   `json:"pages"`
+}
+
+// DocumentAnalyzer analyzes documents for the command line, with the same
+// checks, budgets and processing as the analysis tools.
+type DocumentAnalyzer struct {
+	analyzer *analyzer
+}
+
+// NewDocumentAnalyzer returns an analyzer of documents given on the command line.
+func NewDocumentAnalyzer(analyzerRepo analysis.Repository, opts ...HandlerOption) *DocumentAnalyzer {
+	return &DocumentAnalyzer{analyzer: newAnalyzer(analyzerRepo, opts)}
+}
+
+// Analyze analyzes the document at the URL of params or, if content isn't
+// nil, content of the media type of params.
+func (d *DocumentAnalyzer) Analyze(ctx context.Context, params *AnalysisParams, content []byte) (*AnalysisOutput, error) {
+	a := d.analyzer
+	ctx = logging.NewContext(ctx, sessionLogger(a.logger, a.redactor, nil))
+	if err := a.checkModel(params.ModelID); err != nil {
+		return nil, err
+	}
+	var options analysis.AnalyzeDocumentOptions
+	if content == nil {
+		var err error
+		if options, err = a.documentOptions(ctx, params); err != nil {
+			return nil, err
+		}
+	} else {
+		if params.DocumentURL != "" || params.DocumentContent != "" {
+			return nil, errors.New("either a document URL or content must be provided, but not both")
+		}
+		if params.ContentType == "" {
+			return nil, errors.New("the content type of the document must be provided")
+		}
+		options = analysis.AnalyzeDocumentOptions{
+			Content:     content,
+			ContentType: params.ContentType,
+			Pages:       params.Pages,
+			Region:      params.Region,
+		}
+	}
+	output, _, err := a.analyze(ctx, nil, params, options)
+	return output, err
+}
+
+// Classify analyzes the document as Analyze does, and returns the documents
+// the model of params found in it, by type. The model must extract documents.
+func (d *DocumentAnalyzer) Classify(ctx context.Context, params *AnalysisParams, content []byte) ([]*Classification, error) {
+	if supportedModels[params.ModelID] && !documentModels[params.ModelID] {
+		return nil, fmt.Errorf("modelId %s doesn't extract documents: must be one of %s", params.ModelID, strings.Join(slices.Sorted(maps.Keys(documentModels)), ", "))
+	}
+	// Simplified outputs don't keep the extracted documents.
+	p := *params
+	p.Simplify = false
+	output, err := d.Analyze(ctx, &p, content)
+	if err != nil {
+		return nil, err
+	}
+	classifications := []*Classification{}
+	if output.AnalyzeResult == nil {
+		return classifications, nil
+	}
+	for _, doc := range output.AnalyzeResult.Documents {
+		c := &Classification{DocType: doc.DocType, Confidence: doc.Confidence, Pages: []int{}}
+		for _, region := range doc.BoundingRegions {
+			if page := int(region.PageNumber); !slices.Contains(c.Pages, page) {
+				c.Pages = append(c.Pages, page)
+			}
+		}
+		slices.Sort(c.Pages)
+		classifications = append(classifications, c)
+	}
+	return classifications, nil
+}
@@ -0,0 +1,71 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/linzhengen/azure-document-intelligence-mcp/internal/domain/analysis"
+)
+
+func TestDocumentAnalyzer_Analyze(t *testing.T) {
+	ctx := context.Background()
+	var received []analysis.AnalyzeDocumentOptions
+	mockRepo := &MockAnalysisRepository{
+		AnalyzeDocumentFunc: func(ctx context.Context, modelID string, options analysis.AnalyzeDocumentOptions) (*analysis.AnalyzeOperationResult, error) {
+			received = append(received, options)
+			return &analysis.AnalyzeOperationResult{Status: "succeeded", AnalyzeResult: &analysis.AnalyzeResult{ModelID: modelID, Content: "Contact jane@contoso.com"}}, nil
+		},
+	}
+	analyzer := NewDocumentAnalyzer(mockRepo, WithURLValidator(stubURLValidator{"https://169.254.169.254/": true}))
+
+	output, err := analyzer.Analyze(ctx, &AnalysisParams{ModelID: "prebuilt-read", ContentType: "application/pdf", Pages: "1-2", RedactPII: true}, []byte("%PDF-1.7"))
+	require.NoError(t, err)
+	assert.Equal(t, "Contact [EMAIL_1]", output.AnalyzeResult.Content)
+	_, err = analyzer.Analyze(ctx, &AnalysisParams{ModelID: "prebuilt-read", DocumentURL: "https://example.com/a.pdf"}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []analysis.AnalyzeDocumentOptions{
+		{Content: []byte("%PDF-1.7"), ContentType: "application/pdf", Pages: "1-2"},
+		{DocURL: "https://example.com/a.pdf"},
+	}, received)
+
+	for name, params := range map[string]*AnalysisParams{
+		"model":        {ModelID: "prebuilt-receipt", ContentType: "application/pdf"},
+		"content type": {ModelID: "prebuilt-read"},
+		"url":          {ModelID: "prebuilt-read", ContentType: "application/pdf", DocumentURL: "https://example.com/a.pdf"},
+	} {
+		_, err := analyzer.Analyze(ctx, params, []byte("%PDF-1.7"))
+		assert.Error(t, err, name)
+	}
+	_, err = analyzer.Analyze(ctx, &AnalysisParams{ModelID: "prebuilt-read", DocumentURL: "https://169.254.169.254/"}, nil)
+	assert.Error(t, err, "refused URLs aren't analyzed")
+	assert.Len(t, received, 2)
+}
+
+func TestDocumentAnalyzer_Classify(t *testing.T) {
+	ctx := context.Background()
+	mockRepo := &MockAnalysisRepository{
+		AnalyzeDocumentFunc: func(ctx context.Context, modelID string, options analysis.AnalyzeDocumentOptions) (*analysis.AnalyzeOperationResult, error) {
+			return &analysis.AnalyzeOperationResult{Status: "succeeded", AnalyzeResult: &analysis.AnalyzeResult{
+				ModelID: modelID,
+				Documents: []*analysis.Document{
+					{DocType: "invoice", Confidence: 0.9, BoundingRegions: []analysis.BoundingRegion{{PageNumber: 2}, {PageNumber: 1}, {PageNumber: 2}}},
+					{DocType: "invoice", Confidence: 0.6},
+				},
+			}}, nil
+		},
+	}
+	analyzer := NewDocumentAnalyzer(mockRepo)
+
+	classifications, err := analyzer.Classify(ctx, &AnalysisParams{ModelID: "prebuilt-invoice", ContentType: "application/pdf", Simplify: true}, []byte("%PDF-1.7"))
+	require.NoError(t, err)
+	assert.Equal(t, []*Classification{
+		{DocType: "invoice", Confidence: 0.9, Pages: []int{1, 2}},
+		{DocType: "invoice", Confidence: 0.6, Pages: []int{}},
+	}, classifications)
+
+	_, err = analyzer.Classify(ctx, &AnalysisParams{ModelID: "prebuilt-layout", ContentType: "application/pdf"}, []byte("%PDF-1.7"))
+	assert.ErrorContains(t, err, "doesn't extract documents")
+}
@@ -0,0 +1,216 @@
+package usecase
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+
+	"github.com/linzhengen/azure-document-intelligence-mcp/internal/domain/analysis"
+	"github.com/linzhengen/azure-document-intelligence-mcp/internal/domain/history"
+	"github.com/linzhengen/azure-document-intelligence-mcp/internal/logging"
+	"github.com/linzhengen/azure-document-intelligence-mcp/internal/redact"
+)
+
+// defaultHistoryLimit bounds the records returned by the history tools.
+const defaultHistoryLimit = 20
+
+// WithResultStore saves every completed analysis in store, after personal
+// data has been redacted, so that it can be recalled with the history tools.
+func WithResultStore(store history.Store) HandlerOption {
+	return func(o *handlerOptions) {
+		o.history = store
+	}
+}
+
+// saveRecord saves a completed analysis. Failures are logged rather than
+// returned: the analysis itself succeeded and has been paid for.
+func saveRecord(ctx context.Context, store history.Store, req *mcp.CallToolRequest, modelID string, options analysis.AnalyzeDocumentOptions, result *analysis.AnalyzeOperationResult) {
+	record := &history.Record{
+		Time:        time.Now().UTC(),
+		ModelID:     modelID,
+		Source:      storedSource(options.DocURL),
+		ContentType: options.ContentType,
+		Client:      clientName(req),
+		Session:     sessionID(req),
+	}
+	hash := sha256.Sum256(options.Content)
+	if options.DocURL != "" {
+		hash = sha256.Sum256([]byte(options.DocURL))
+	}
+	record.Hash = hex.EncodeToString(hash[:])
+	if result.AnalyzeResult != nil {
+		record.Pages = len(result.AnalyzeResult.Pages)
+	}
+	if err := store.Save(ctx, record, result); err != nil {
+		logging.FromContext(ctx).WarnContext(ctx, "failed to save analysis to history", "modelId", modelID, "error", err)
+	}
+}
+
+// storedSource returns a document URL as saved in history. Its query string,
+// which may hold credentials such as a SAS signature, is replaced by
+// redact.Placeholder and its user info and fragment are dropped: the index
+// is a plain file that outlives the token.
+func storedSource(docURL string) string {
+	if docURL == "" {
+		return ""
+	}
+	u, err := url.Parse(docURL)
+	if err != nil {
+		return redact.Placeholder
+	}
+	u.User = nil
+	u.Fragment, u.RawFragment, u.ForceQuery = "", "", false
+	if u.RawQuery != "" {
+		u.RawQuery = redact.Placeholder
+	}
+	return u.String()
+}
+
+// HistoryFilterParams select stored analyses.
+type HistoryFilterParams struct {
+	ModelID string `json:"modelId,omitempty" jsonschema:"Only analyses with this model ID."`
+	Client  string `json:"client,omitempty" jsonschema:"Only analyses requested by this MCP client."`
+	Since   string `json:"since,omitempty" jsonschema:"Only analyses completed on or after this UTC day, as YYYY-MM-DD."`
+	Limit   int    `json:"limit,omitempty" jsonschema:"Maximum number of analyses to return. Defaults to 20."`
+}
+
+// filter returns the filter selecting the analyses of params among those
+// requested in the session of req: like result resources, an analysis
+// requested over HTTP is only recalled by its session.
+func (p *HistoryFilterParams) filter(req *mcp.CallToolRequest) (history.Filter, error) {
+	f := history.Filter{ModelID: p.ModelID, Client: p.Client, Scoped: true, Session: sessionID(req), Limit: p.Limit}
+	if f.Limit <= 0 {
+		f.Limit = defaultHistoryLimit
+	}
+	if p.Since != "" {
+		since, err := time.Parse(time.DateOnly, p.Since)
+		if err != nil {
+			return history.Filter{}, fmt.Errorf("invalid since date %q: expected YYYY-MM-DD", p.Since)
+		}
+		f.Since = since
+	}
+	return f, nil
+}
+
+// AnalysisRecord describes a stored analysis.
+type AnalysisRecord struct {
+	ID          string `json:"id" jsonschema:"Identifier of the stored analysis."`
+	Time        string `json:"time" jsonschema:"When the analysis completed, in RFC 3339 format."`
+	ModelID     string `json:"modelId" jsonschema:"Model used for the analysis."`
+	Source      string `json:"source,omitempty" jsonschema:"URL of the analyzed document. Empty for uploaded content."`
+	ContentType string `json:"contentType,omitempty" jsonschema:"MIME type of the uploaded content."`
+	Hash        string `json:"hash" jsonschema:"SHA-256 of the document content, or of its URL when the service downloaded it."`
+	Client      string `json:"client,omitempty" jsonschema:"Name of the MCP client that requested the analysis."`
+	Session     string `json:"session,omitempty" jsonschema:"MCP session that requested the analysis."`
+	Pages       int    `json:"pages" jsonschema:"Number of pages analyzed."`
+}
+
+func newAnalysisRecord(r *history.Record) *AnalysisRecord {
+	return &AnalysisRecord{
+		ID:          r.ID,
+		Time:        r.Time.Format(time.RFC3339),
+		ModelID:     r.ModelID,
+		Source:      r.Source,
+		ContentType: r.ContentType,
+		Hash:        r.Hash,
+		Client:      r.Client,
+		Session:     r.Session,
+		Pages:       r.Pages,
+	}
+}
+
+// ListAnalysesOutput lists stored analyses.
+type ListAnalysesOutput struct {
+	Analyses []*AnalysisRecord `json:"analyses" jsonschema:"Stored analyses, newest first, without their results. Get a result with get_analysis."`
+}
+
+// NewListAnalysesHandler creates a tool handler listing the analyses in store.
+func NewListAnalysesHandler(store history.Store) func(context.Context, *mcp.CallToolRequest, *HistoryFilterParams) (*mcp.CallToolResult, *ListAnalysesOutput, error) {
+	return func(ctx context.Context, req *mcp.CallToolRequest, params *HistoryFilterParams) (*mcp.CallToolResult, *ListAnalysesOutput, error) {
+		filter, err := params.filter(req)
+		if err != nil {
+			return nil, nil, err
+		}
+		records, err := store.List(ctx, filter)
+		if err != nil {
+			return nil, nil, err
+		}
+		output := &ListAnalysesOutput{Analyses: []*AnalysisRecord{}}
+		for _, r := range records {
+			output.Analyses = append(output.Analyses, newAnalysisRecord(r))
+		}
+		return nil, output, nil
+	}
+}
+
+// GetAnalysisParams identifies a stored analysis.
+type GetAnalysisParams struct {
+	ID string `json:"id" jsonschema:"Identifier of the stored analysis, as returned by list_analyses or search_analyses."`
+}
+
+// GetAnalysisOutput is a stored analysis with its result.
+type GetAnalysisOutput struct {
+	*AnalysisRecord
+	Result *analysis.AnalyzeOperationResult `json:"result" jsonschema:"Status and result of the analysis."`
+}
+
+// NewGetAnalysisHandler creates a tool handler returning an analysis of store
+// with its result. Analyses of other sessions aren't found.
+func NewGetAnalysisHandler(store history.Store) func(context.Context, *mcp.CallToolRequest, *GetAnalysisParams) (*mcp.CallToolResult, *GetAnalysisOutput, error) {
+	return func(ctx context.Context, req *mcp.CallToolRequest, params *GetAnalysisParams) (*mcp.CallToolResult, *GetAnalysisOutput, error) {
+		if params.ID == "" {
+			return nil, nil, errors.New("id must be provided")
+		}
+		record, result, err := store.Get(ctx, params.ID)
+		if err != nil {
+			return nil, nil, err
+		}
+		if record.Session != sessionID(req) {
+			return nil, nil, fmt.Errorf("%w: %s", history.ErrNotFound, params.ID)
+		}
+		return nil, &GetAnalysisOutput{AnalysisRecord: newAnalysisRecord(record), Result: result}, nil
+	}
+}
+
+// SearchAnalysesParams defines the parameters of the search tool.
+type SearchAnalysesParams struct {
+	Query string `json:"query" jsonschema:"Words to find in the extracted content and field values. Every word must occur; case is ignored."`
+	HistoryFilterParams
+}
+
+// SearchAnalysesOutput lists the analyses matching a search.
+type SearchAnalysesOutput struct {
+	Hits []*SearchHit `json:"hits" jsonschema:"Matching analyses, best first, without their results. Get a result with get_analysis."`
+}
+
+// SearchHit is an analysis matching a search.
+type SearchHit struct {
+	Analysis *AnalysisRecord `json:"analysis" jsonschema:"The matching analysis."`
+	Score    int             `json:"score" jsonschema:"Number of occurrences of the query words."`
+	Snippets []string        `json:"snippets" jsonschema:"Field values, or excerpts of the content, where the query words occur."`
+}
+
+// NewSearchAnalysesHandler creates a tool handler searching the analyses in store.
+func NewSearchAnalysesHandler(store history.Store) func(context.Context, *mcp.CallToolRequest, *SearchAnalysesParams) (*mcp.CallToolResult, *SearchAnalysesOutput, error) {
+	return func(ctx context.Context, req *mcp.CallToolRequest, params *SearchAnalysesParams) (*mcp.CallToolResult, *SearchAnalysesOutput, error) {
+		filter, err := params.filter(req)
+		if err != nil {
+			return nil, nil, err
+		}
+		hits, err := store.Search(ctx, params.Query, filter)
+		if err != nil {
+			return nil, nil, err
+		}
+		output := &SearchAnalysesOutput{Hits: []*SearchHit{}}
+		for _, h := range hits {
+			output.Hits = append(output.Hits, &SearchHit{Analysis: newAnalysisRecord(h.Record), Score: h.Score, Snippets: h.Snippets})
+		}
+		return nil, output, nil
+	}
+}
@@ -0,0 +1,29 @@
+package usecase
+
+import (
+	"log/slog"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+
+	"github.com/linzhengen/azure-document-intelligence-mcp/internal/logging"
+	"github.com/linzhengen/azure-document-intelligence-mcp/internal/redact"
+)
+
+// loggerName is the logger reported in MCP log messages.
+const loggerName = "azure-document-intelligence-mcp"
+
+// sessionLogger returns a logger that writes to base and forwards records,
+// redacted by redactor, to the session of req. The session only sends records
+// at or above the level set by its client, and nothing if the client never set one.
+func sessionLogger(base *slog.Logger, redactor *redact.Redactor, req *mcp.CallToolRequest) *slog.Logger {
+	if base == nil {
+		base = slog.Default()
+	}
+	if req == nil || req.Session == nil {
+		return base
+	}
+	return slog.New(logging.Fanout(
+		base.Handler(),
+		redactor.Handler(mcp.NewLoggingHandler(req.Session, &mcp.LoggingHandlerOptions{LoggerName: loggerName})),
+	))
+}
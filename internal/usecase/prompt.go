@@ -0,0 +1,122 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// documentPrompt describes a prompt that guides the model through analyzing documents.
+type documentPrompt struct {
+	prompt *mcp.Prompt
+	render func(args map[string]string) string
+}
+
+var documentArgument = &mcp.PromptArgument{
+	Name:        "document",
+	Description: "URL or local file path of the document.",
+	Required:    true,
+}
+
+var documentPrompts = []documentPrompt{
+	{
+		prompt: &mcp.Prompt{
+			Name:        "summarize_document",
+			Title:       "Summarize document",
+			Description: "Summarizes a document after analyzing its layout.",
+			Arguments:   []*mcp.PromptArgument{documentArgument},
+		},
+		render: func(args map[string]string) string {
+			return analyzeInstruction(args["document"], "prebuilt-layout") +
+				"\n\nThen write a concise summary of the document based on the returned content. " +
+				"Start with one sentence describing what kind of document it is, followed by its key points as a bulleted list. " +
+				"Use the section headings and tables in the content to structure the summary."
+		},
+	},
+	{
+		prompt: &mcp.Prompt{
+			Name:        "extract_invoice_fields",
+			Title:       "Extract invoice fields",
+			Description: "Extracts vendor, customer, dates, totals and line items from an invoice.",
+			Arguments:   []*mcp.PromptArgument{documentArgument},
+		},
+		render: func(args map[string]string) string {
+			return analyzeInstruction(args["document"], "prebuilt-invoice") +
+				"\n\nThen report the invoice fields from the first entry of analyzeResult.documents: " +
+				"VendorName, CustomerName, InvoiceId, InvoiceDate, DueDate, SubTotal, TotalTax, InvoiceTotal and the Items line items " +
+				"(Description, Quantity, UnitPrice, Amount). Use the typed value of each field (for example valueCurrency or valueDate) " +
+				"rather than its raw content, and mention any field that is missing or has a confidence below 0.8."
+		},
+	},
+	{
+		prompt: &mcp.Prompt{
+			Name:        "compare_contracts",
+			Title:       "Compare contracts",
+			Description: "Compares the parties, terms and clauses of two contracts.",
+			Arguments: []*mcp.PromptArgument{
+				{Name: "document", Description: "URL or local file path of the first contract.", Required: true},
+				{Name: "otherDocument", Description: "URL or local file path of the second contract.", Required: true},
+			},
+		},
+		render: func(args map[string]string) string {
+			return analyzeInstruction(args["document"], "prebuilt-contract") + "\n\n" +
+				analyzeInstruction(args["otherDocument"], "prebuilt-contract") +
+				"\n\nThen compare the two contracts using the fields of analyzeResult.documents and the extracted content: " +
+				"parties, execution and expiration dates, renewal and termination terms, jurisdictions and any clause that appears in only one of them. " +
+				"Present the comparison as a table with one row per aspect, followed by a list of the most important differences."
+		},
+	},
+	{
+		prompt: &mcp.Prompt{
+			Name:        "answer_from_document",
+			Title:       "Answer from document",
+			Description: "Answers a question using only the content of a document.",
+			Arguments: []*mcp.PromptArgument{
+				documentArgument,
+				{Name: "question", Description: "The question to answer.", Required: true},
+			},
+		},
+		render: func(args map[string]string) string {
+			return analyzeInstruction(args["document"], "prebuilt-layout") +
+				"\n\nThen answer the following question using only the returned content:\n\n" + args["question"] +
+				"\n\nQuote the passages the answer is based on and name the page they appear on. " +
+				"If the document does not contain the answer, say so instead of guessing."
+		},
+	},
+}
+
+// analyzeInstruction tells the model how to call analyze_document for the given document.
+func analyzeInstruction(document, modelID string) string {
+	if strings.HasPrefix(document, "http://") || strings.HasPrefix(document, "https://") {
+		return fmt.Sprintf("Call the analyze_document tool with modelId %q and documentUrl %q.", modelID, document)
+	}
+	return fmt.Sprintf("Read the local file %q, encode its bytes as base64 and call the analyze_document tool with modelId %q, "+
+		"the encoded bytes as documentContent and the file's MIME type (for example application/pdf or image/png) as contentType.", document, modelID)
+}
+
+// RegisterPrompts adds the document workflow prompts to the server.
+func RegisterPrompts(server *mcp.Server) {
+	for _, p := range documentPrompts {
+		server.AddPrompt(p.prompt, newPromptHandler(p))
+	}
+}
+
+func newPromptHandler(p documentPrompt) mcp.PromptHandler {
+	return func(_ context.Context, req *mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+		args := req.Params.Arguments
+		for _, arg := range p.prompt.Arguments {
+			if arg.Required && strings.TrimSpace(args[arg.Name]) == "" {
+				return nil, fmt.Errorf("missing required argument: %s", arg.Name)
+			}
+		}
+		return &mcp.GetPromptResult{
+			Description: p.prompt.Description,
+			Messages: []*mcp.PromptMessage{{
+				Role:    "user",
+				Content: &mcp.TextContent{Text: p.render(args)},
+			}},
+		}, nil
+	}
+}
@@ -0,0 +1,81 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func connectPromptClient(t *testing.T) *mcp.ClientSession {
+	t.Helper()
+	ctx := context.Background()
+	server := mcp.NewServer(&mcp.Implementation{Name: "test"}, nil)
+	RegisterPrompts(server)
+
+	serverTransport, clientTransport := mcp.NewInMemoryTransports()
+	serverSession, err := server.Connect(ctx, serverTransport, nil)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = serverSession.Close() })
+
+	client := mcp.NewClient(&mcp.Implementation{Name: "client"}, nil)
+	session, err := client.Connect(ctx, clientTransport, nil)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = session.Close() })
+	return session
+}
+
+func TestPrompts_List(t *testing.T) {
+	session := connectPromptClient(t)
+
+	res, err := session.ListPrompts(context.Background(), nil)
+
+	require.NoError(t, err)
+	var names []string
+	for _, p := range res.Prompts {
+		names = append(names, p.Name)
+	}
+	assert.ElementsMatch(t, []string{"summarize_document", "extract_invoice_fields", "compare_contracts", "answer_from_document"}, names)
+}
+
+func TestPrompts_GetWithURL(t *testing.T) {
+	session := connectPromptClient(t)
+
+	res, err := session.GetPrompt(context.Background(), &mcp.GetPromptParams{
+		Name:      "extract_invoice_fields",
+		Arguments: map[string]string{"document": "https://example.com/invoice.pdf"},
+	})
+
+	require.NoError(t, err)
+	require.Len(t, res.Messages, 1)
+	text := res.Messages[0].Content.(*mcp.TextContent).Text
+	assert.Contains(t, text, `modelId "prebuilt-invoice" and documentUrl "https://example.com/invoice.pdf"`)
+}
+
+func TestPrompts_GetWithLocalPath(t *testing.T) {
+	session := connectPromptClient(t)
+
+	res, err := session.GetPrompt(context.Background(), &mcp.GetPromptParams{
+		Name:      "compare_contracts",
+		Arguments: map[string]string{"document": "/tmp/a.pdf", "otherDocument": "https://example.com/b.pdf"},
+	})
+
+	require.NoError(t, err)
+	text := res.Messages[0].Content.(*mcp.TextContent).Text
+	assert.Contains(t, text, `Read the local file "/tmp/a.pdf"`)
+	assert.Contains(t, text, `documentUrl "https://example.com/b.pdf"`)
+}
+
+func TestPrompts_MissingArgument(t *testing.T) {
+	session := connectPromptClient(t)
+
+	_, err := session.GetPrompt(context.Background(), &mcp.GetPromptParams{
+		Name:      "answer_from_document",
+		Arguments: map[string]string{"document": "https://example.com/doc.pdf"},
+	})
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "missing required argument: question")
+}
@@ -0,0 +1,77 @@
+package usecase
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+
+	"github.com/linzhengen/azure-document-intelligence-mcp/internal/redact"
+)
+
+// WithRedactor redacts the log records forwarded to clients with redactor.
+// Tool results and errors are redacted by RedactionMiddleware.
+func WithRedactor(redactor *redact.Redactor) HandlerOption {
+	return func(o *handlerOptions) {
+		o.redactor = redactor
+	}
+}
+
+// RedactionMiddleware removes secrets from the results and errors of tool
+// calls, so that response bodies, signed URLs and keys quoted in them never
+// reach the client. Errors are redacted as server messages; other results
+// hold document content, which is redacted with the narrower rules of
+// redact.Redactor.Content so that ordinary text comes through unchanged.
+func RedactionMiddleware(redactor *redact.Redactor) mcp.Middleware {
+	return func(next mcp.MethodHandler) mcp.MethodHandler {
+		return func(ctx context.Context, method string, req mcp.Request) (mcp.Result, error) {
+			result, err := next(ctx, method, req)
+			if err != nil {
+				return result, redactor.Error(err)
+			}
+			if res, ok := result.(*mcp.CallToolResult); ok && res != nil {
+				return redactToolResult(redactor, res), nil
+			}
+			return result, nil
+		}
+	}
+}
+
+// redactToolResult returns res, or a copy of it with its secrets redacted.
+func redactToolResult(redactor *redact.Redactor, res *mcp.CallToolResult) *mcp.CallToolResult {
+	redactText := redactor.Content
+	if res.IsError {
+		redactText = redactor.String
+	}
+	redacted := *res
+	redacted.Content = make([]mcp.Content, len(res.Content))
+	for i, c := range res.Content {
+		if text, ok := c.(*mcp.TextContent); ok {
+			t := *text
+			t.Text = redactText(text.Text)
+			c = &t
+		}
+		redacted.Content[i] = c
+	}
+	if res.StructuredContent != nil {
+		redacted.StructuredContent = redactStructured(redactor, res.StructuredContent)
+	}
+	return &redacted
+}
+
+// redactStructured redacts the strings of structured content. It only decodes
+// the content if its JSON encoding holds a secret.
+func redactStructured(redactor *redact.Redactor, content any) any {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false) // keep "&" so that URL parameters match
+	if err := enc.Encode(content); err != nil || redactor.Content(buf.String()) == buf.String() {
+		return content
+	}
+	var decoded any
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		return content
+	}
+	return redactor.Value(decoded)
+}
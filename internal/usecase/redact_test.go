@@ -0,0 +1,174 @@
+package usecase
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/linzhengen/azure-document-intelligence-mcp/internal/domain/analysis"
+	analysisinfra "github.com/linzhengen/azure-document-intelligence-mcp/internal/infrastructure/analysis"
+	"github.com/linzhengen/azure-document-intelligence-mcp/internal/logging"
+	"github.com/linzhengen/azure-document-intelligence-mcp/internal/redact"
+)
+
+const (
+	testAPIKey    = "0123456789abcdef0123456789abcdef"
+	sasURL        = "https://files.blob.core.windows.net/docs/a.pdf?sv=2022-11-02&sig=c2VjcmV0LXNpZ25hdHVyZQ%3D%3D"
+	sasSignature  = "c2VjcmV0LXNpZ25hdHVyZQ"
+	operationPath = "/documentintelligence/documentModels/prebuilt-read/analyzeResults/1"
+)
+
+// doFunc is an analysis HTTPClient scripted by a function.
+type doFunc func(req *http.Request) (*http.Response, error)
+
+func (f doFunc) Do(req *http.Request) (*http.Response, error) { return f(req) }
+
+func jsonResponse(status int, body string, header http.Header) *http.Response {
+	if header == nil {
+		header = http.Header{}
+	}
+	return &http.Response{StatusCode: status, Header: header, Body: io.NopCloser(strings.NewReader(body))}
+}
+
+// TestRedaction_RepositoryPaths runs the analysis tool over the HTTP
+// repository for every way it can fail or succeed with a secret in its
+// output, and checks that neither the tool result nor the logs reveal it.
+func TestRedaction_RepositoryPaths(t *testing.T) {
+	accepted := jsonResponse(http.StatusAccepted, "", http.Header{"Operation-Location": {"https://svc.test" + operationPath}})
+	tests := []struct {
+		name string
+		do   doFunc
+	}{
+		{
+			name: "initiate rejected with the document URL in the body",
+			do: func(req *http.Request) (*http.Response, error) {
+				return jsonResponse(http.StatusBadRequest, `{"error":{"code":"InvalidContent","message":"Could not download `+sasURL+`"}}`, nil), nil
+			},
+		},
+		{
+			name: "initiate rejected with the key in the body",
+			do: func(req *http.Request) (*http.Response, error) {
+				return jsonResponse(http.StatusUnauthorized, `{"error":{"message":"Key `+req.Header.Get("Ocp-Apim-Subscription-Key")+` is invalid"}}`, nil), nil
+			},
+		},
+		{
+			name: "initiate transport error",
+			do: func(req *http.Request) (*http.Response, error) {
+				return nil, errors.New("proxy refused request with header Ocp-Apim-Subscription-Key: " + testAPIKey)
+			},
+		},
+		{
+			name: "polling transport error",
+			do: func(req *http.Request) (*http.Response, error) {
+				if req.Method == http.MethodPost {
+					return accepted, nil
+				}
+				return nil, errors.New("Get " + sasURL + ": connection reset")
+			},
+		},
+		{
+			name: "succeeded with the document URL in the content",
+			do: func(req *http.Request) (*http.Response, error) {
+				if req.Method == http.MethodPost {
+					return accepted, nil
+				}
+				result, _ := json.Marshal(&analysis.AnalyzeOperationResult{
+					Status:        "succeeded",
+					AnalyzeResult: &analysis.AnalyzeResult{ModelID: "prebuilt-read", Content: "Download the file from " + sasURL},
+				})
+				return jsonResponse(http.StatusOK, string(result), nil), nil
+			},
+		},
+	}
+
+	redactor, err := redact.New([]string{testAPIKey}, nil)
+	require.NoError(t, err)
+	outputSchema, err := AnalysisOutputSchema()
+	require.NoError(t, err)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			var logs bytes.Buffer
+			logger := slog.New(redactor.Handler(slog.NewJSONHandler(&logs, nil)))
+			repo := analysisinfra.NewRepositoryWithClient("https://svc.test", testAPIKey, tt.do)
+
+			server := mcp.NewServer(&mcp.Implementation{Name: "test"}, nil)
+			server.AddReceivingMiddleware(RedactionMiddleware(redactor))
+			mcp.AddTool(server, &mcp.Tool{Name: "analyze_document", OutputSchema: outputSchema},
+				NewAnalysisHandler(repo, WithLogger(logger), WithRedactor(redactor)))
+			serverTransport, clientTransport := mcp.NewInMemoryTransports()
+			serverSession, err := server.Connect(ctx, serverTransport, nil)
+			require.NoError(t, err)
+			defer func() { _ = serverSession.Close() }()
+			session, err := mcp.NewClient(&mcp.Implementation{Name: "client"}, nil).Connect(ctx, clientTransport, nil)
+			require.NoError(t, err)
+			defer func() { _ = session.Close() }()
+
+			res, err := session.CallTool(ctx, &mcp.CallToolParams{
+				Name:      "analyze_document",
+				Arguments: map[string]any{"modelId": "prebuilt-read", "documentUrl": sasURL},
+			})
+			require.NoError(t, err)
+
+			output, err := json.Marshal(res)
+			require.NoError(t, err)
+			for _, leaked := range []string{testAPIKey, sasSignature} {
+				assert.NotContains(t, string(output), leaked)
+				assert.NotContains(t, logs.String(), leaked)
+			}
+			assert.Contains(t, string(output), redact.Placeholder)
+			assert.Contains(t, logs.String(), "document analysis", "the call is logged")
+		})
+	}
+}
+
+func TestRedactionMiddleware_LeavesCleanResults(t *testing.T) {
+	redactor, err := redact.New(nil, nil)
+	require.NoError(t, err)
+	structured := &AnalysisOutput{AnalyzeOperationResult: &analysis.AnalyzeOperationResult{Status: "succeeded"}}
+	handler := RedactionMiddleware(redactor)(func(context.Context, string, mcp.Request) (mcp.Result, error) {
+		return &mcp.CallToolResult{StructuredContent: structured}, nil
+	})
+
+	result, err := handler(logging.NewContext(context.Background(), slog.Default()), "tools/call", nil)
+	require.NoError(t, err)
+	assert.Same(t, structured, result.(*mcp.CallToolResult).StructuredContent)
+}
+
+func TestRedactionMiddleware_KeepsDocumentText(t *testing.T) {
+	redactor, err := redact.New([]string{testAPIKey}, nil)
+	require.NoError(t, err)
+	const text = "Basic salary: 5000\nBearer bonds\nPrior Authorization: 88231\nPassword: ______\nEnter ?code=42"
+	structured := &AnalysisOutput{AnalyzeOperationResult: &analysis.AnalyzeOperationResult{
+		Status:        "succeeded",
+		AnalyzeResult: &analysis.AnalyzeResult{ModelID: "prebuilt-read", Content: text},
+	}}
+	encoded, err := json.Marshal(structured)
+	require.NoError(t, err)
+	result := &mcp.CallToolResult{Content: []mcp.Content{&mcp.TextContent{Text: string(encoded)}}, StructuredContent: structured}
+	handler := RedactionMiddleware(redactor)(func(context.Context, string, mcp.Request) (mcp.Result, error) {
+		return result, nil
+	})
+
+	res, err := handler(context.Background(), "tools/call", nil)
+	require.NoError(t, err)
+	redacted := res.(*mcp.CallToolResult)
+	assert.Same(t, structured, redacted.StructuredContent, "document content isn't redacted")
+	assert.Equal(t, string(encoded), redacted.Content[0].(*mcp.TextContent).Text)
+
+	result = &mcp.CallToolResult{IsError: true, Content: []mcp.Content{&mcp.TextContent{Text: "upstream replied: Authorization: Bearer abc.def"}}}
+	res, err = handler(context.Background(), "tools/call", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "upstream replied: Authorization: Bearer [REDACTED]", res.(*mcp.CallToolResult).Content[0].(*mcp.TextContent).Text, "errors are redacted as server messages")
+}
@@ -0,0 +1,210 @@
+package usecase
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+
+	"github.com/linzhengen/azure-document-intelligence-mcp/internal/domain/analysis"
+)
+
+const resultURIPrefix = "docintel://results/"
+
+// ResultResources exposes completed analyses as MCP resources so that clients
+// can attach a result to a conversation without running the analysis again.
+//
+// Each result is available as:
+//   - docintel://results/{id}              the full result as JSON
+//   - docintel://results/{id}/content.md   the extracted content
+//   - docintel://results/{id}/tables/{n}.csv  table n (0-based) as CSV
+//   - docintel://results/{id}/pages/{n}.json  the page with pageNumber n as JSON
+//
+// Results are scoped to the session that published them. Sessions are only
+// identified over HTTP, where the server is shared by several clients: there,
+// results aren't listed, since the list is the same for every session, and
+// are only read by their session, through the link of the tool result.
+type ResultResources struct {
+	server *mcp.Server
+	limit  int
+
+	mu      sync.RWMutex
+	results map[string]*publishedResult
+	order   []string
+}
+
+// publishedResult is a result with the session that published it.
+type publishedResult struct {
+	*analysis.AnalyzeOperationResult
+	session string
+}
+
+// NewResultResources registers the result resource templates on server.
+// At most limit results are kept; older ones are removed as new ones arrive.
+func NewResultResources(server *mcp.Server, limit int) *ResultResources {
+	r := &ResultResources{
+		server:  server,
+		limit:   limit,
+		results: make(map[string]*publishedResult),
+	}
+	server.AddResourceTemplate(&mcp.ResourceTemplate{
+		Name:        "analysis-result",
+		Description: "The full result of a completed document analysis.",
+		URITemplate: resultURIPrefix + "{id}",
+		MIMEType:    "application/json",
+	}, r.ReadResource)
+	server.AddResourceTemplate(&mcp.ResourceTemplate{
+		Name:        "analysis-content",
+		Description: "The content extracted by a completed document analysis.",
+		URITemplate: resultURIPrefix + "{id}/content.md",
+		MIMEType:    "text/markdown",
+	}, r.ReadResource)
+	server.AddResourceTemplate(&mcp.ResourceTemplate{
+		Name:        "analysis-table",
+		Description: "A table extracted by a completed document analysis, indexed from 0.",
+		URITemplate: resultURIPrefix + "{id}/tables/{n}.csv",
+		MIMEType:    "text/csv",
+	}, r.ReadResource)
+	server.AddResourceTemplate(&mcp.ResourceTemplate{
+		Name:        "analysis-page",
+		Description: "A page of a completed document analysis, by page number.",
+		URITemplate: resultURIPrefix + "{id}/pages/{n}.json",
+		MIMEType:    "application/json",
+	}, r.ReadResource)
+	return r
+}
+
+// Publish stores the result of session and registers it as a resource. Over
+// stdio, the resource is listed, which notifies the client that the resource
+// list has changed. It returns the resource URI.
+func (r *ResultResources) Publish(session string, result *analysis.AnalyzeOperationResult) string {
+	id := strings.ToLower(rand.Text())
+	uri := resultURIPrefix + id
+
+	r.mu.Lock()
+	r.results[id] = &publishedResult{AnalyzeOperationResult: result, session: session}
+	r.order = append(r.order, id)
+	var evicted []string
+	for r.limit > 0 && len(r.order) > r.limit {
+		if r.results[r.order[0]].session == "" {
+			evicted = append(evicted, resultURIPrefix+r.order[0])
+		}
+		delete(r.results, r.order[0])
+		r.order = r.order[1:]
+	}
+	r.mu.Unlock()
+
+	if len(evicted) > 0 {
+		r.server.RemoveResources(evicted...)
+	}
+	if session != "" {
+		return uri
+	}
+	name := "analysis " + id
+	if result.AnalyzeResult != nil {
+		name = fmt.Sprintf("%s analysis %s", result.AnalyzeResult.ModelID, id)
+	}
+	r.server.AddResource(&mcp.Resource{
+		Name:     name,
+		URI:      uri,
+		MIMEType: "application/json",
+	}, r.ReadResource)
+	return uri
+}
+
+// ReadResource serves a result or one of its sub-resources.
+func (r *ResultResources) ReadResource(_ context.Context, req *mcp.ReadResourceRequest) (*mcp.ReadResourceResult, error) {
+	uri := req.Params.URI
+	path, ok := strings.CutPrefix(uri, resultURIPrefix)
+	if !ok {
+		return nil, mcp.ResourceNotFoundError(uri)
+	}
+	parts := strings.Split(path, "/")
+
+	r.mu.RLock()
+	published, ok := r.results[parts[0]]
+	r.mu.RUnlock()
+	// Results of other sessions don't exist for this one.
+	if !ok || published.session != readerSession(req) {
+		return nil, mcp.ResourceNotFoundError(uri)
+	}
+	result := published.AnalyzeOperationResult
+
+	var contents *mcp.ResourceContents
+	var err error
+	switch {
+	case len(parts) == 1:
+		contents, err = jsonContents(result)
+	case len(parts) == 2 && parts[1] == "content.md":
+		contents = markdownContents(result)
+	case len(parts) == 3 && parts[1] == "tables" && strings.HasSuffix(parts[2], ".csv"):
+		contents, err = tableContents(result, strings.TrimSuffix(parts[2], ".csv"))
+	case len(parts) == 3 && parts[1] == "pages" && strings.HasSuffix(parts[2], ".json"):
+		contents, err = pageContents(result, strings.TrimSuffix(parts[2], ".json"))
+	}
+	if err != nil {
+		return nil, err
+	}
+	if contents == nil {
+		return nil, mcp.ResourceNotFoundError(uri)
+	}
+	contents.URI = uri
+	return &mcp.ReadResourceResult{Contents: []*mcp.ResourceContents{contents}}, nil
+}
+
+// readerSession returns the ID of the session of req, which is empty over stdio.
+func readerSession(req *mcp.ReadResourceRequest) string {
+	if req.Session == nil {
+		return ""
+	}
+	return req.Session.ID()
+}
+
+func jsonContents(v any) (*mcp.ResourceContents, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal resource: %w", err)
+	}
+	return &mcp.ResourceContents{MIMEType: "application/json", Text: string(data)}, nil
+}
+
+func markdownContents(result *analysis.AnalyzeOperationResult) *mcp.ResourceContents {
+	var text string
+	if result.AnalyzeResult != nil {
+		text = result.AnalyzeResult.Content
+	}
+	return &mcp.ResourceContents{MIMEType: "text/markdown", Text: text}
+}
+
+func tableContents(result *analysis.AnalyzeOperationResult, index string) (*mcp.ResourceContents, error) {
+	n, err := strconv.Atoi(index)
+	if err != nil || result.AnalyzeResult == nil || n < 0 || n >= len(result.AnalyzeResult.Tables) {
+		return nil, nil
+	}
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	if err := w.WriteAll(result.AnalyzeResult.Tables[n].Grid()); err != nil {
+		return nil, fmt.Errorf("failed to write table: %w", err)
+	}
+	return &mcp.ResourceContents{MIMEType: "text/csv", Text: buf.String()}, nil
+}
+
+func pageContents(result *analysis.AnalyzeOperationResult, number string) (*mcp.ResourceContents, error) {
+	n, err := strconv.Atoi(number)
+	if err != nil || result.AnalyzeResult == nil {
+		return nil, nil
+	}
+	for i := range result.AnalyzeResult.Pages {
+		if int(result.AnalyzeResult.Pages[i].PageNumber) == n {
+			return jsonContents(&result.AnalyzeResult.Pages[i])
+		}
+	}
+	return nil, nil
+}
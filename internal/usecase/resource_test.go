@@ -0,0 +1,172 @@
+package usecase
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/linzhengen/azure-document-intelligence-mcp/internal/domain/analysis"
+)
+
+func newTestResult() *analysis.AnalyzeOperationResult {
+	return &analysis.AnalyzeOperationResult{
+		Status: "succeeded",
+		AnalyzeResult: &analysis.AnalyzeResult{
+			ModelID: "prebuilt-layout",
+			Content: "# Invoice\nTotal: 10",
+			Pages:   []analysis.Page{{PageNumber: 1}},
+			Tables: []*analysis.Table{{
+				RowCount:    2,
+				ColumnCount: 2,
+				Cells: []analysis.Cell{
+					{RowIndex: 0, ColumnIndex: 0, Content: "Item"},
+					{RowIndex: 0, ColumnIndex: 1, Content: "Price"},
+					{RowIndex: 1, ColumnIndex: 0, Content: "Pen, blue"},
+					{RowIndex: 1, ColumnIndex: 1, Content: "10"},
+				},
+			}},
+		},
+	}
+}
+
+func readResource(t *testing.T, r *ResultResources, uri string) (*mcp.ReadResourceResult, error) {
+	t.Helper()
+	return r.ReadResource(context.Background(), &mcp.ReadResourceRequest{Params: &mcp.ReadResourceParams{URI: uri}})
+}
+
+func TestResultResources_ReadSubResources(t *testing.T) {
+	server := mcp.NewServer(&mcp.Implementation{Name: "test"}, nil)
+	resources := NewResultResources(server, 10)
+	uri := resources.Publish("", newTestResult())
+	require.True(t, strings.HasPrefix(uri, "docintel://results/"))
+
+	res, err := readResource(t, resources, uri)
+	require.NoError(t, err)
+	assert.Equal(t, "application/json", res.Contents[0].MIMEType)
+	assert.Contains(t, res.Contents[0].Text, `"status":"succeeded"`)
+
+	res, err = readResource(t, resources, uri+"/content.md")
+	require.NoError(t, err)
+	assert.Equal(t, "# Invoice\nTotal: 10", res.Contents[0].Text)
+
+	res, err = readResource(t, resources, uri+"/tables/0.csv")
+	require.NoError(t, err)
+	assert.Equal(t, "Item,Price\n\"Pen, blue\",10\n", res.Contents[0].Text)
+
+	res, err = readResource(t, resources, uri+"/pages/1.json")
+	require.NoError(t, err)
+	assert.Contains(t, res.Contents[0].Text, `"pageNumber":1`)
+}
+
+func TestResultResources_NotFound(t *testing.T) {
+	server := mcp.NewServer(&mcp.Implementation{Name: "test"}, nil)
+	resources := NewResultResources(server, 10)
+	uri := resources.Publish("", newTestResult())
+
+	for _, u := range []string{
+		"docintel://results/unknown",
+		uri + "/tables/1.csv",
+		uri + "/pages/2.json",
+		uri + "/other",
+	} {
+		_, err := readResource(t, resources, u)
+		assert.Error(t, err, u)
+	}
+}
+
+func TestResultResources_EvictsOldest(t *testing.T) {
+	server := mcp.NewServer(&mcp.Implementation{Name: "test"}, nil)
+	resources := NewResultResources(server, 1)
+	first := resources.Publish("", newTestResult())
+	second := resources.Publish("", newTestResult())
+
+	_, err := readResource(t, resources, first)
+	assert.Error(t, err)
+	_, err = readResource(t, resources, second)
+	assert.NoError(t, err)
+}
+
+func TestResultResources_NotifiesListChanged(t *testing.T) {
+	ctx := context.Background()
+	server := mcp.NewServer(&mcp.Implementation{Name: "test"}, nil)
+	resources := NewResultResources(server, 10)
+
+	changed := make(chan struct{}, 1)
+	client := mcp.NewClient(&mcp.Implementation{Name: "client"}, &mcp.ClientOptions{
+		ResourceListChangedHandler: func(context.Context, *mcp.ResourceListChangedRequest) {
+			changed <- struct{}{}
+		},
+	})
+	serverTransport, clientTransport := mcp.NewInMemoryTransports()
+	serverSession, err := server.Connect(ctx, serverTransport, nil)
+	require.NoError(t, err)
+	defer func() { _ = serverSession.Close() }()
+	session, err := client.Connect(ctx, clientTransport, nil)
+	require.NoError(t, err)
+	defer func() { _ = session.Close() }()
+
+	uri := resources.Publish("", newTestResult())
+
+	select {
+	case <-changed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("resources/list_changed notification not received")
+	}
+
+	list, err := session.ListResources(ctx, nil)
+	require.NoError(t, err)
+	require.Len(t, list.Resources, 1)
+	assert.Equal(t, uri, list.Resources[0].URI)
+
+	read, err := session.ReadResource(ctx, &mcp.ReadResourceParams{URI: uri + "/tables/0.csv"})
+	require.NoError(t, err)
+	assert.Equal(t, "text/csv", read.Contents[0].MIMEType)
+}
+
+func TestResultResources_ScopedToSession(t *testing.T) {
+	ctx := context.Background()
+	server := mcp.NewServer(&mcp.Implementation{Name: "test"}, nil)
+	resources := NewResultResources(server, 10)
+	outputSchema, err := AnalysisOutputSchema()
+	require.NoError(t, err)
+	mcp.AddTool(server, &mcp.Tool{Name: "analyze_document", OutputSchema: outputSchema}, NewAnalysisHandler(&MockAnalysisRepository{}, WithResultPublisher(resources)))
+	httpServer := httptest.NewServer(mcp.NewStreamableHTTPHandler(func(*http.Request) *mcp.Server { return server }, nil))
+	// Registered first, so that it runs once the sessions are closed.
+	t.Cleanup(func() {
+		httpServer.CloseClientConnections()
+		httpServer.Close()
+	})
+
+	connect := func() *mcp.ClientSession {
+		client := mcp.NewClient(&mcp.Implementation{Name: "client"}, nil)
+		session, err := client.Connect(ctx, &mcp.StreamableClientTransport{Endpoint: httpServer.URL}, nil)
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = session.Close() })
+		return session
+	}
+	owner, other := connect(), connect()
+
+	result, err := owner.CallTool(ctx, &mcp.CallToolParams{Name: "analyze_document", Arguments: map[string]any{"modelId": "prebuilt-read", "documentUrl": "https://example.com/a.pdf"}})
+	require.NoError(t, err)
+	require.False(t, result.IsError)
+	require.Len(t, result.Content, 2)
+	link, ok := result.Content[1].(*mcp.ResourceLink)
+	require.True(t, ok, "the result links its resource")
+
+	_, err = owner.ReadResource(ctx, &mcp.ReadResourceParams{URI: link.URI})
+	assert.NoError(t, err)
+	_, err = other.ReadResource(ctx, &mcp.ReadResourceParams{URI: link.URI})
+	assert.Error(t, err, "other sessions can't read the result")
+	for _, session := range []*mcp.ClientSession{owner, other} {
+		list, err := session.ListResources(ctx, nil)
+		require.NoError(t, err)
+		assert.Empty(t, list.Resources, "results of HTTP sessions aren't listed")
+	}
+}
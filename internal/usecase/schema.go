@@ -0,0 +1,166 @@
+package usecase
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/google/jsonschema-go/jsonschema"
+)
+
+// AnalysisOutputSchema returns the JSON schema of AnalysisOutput, the
+// structured content of the analyze_document tool.
+//
+// The schema is derived from the Go types and their jsonschema tags. Unlike
+// jsonschema.For, every named struct type is described once under $defs,
+// which supports the recursive domain types (DocumentField and Error), and
+// fields of embedded structs are promoted like encoding/json does.
+func AnalysisOutputSchema() (*jsonschema.Schema, error) {
+	return outputSchema[AnalysisOutput]("Status and result of a document analysis.")
+}
+
+// GetAnalysisOutputSchema returns the JSON schema of GetAnalysisOutput, the
+// structured content of the get_analysis tool.
+func GetAnalysisOutputSchema() (*jsonschema.Schema, error) {
+	return outputSchema[GetAnalysisOutput]("A stored analysis, with its metadata and result.")
+}
+
+// BatchAnalysisOutputSchema returns the JSON schema of BatchAnalysisOutput,
+// the structured content of the analyze_documents tool.
+func BatchAnalysisOutputSchema() (*jsonschema.Schema, error) {
+	return outputSchema[BatchAnalysisOutput]("Outcome of the analysis of each document of a batch.")
+}
+
+func outputSchema[T any](description string) (*jsonschema.Schema, error) {
+	g := &schemaGenerator{defs: make(map[string]*jsonschema.Schema)}
+	s, err := g.structSchema(reflect.TypeFor[T]())
+	if err != nil {
+		return nil, err
+	}
+	s.Description = description
+	s.Defs = g.defs
+	return s, nil
+}
+
+type schemaGenerator struct {
+	defs map[string]*jsonschema.Schema
+}
+
+func (g *schemaGenerator) schema(t reflect.Type) (*jsonschema.Schema, error) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return &jsonschema.Schema{Type: "boolean"}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &jsonschema.Schema{Type: "integer"}, nil
+	case reflect.Float32, reflect.Float64:
+		return &jsonschema.Schema{Type: "number"}, nil
+	case reflect.String:
+		return &jsonschema.Schema{Type: "string"}, nil
+	case reflect.Interface:
+		return &jsonschema.Schema{}, nil
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &jsonschema.Schema{Type: "string", ContentEncoding: "base64"}, nil
+		}
+		items, err := g.schema(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return &jsonschema.Schema{Type: "array", Items: items}, nil
+	case reflect.Map:
+		if t.Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("unsupported map key type %s", t.Key())
+		}
+		values, err := g.schema(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return &jsonschema.Schema{Type: "object", AdditionalProperties: values}, nil
+	case reflect.Struct:
+		if t == reflect.TypeFor[time.Time]() {
+			return &jsonschema.Schema{Type: "string", Format: "date-time"}, nil
+		}
+		if t.Name() == "" {
+			return g.structSchema(t)
+		}
+		ref := &jsonschema.Schema{Ref: "#/$defs/" + t.Name()}
+		if _, ok := g.defs[t.Name()]; ok {
+			return ref, nil
+		}
+		// Reserve the name before descending so that recursive fields refer to it.
+		g.defs[t.Name()] = nil
+		s, err := g.structSchema(t)
+		if err != nil {
+			return nil, err
+		}
+		g.defs[t.Name()] = s
+		return ref, nil
+	}
+	return nil, fmt.Errorf("unsupported type %s", t)
+}
+
+func (g *schemaGenerator) structSchema(t reflect.Type) (*jsonschema.Schema, error) {
+	s := &jsonschema.Schema{
+		Type:                 "object",
+		Properties:           make(map[string]*jsonschema.Schema),
+		AdditionalProperties: &jsonschema.Schema{Not: &jsonschema.Schema{}},
+	}
+	for i := range t.NumField() {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		tag := f.Tag.Get("json")
+		name, opts, _ := strings.Cut(tag, ",")
+		if name == "-" {
+			continue
+		}
+
+		if f.Anonymous && name == "" {
+			embedded, err := g.structSchema(derefType(f.Type))
+			if err != nil {
+				return nil, err
+			}
+			for prop, ps := range embedded.Properties {
+				s.Properties[prop] = ps
+			}
+			s.Required = append(s.Required, embedded.Required...)
+			// Validation of Go values still sees the embedded field itself,
+			// so it must not be rejected as an additional property.
+			s.AdditionalProperties = nil
+			continue
+		}
+
+		if name == "" {
+			name = f.Name
+		}
+		fs, err := g.schema(f.Type)
+		if err != nil {
+			return nil, fmt.Errorf("%s.%s: %w", t, f.Name, err)
+		}
+		if desc, ok := f.Tag.Lookup("jsonschema"); ok {
+			if fs.Ref != "" {
+				// Siblings of $ref are allowed since draft 2019-09.
+				fs = &jsonschema.Schema{Ref: fs.Ref}
+			}
+			fs.Description = desc
+		}
+		s.Properties[name] = fs
+		if !strings.Contains(opts, "omitempty") && !strings.Contains(opts, "omitzero") {
+			s.Required = append(s.Required, name)
+		}
+	}
+	return s, nil
+}
+
+func derefType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t
+}
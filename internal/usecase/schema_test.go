@@ -0,0 +1,189 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/google/jsonschema-go/jsonschema"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/linzhengen/azure-document-intelligence-mcp/internal/domain/analysis"
+)
+
+const sampleOperationResult = `{
+	"status": "succeeded",
+	"createdDateTime": "2024-11-30T10:00:00Z",
+	"lastUpdatedDateTime": "2024-11-30T10:00:05Z",
+	"analyzeResult": {
+		"apiVersion": "2024-11-30",
+		"modelId": "prebuilt-invoice",
+		"stringIndexType": "textElements",
+		"content": "Contoso\nTotal $110",
+		"pages": [{
+			"pageNumber": 1, "angle": 0, "width": 8.5, "height": 11, "unit": "inch",
+			"spans": [{"offset": 0, "length": 18}],
+			"words": [{"content": "Contoso", "polygon": [1, 1, 2, 1, 2, 2, 1, 2], "span": {"offset": 0, "length": 7}, "confidence": 0.99}],
+			"lines": [{"content": "Contoso", "polygon": [1, 1, 2, 1, 2, 2, 1, 2], "spans": [{"offset": 0, "length": 7}]}]
+		}],
+		"tables": [{"rowCount": 1, "columnCount": 1, "cells": [{"rowIndex": 0, "columnIndex": 0, "content": "Total", "spans": []}], "spans": []}],
+		"documents": [{
+			"docType": "invoice",
+			"spans": [{"offset": 0, "length": 18}],
+			"confidence": 0.9,
+			"fields": {
+				"InvoiceDate": {"type": "date", "valueDate": "2024-11-15", "confidence": 0.9},
+				"InvoiceTotal": {"type": "currency", "valueCurrency": {"amount": 110, "currencySymbol": "$"}, "content": "$110"},
+				"Items": {"type": "array", "valueArray": [
+					{"type": "object", "valueObject": {"Amount": {"type": "number", "valueNumber": 110}}}
+				]}
+			}
+		}]
+	}
+}`
+
+func resolvedOutputSchema(t *testing.T) *jsonschema.Resolved {
+	t.Helper()
+	s, err := AnalysisOutputSchema()
+	require.NoError(t, err)
+	resolved, err := s.Resolve(nil)
+	require.NoError(t, err)
+	return resolved
+}
+
+func sampleOutput(t *testing.T) *AnalysisOutput {
+	t.Helper()
+	var result analysis.AnalyzeOperationResult
+	require.NoError(t, json.Unmarshal([]byte(sampleOperationResult), &result))
+	output := simplifyOutput(&result)
+	output.ReviewReport = []*analysis.ReviewItem{{Kind: analysis.ReviewKindField, Name: "InvoiceDate", Value: "2024-11-15", Confidence: 0.9}}
+	return output
+}
+
+func TestAnalysisOutputSchema_ValidatesOutput(t *testing.T) {
+	resolved := resolvedOutputSchema(t)
+	output := sampleOutput(t)
+
+	// Validate the wire form that clients receive.
+	data, err := json.Marshal(output)
+	require.NoError(t, err)
+	var wire map[string]any
+	require.NoError(t, json.Unmarshal(data, &wire))
+	assert.NoError(t, resolved.Validate(wire))
+
+	// Validate the Go value, as the MCP server does before sending it.
+	assert.NoError(t, resolved.Validate(&output))
+
+	// Validate a raw service response, including the recursive document fields.
+	var raw map[string]any
+	require.NoError(t, json.Unmarshal([]byte(sampleOperationResult), &raw))
+	assert.NoError(t, resolved.Validate(raw))
+}
+
+func TestAnalysisOutputSchema_RejectsInvalidOutput(t *testing.T) {
+	resolved := resolvedOutputSchema(t)
+
+	var wire map[string]any
+	require.NoError(t, json.Unmarshal([]byte(`{"status": 1}`), &wire))
+
+	assert.Error(t, resolved.Validate(wire))
+}
+
+// TestAnalysisOutputSchema_InSync checks that every JSON field of the Go
+// types appears in the schema with a description, and nothing else does.
+func TestAnalysisOutputSchema_InSync(t *testing.T) {
+	s, err := AnalysisOutputSchema()
+	require.NoError(t, err)
+
+	checkSchemaInSync(t, s, s, reflect.TypeFor[AnalysisOutput](), "AnalysisOutput", map[reflect.Type]bool{})
+}
+
+func checkSchemaInSync(t *testing.T, root, s *jsonschema.Schema, typ reflect.Type, path string, seen map[reflect.Type]bool) {
+	for typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+	if s.Ref != "" {
+		name := strings.TrimPrefix(s.Ref, "#/$defs/")
+		def, ok := root.Defs[name]
+		require.True(t, ok, "%s: missing definition %s", path, name)
+		s = def
+	}
+	switch typ.Kind() {
+	case reflect.Slice:
+		if typ.Elem().Kind() == reflect.Uint8 || s.Items == nil {
+			return
+		}
+		checkSchemaInSync(t, root, s.Items, typ.Elem(), path+"[]", seen)
+	case reflect.Map:
+		require.NotNil(t, s.AdditionalProperties, path)
+		checkSchemaInSync(t, root, s.AdditionalProperties, typ.Elem(), path+"{}", seen)
+	case reflect.Struct:
+		if seen[typ] {
+			return
+		}
+		seen[typ] = true
+		defer delete(seen, typ)
+
+		want := map[string]bool{}
+		for _, f := range reflect.VisibleFields(typ) {
+			if !f.IsExported() || f.Anonymous {
+				continue
+			}
+			name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+			if name == "-" {
+				continue
+			}
+			want[name] = true
+			prop, ok := s.Properties[name]
+			if !assert.True(t, ok, "%s.%s: missing from schema", path, name) {
+				continue
+			}
+			assert.NotEmpty(t, prop.Description, "%s.%s: missing description", path, name)
+			checkSchemaInSync(t, root, prop, f.Type, path+"."+name, seen)
+		}
+		for name := range s.Properties {
+			assert.True(t, want[name], "%s.%s: not a field of %s", path, name, typ)
+		}
+	}
+}
+
+func TestAnalysisOutputSchema_ToolCall(t *testing.T) {
+	ctx := context.Background()
+	server := mcp.NewServer(&mcp.Implementation{Name: "test"}, nil)
+	outputSchema, err := AnalysisOutputSchema()
+	require.NoError(t, err)
+	mockRepo := &MockAnalysisRepository{
+		AnalyzeDocumentFunc: func(ctx context.Context, modelID string, options analysis.AnalyzeDocumentOptions) (*analysis.AnalyzeOperationResult, error) {
+			var result analysis.AnalyzeOperationResult
+			err := json.Unmarshal([]byte(sampleOperationResult), &result)
+			return &result, err
+		},
+	}
+	mcp.AddTool(server, &mcp.Tool{Name: "analyze_document", OutputSchema: outputSchema}, NewAnalysisHandler(mockRepo))
+
+	serverTransport, clientTransport := mcp.NewInMemoryTransports()
+	serverSession, err := server.Connect(ctx, serverTransport, nil)
+	require.NoError(t, err)
+	defer func() { _ = serverSession.Close() }()
+	session, err := mcp.NewClient(&mcp.Implementation{Name: "client"}, nil).Connect(ctx, clientTransport, nil)
+	require.NoError(t, err)
+	defer func() { _ = session.Close() }()
+
+	tools, err := session.ListTools(ctx, nil)
+	require.NoError(t, err)
+	require.Len(t, tools.Tools, 1)
+	assert.Contains(t, tools.Tools[0].OutputSchema.Defs, "DocumentField")
+	assert.NotEmpty(t, tools.Tools[0].InputSchema.Properties["modelId"].Description)
+
+	res, err := session.CallTool(ctx, &mcp.CallToolParams{
+		Name:      "analyze_document",
+		Arguments: map[string]any{"modelId": "prebuilt-invoice", "documentUrl": "https://example.com/invoice.pdf", "simplify": true},
+	})
+	require.NoError(t, err)
+	require.False(t, res.IsError, "%v", res.Content)
+	assert.NotNil(t, res.StructuredContent)
+}
@@ -0,0 +1,65 @@
+package usecase
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/linzhengen/azure-document-intelligence-mcp/internal/redact"
+)
+
+// tracerName names the tracer of the MCP layer.
+const tracerName = "github.com/linzhengen/azure-document-intelligence-mcp/internal/usecase"
+
+// TracingMiddleware records a server span for every tool call. The span
+// continues the W3C trace context of the call: the traceparent and tracestate
+// of its _meta take precedence over those of the HTTP request that carried it.
+// Errors are recorded on spans after redactor removed their secrets, as the
+// middleware may run inside RedactionMiddleware.
+func TracingMiddleware(redactor *redact.Redactor) mcp.Middleware {
+	return func(next mcp.MethodHandler) mcp.MethodHandler {
+		return func(ctx context.Context, method string, req mcp.Request) (mcp.Result, error) {
+			params, ok := req.GetParams().(*mcp.CallToolParamsRaw)
+			if method != "tools/call" || !ok {
+				return next(ctx, method, req)
+			}
+
+			carrier := propagation.HeaderCarrier(http.Header{})
+			if extra := req.GetExtra(); extra != nil && extra.Header != nil {
+				carrier = propagation.HeaderCarrier(extra.Header.Clone())
+			}
+			for _, key := range []string{"traceparent", "tracestate"} {
+				if v, ok := params.Meta[key].(string); ok {
+					carrier.Set(key, v)
+				}
+			}
+			ctx = otel.GetTextMapPropagator().Extract(ctx, carrier)
+
+			ctx, span := otel.Tracer(tracerName).Start(ctx, "tools/call "+params.Name,
+				trace.WithSpanKind(trace.SpanKindServer),
+				trace.WithAttributes(
+					attribute.String("mcp.method.name", method),
+					attribute.String("mcp.tool.name", params.Name),
+				),
+			)
+			defer span.End()
+
+			result, err := next(ctx, method, req)
+			switch res, _ := result.(*mcp.CallToolResult); {
+			case err != nil:
+				redacted := redactor.Error(err)
+				span.RecordError(redacted)
+				span.SetStatus(codes.Error, redacted.Error())
+			case res != nil && res.IsError:
+				span.SetStatus(codes.Error, "tool call failed")
+			}
+			return result, err
+		}
+	}
+}
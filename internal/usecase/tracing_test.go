@@ -0,0 +1,100 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/linzhengen/azure-document-intelligence-mcp/internal/domain/analysis"
+	"github.com/linzhengen/azure-document-intelligence-mcp/internal/redact"
+)
+
+func TestTracingMiddleware(t *testing.T) {
+	ctx := context.Background()
+	recorder := tracetest.NewSpanRecorder()
+	provider, propagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	defer func() {
+		otel.SetTracerProvider(provider)
+		otel.SetTextMapPropagator(propagator)
+	}()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	var repoSpan trace.SpanContext
+	mockRepo := &MockAnalysisRepository{
+		AnalyzeDocumentFunc: func(ctx context.Context, modelID string, options analysis.AnalyzeDocumentOptions) (*analysis.AnalyzeOperationResult, error) {
+			repoSpan = trace.SpanContextFromContext(ctx)
+			return &analysis.AnalyzeOperationResult{Status: "succeeded"}, nil
+		},
+	}
+	outputSchema, err := AnalysisOutputSchema()
+	require.NoError(t, err)
+	server := mcp.NewServer(&mcp.Implementation{Name: "test"}, nil)
+	server.AddReceivingMiddleware(TracingMiddleware(nil))
+	mcp.AddTool(server, &mcp.Tool{Name: "analyze_document", OutputSchema: outputSchema}, NewAnalysisHandler(mockRepo))
+
+	serverTransport, clientTransport := mcp.NewInMemoryTransports()
+	serverSession, err := server.Connect(ctx, serverTransport, nil)
+	require.NoError(t, err)
+	defer func() { _ = serverSession.Close() }()
+	session, err := mcp.NewClient(&mcp.Implementation{Name: "client"}, nil).Connect(ctx, clientTransport, nil)
+	require.NoError(t, err)
+	defer func() { _ = session.Close() }()
+
+	traceparent := "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01"
+	_, err = session.CallTool(ctx, &mcp.CallToolParams{
+		Meta:      mcp.Meta{"traceparent": traceparent},
+		Name:      "analyze_document",
+		Arguments: map[string]any{"modelId": "prebuilt-read", "documentUrl": "https://example.com/doc.pdf"},
+	})
+	require.NoError(t, err)
+	_, err = session.CallTool(ctx, &mcp.CallToolParams{
+		Name:      "analyze_document",
+		Arguments: map[string]any{"modelId": "unknown", "documentUrl": "https://example.com/doc.pdf"},
+	})
+	require.NoError(t, err)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+	assert.Equal(t, "tools/call analyze_document", spans[0].Name())
+	assert.Equal(t, trace.SpanKindServer, spans[0].SpanKind())
+	assert.Equal(t, "0af7651916cd43dd8448eb211c80319c", spans[0].SpanContext().TraceID().String())
+	assert.Equal(t, "b7ad6b7169203331", spans[0].Parent().SpanID().String())
+	assert.Equal(t, spans[0].SpanContext().SpanID(), repoSpan.SpanID(), "the repository runs in the tool call span")
+
+	assert.False(t, spans[1].Parent().IsValid())
+	assert.Equal(t, "Error", spans[1].Status().Code.String())
+}
+
+func TestTracingMiddleware_RedactsErrors(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := otel.GetTracerProvider()
+	defer otel.SetTracerProvider(provider)
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+
+	redactor, err := redact.New([]string{"api-key-s3cr3t"}, nil)
+	require.NoError(t, err)
+	callErr := errors.New("request failed with key api-key-s3cr3t")
+	handler := TracingMiddleware(redactor)(func(context.Context, string, mcp.Request) (mcp.Result, error) {
+		return nil, callErr
+	})
+
+	_, err = handler(context.Background(), "tools/call", &mcp.CallToolRequest{Params: &mcp.CallToolParamsRaw{Name: "analyze_document"}})
+	assert.Same(t, callErr, err, "the error is returned unchanged")
+	spans := recorder.Ended()
+	require.Len(t, spans, 1)
+	assert.Equal(t, "request failed with key "+redact.Placeholder, spans[0].Status().Description)
+	require.Len(t, spans[0].Events(), 1)
+	for _, attr := range spans[0].Events()[0].Attributes {
+		assert.NotContains(t, attr.Value.Emit(), "s3cr3t", attr.Key)
+	}
+}
@@ -0,0 +1,227 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+
+	"github.com/linzhengen/azure-document-intelligence-mcp/internal/domain/analysis"
+	"github.com/linzhengen/azure-document-intelligence-mcp/internal/domain/usage"
+)
+
+// UsageMeter records the pages of every analysis in a ledger and rejects
+// analyses that would exceed the page budget before they are sent.
+type UsageMeter struct {
+	ledger usage.Ledger
+	budget usage.Budget
+	now    func() time.Time
+
+	mu       sync.Mutex
+	reserved int // pages held by analyses in progress
+}
+
+// NewUsageMeter creates a meter recording to ledger and enforcing budget.
+func NewUsageMeter(ledger usage.Ledger, budget usage.Budget) *UsageMeter {
+	return &UsageMeter{ledger: ledger, budget: budget, now: time.Now}
+}
+
+// WithUsageMeter accounts for the pages of every analysis with meter.
+func WithUsageMeter(meter *UsageMeter) HandlerOption {
+	return func(o *handlerOptions) {
+		o.usage = meter
+	}
+}
+
+// used returns the pages used today and this month, including reservations.
+func (m *UsageMeter) used(ctx context.Context, now time.Time) (today, month int, err error) {
+	entries, err := m.ledger.Entries(ctx, usage.Month(now))
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to read usage ledger: %w", err)
+	}
+	day := usage.Day(now)
+	for _, e := range entries {
+		month += e.Pages
+		if !e.Time.Before(day) {
+			today += e.Pages
+		}
+	}
+	return today + m.reserved, month + m.reserved, nil
+}
+
+// reservation holds the pages an analysis in progress is expected to use.
+type reservation struct {
+	meter    *UsageMeter
+	pages    string // range to analyze
+	limit    int    // pages a whole document was restricted to, or 0
+	held     int
+	released bool
+}
+
+// release frees the pages held.
+func (r *reservation) release() {
+	r.meter.mu.Lock()
+	defer r.meter.mu.Unlock()
+	r.releaseLocked()
+}
+
+func (r *reservation) releaseLocked() {
+	if !r.released {
+		r.released = true
+		r.meter.reserved -= r.held
+	}
+}
+
+// limitReached reports whether the analysis of result of a whole document
+// reached the maximum pages per request, so that the document may have more
+// pages.
+func (r *reservation) limitReached(result *analysis.AnalyzeOperationResult) bool {
+	return r.limit > 0 && result.AnalyzeResult != nil && len(result.AnalyzeResult.Pages) >= r.limit
+}
+
+// reserve checks the budget for an analysis of the page range and holds the
+// pages it may use until it's recorded or released. An empty range selects
+// the whole document, whose page count is unknown: a single page is held, so
+// that concurrent analyses aren't refused, and the range is only restricted
+// to the maximum pages per request. The analysis of a whole document may so
+// exceed the daily and monthly budgets by its pages past the first. The
+// reservation holds the range to analyze.
+func (m *UsageMeter) reserve(ctx context.Context, pages string) (*reservation, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	today, month, err := m.used(ctx, m.now())
+	if err != nil {
+		return nil, err
+	}
+	requested := 1
+	if pages != "" {
+		if requested, err = analysis.CountPages(pages); err != nil {
+			return nil, err
+		}
+	}
+	if err := m.budget.Check(today, month, requested); err != nil {
+		return nil, err
+	}
+	r := &reservation{meter: m, pages: pages, held: requested}
+	if pages == "" && m.budget.MaxPagesPerRequest > 0 {
+		r.limit = m.budget.MaxPagesPerRequest
+		r.pages = "1-" + strconv.Itoa(r.limit)
+	}
+	m.reserved += r.held
+	return r, nil
+}
+
+// record adds the pages of a completed analysis to the ledger, where they
+// replace the pages held by its reservation.
+func (m *UsageMeter) record(ctx context.Context, req *mcp.CallToolRequest, modelID string, result *analysis.AnalyzeOperationResult, r *reservation) error {
+	entry := usage.Entry{Time: m.now().UTC(), ModelID: modelID}
+	if result.AnalyzeResult != nil {
+		entry.Pages = len(result.AnalyzeResult.Pages)
+	}
+	entry.Session = sessionID(req)
+	entry.Client = clientName(req)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	r.releaseLocked()
+	if err := m.ledger.Append(ctx, entry); err != nil {
+		return fmt.Errorf("failed to record usage: %w", err)
+	}
+	return nil
+}
+
+// sessionID returns the ID of the session of req, which is empty over stdio.
+func sessionID(req *mcp.CallToolRequest) string {
+	if req == nil || req.Session == nil {
+		return ""
+	}
+	return req.Session.ID()
+}
+
+// clientName returns the name the client of req gave when initializing its session.
+func clientName(req *mcp.CallToolRequest) string {
+	if req == nil || req.Session == nil {
+		return ""
+	}
+	if params := req.Session.InitializeParams(); params != nil && params.ClientInfo != nil {
+		return params.ClientInfo.Name
+	}
+	return ""
+}
+
+// UsageParams defines the parameters of the usage tool.
+type UsageParams struct {
+	From string `json:"from,omitempty" jsonschema:"First UTC day to report, as YYYY-MM-DD. Defaults to the first day of the current month."`
+	To   string `json:"to,omitempty" jsonschema:"Last UTC day to report, as YYYY-MM-DD. Defaults to today."`
+}
+
+// UsageOutput reports the pages analyzed and the budget left.
+type UsageOutput struct {
+	From    string         `json:"from" jsonschema:"First UTC day reported."`
+	To      string         `json:"to" jsonschema:"Last UTC day reported."`
+	Summary *usage.Summary `json:"summary" jsonschema:"Pages analyzed between from and to."`
+	Budget  *BudgetStatus  `json:"budget" jsonschema:"Page budget and its use today and this month."`
+}
+
+// BudgetStatus reports the page budget. Zero limits are disabled.
+type BudgetStatus struct {
+	Daily              int `json:"daily" jsonschema:"Pages allowed per UTC day, or 0 for no limit."`
+	Monthly            int `json:"monthly" jsonschema:"Pages allowed per UTC calendar month, or 0 for no limit."`
+	MaxPagesPerRequest int `json:"maxPagesPerRequest" jsonschema:"Pages allowed per analysis, or 0 for no limit."`
+	UsedToday          int `json:"usedToday" jsonschema:"Pages used today, including analyses in progress."`
+	UsedThisMonth      int `json:"usedThisMonth" jsonschema:"Pages used this month, including analyses in progress."`
+}
+
+// NewUsageHandler creates a tool handler reporting the usage recorded by meter.
+func NewUsageHandler(meter *UsageMeter) func(context.Context, *mcp.CallToolRequest, *UsageParams) (*mcp.CallToolResult, *UsageOutput, error) {
+	return func(ctx context.Context, _ *mcp.CallToolRequest, params *UsageParams) (*mcp.CallToolResult, *UsageOutput, error) {
+		now := meter.now()
+		from, to := usage.Month(now), usage.Day(now)
+		var err error
+		if params.From != "" {
+			if from, err = time.Parse(time.DateOnly, params.From); err != nil {
+				return nil, nil, fmt.Errorf("invalid from date %q: expected YYYY-MM-DD", params.From)
+			}
+		}
+		if params.To != "" {
+			if to, err = time.Parse(time.DateOnly, params.To); err != nil {
+				return nil, nil, fmt.Errorf("invalid to date %q: expected YYYY-MM-DD", params.To)
+			}
+		}
+
+		entries, err := meter.ledger.Entries(ctx, from)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read usage ledger: %w", err)
+		}
+		end := to.AddDate(0, 0, 1)
+		var selected []usage.Entry
+		for _, e := range entries {
+			if e.Time.Before(end) {
+				selected = append(selected, e)
+			}
+		}
+
+		meter.mu.Lock()
+		today, month, err := meter.used(ctx, now)
+		meter.mu.Unlock()
+		if err != nil {
+			return nil, nil, err
+		}
+		return nil, &UsageOutput{
+			From:    from.Format(time.DateOnly),
+			To:      to.Format(time.DateOnly),
+			Summary: usage.Summarize(selected),
+			Budget: &BudgetStatus{
+				Daily:              meter.budget.Daily,
+				Monthly:            meter.budget.Monthly,
+				MaxPagesPerRequest: meter.budget.MaxPagesPerRequest,
+				UsedToday:          today,
+				UsedThisMonth:      month,
+			},
+		}, nil
+	}
+}
@@ -0,0 +1,264 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/linzhengen/azure-document-intelligence-mcp/internal/domain/analysis"
+	"github.com/linzhengen/azure-document-intelligence-mcp/internal/domain/usage"
+	analysisinfra "github.com/linzhengen/azure-document-intelligence-mcp/internal/infrastructure/analysis"
+)
+
+// memoryLedger is an in-memory usage.Ledger.
+type memoryLedger struct {
+	mu      sync.Mutex
+	entries []usage.Entry
+}
+
+func (l *memoryLedger) Append(_ context.Context, entry usage.Entry) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.entries = append(l.entries, entry)
+	return nil
+}
+
+func (l *memoryLedger) Entries(_ context.Context, since time.Time) ([]usage.Entry, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var entries []usage.Entry
+	for _, e := range l.entries {
+		if !e.Time.Before(since) {
+			entries = append(entries, e)
+		}
+	}
+	return entries, nil
+}
+
+func newTestMeter(ledger usage.Ledger, budget usage.Budget, now time.Time) *UsageMeter {
+	meter := NewUsageMeter(ledger, budget)
+	meter.now = func() time.Time { return now }
+	return meter
+}
+
+// pagesResult returns a result with n pages.
+func pagesResult(n int) *analysis.AnalyzeOperationResult {
+	return &analysis.AnalyzeOperationResult{
+		Status:        "succeeded",
+		AnalyzeResult: &analysis.AnalyzeResult{Pages: make([]analysis.Page, n)},
+	}
+}
+
+func TestNewAnalysisHandler_DailyBudget(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 5, 2, 10, 0, 0, 0, time.UTC)
+	ledger := &memoryLedger{entries: []usage.Entry{
+		{Time: now.Add(-24 * time.Hour), ModelID: "prebuilt-read", Pages: 100}, // yesterday
+	}}
+	var sentPages []string
+	mockRepo := &MockAnalysisRepository{
+		AnalyzeDocumentFunc: func(ctx context.Context, modelID string, options analysis.AnalyzeDocumentOptions) (*analysis.AnalyzeOperationResult, error) {
+			sentPages = append(sentPages, options.Pages)
+			return pagesResult(3), nil
+		},
+	}
+	handler := NewAnalysisHandler(mockRepo, WithUsageMeter(newTestMeter(ledger, usage.Budget{Daily: 5}, now)))
+	params := &AnalysisParams{ModelID: "prebuilt-read", DocumentURL: "http://example.com/doc.pdf"}
+
+	_, output, err := handler(ctx, nil, params)
+	require.NoError(t, err)
+	assert.False(t, output.PageLimitReached)
+	_, _, err = handler(ctx, nil, params)
+	require.NoError(t, err, "a whole document only needs one page left")
+	_, _, err = handler(ctx, nil, params)
+
+	var exceeded *usage.ExceededError
+	require.True(t, errors.As(err, &exceeded), "%v", err)
+	assert.Equal(t, "daily", exceeded.Period)
+	assert.Equal(t, []string{"", ""}, sentPages, "the range of whole documents isn't made up from the budget")
+	require.Len(t, ledger.entries, 3)
+	assert.Equal(t, usage.Entry{Time: now, ModelID: "prebuilt-read", Pages: 3}, ledger.entries[1])
+}
+
+func TestNewAnalysisHandler_ConcurrentBudget(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 5, 2, 10, 0, 0, 0, time.UTC)
+	ledger := &memoryLedger{}
+	var mu sync.Mutex
+	var sentPages []string
+	arrived, proceed := make(chan struct{}), make(chan struct{})
+	mockRepo := &MockAnalysisRepository{
+		AnalyzeDocumentFunc: func(ctx context.Context, modelID string, options analysis.AnalyzeDocumentOptions) (*analysis.AnalyzeOperationResult, error) {
+			mu.Lock()
+			sentPages = append(sentPages, options.Pages)
+			mu.Unlock()
+			arrived <- struct{}{}
+			<-proceed
+			return pagesResult(3), nil
+		},
+	}
+	meter := newTestMeter(ledger, usage.Budget{Daily: 1000}, now)
+	handler := NewAnalysisHandler(mockRepo, WithUsageMeter(meter))
+	params := &AnalysisParams{ModelID: "prebuilt-read", DocumentURL: "http://example.com/doc.pdf"}
+
+	errs := make(chan error, 2)
+	for range 2 {
+		go func() {
+			_, _, err := handler(ctx, nil, params)
+			errs <- err
+		}()
+	}
+	<-arrived
+	<-arrived
+	meter.mu.Lock()
+	assert.Equal(t, 2, meter.reserved, "whole documents hold a single page until their count is known")
+	meter.mu.Unlock()
+	close(proceed)
+	require.NoError(t, <-errs)
+	require.NoError(t, <-errs)
+
+	assert.Equal(t, []string{"", ""}, sentPages)
+	assert.Len(t, ledger.entries, 2)
+	assert.Zero(t, meter.reserved)
+	today, _, err := meter.used(ctx, now)
+	require.NoError(t, err)
+	assert.Equal(t, 6, today, "the pages held are replaced by the pages analyzed")
+}
+
+func TestNewAnalysisHandler_SharedAnalysesRecordedOnce(t *testing.T) {
+	ctx := context.Background()
+	ledger := &memoryLedger{}
+	var calls atomic.Int32
+	proceed := make(chan struct{})
+	mockRepo := &MockAnalysisRepository{
+		AnalyzeDocumentFunc: func(ctx context.Context, modelID string, options analysis.AnalyzeDocumentOptions) (*analysis.AnalyzeOperationResult, error) {
+			calls.Add(1)
+			<-proceed
+			return pagesResult(3), nil
+		},
+	}
+	var joined atomic.Int32
+	repo := analysisinfra.Coalesce(mockRepo, func(string) { joined.Add(1) })
+	meter := newTestMeter(ledger, usage.Budget{}, time.Now())
+	handler := NewAnalysisHandler(repo, WithUsageMeter(meter))
+	params := &AnalysisParams{ModelID: "prebuilt-read", DocumentURL: "http://example.com/doc.pdf"}
+
+	errs := make(chan error, 3)
+	for range 3 {
+		go func() {
+			_, _, err := handler(ctx, nil, params)
+			errs <- err
+		}()
+	}
+	require.Eventually(t, func() bool { return joined.Load() == 2 }, time.Second, time.Millisecond)
+	close(proceed)
+	for range 3 {
+		require.NoError(t, <-errs)
+	}
+
+	assert.Equal(t, int32(1), calls.Load())
+	require.Len(t, ledger.entries, 1, "one analysis is recorded once")
+	assert.Equal(t, 3, ledger.entries[0].Pages)
+	assert.Zero(t, meter.reserved)
+}
+
+func TestNewAnalysisHandler_MaxPagesPerRequest(t *testing.T) {
+	ctx := context.Background()
+	called := false
+	mockRepo := &MockAnalysisRepository{
+		AnalyzeDocumentFunc: func(ctx context.Context, modelID string, options analysis.AnalyzeDocumentOptions) (*analysis.AnalyzeOperationResult, error) {
+			called = true
+			return pagesResult(1), nil
+		},
+	}
+	meter := newTestMeter(&memoryLedger{}, usage.Budget{MaxPagesPerRequest: 4}, time.Now())
+	handler := NewAnalysisHandler(mockRepo, WithUsageMeter(meter))
+
+	_, _, err := handler(ctx, nil, &AnalysisParams{ModelID: "prebuilt-read", DocumentURL: "http://example.com/doc.pdf", Pages: "1-3,8-9"})
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "at most 4 pages")
+	assert.False(t, called, "the request must be rejected before it's sent")
+	assert.Zero(t, meter.reserved)
+}
+
+func TestNewAnalysisHandler_WholeDocumentPageLimit(t *testing.T) {
+	ctx := context.Background()
+	var sent []analysis.AnalyzeDocumentOptions
+	pages := 3
+	mockRepo := &MockAnalysisRepository{
+		AnalyzeDocumentFunc: func(ctx context.Context, modelID string, options analysis.AnalyzeDocumentOptions) (*analysis.AnalyzeOperationResult, error) {
+			sent = append(sent, options)
+			return pagesResult(pages), nil
+		},
+	}
+	meter := newTestMeter(&memoryLedger{}, usage.Budget{Daily: 100, MaxPagesPerRequest: 4}, time.Now())
+	handler := NewAnalysisHandler(mockRepo, WithUsageMeter(meter))
+	params := &AnalysisParams{ModelID: "prebuilt-read", DocumentURL: "http://example.com/doc.pdf"}
+
+	_, output, err := handler(ctx, nil, params)
+	require.NoError(t, err)
+	assert.False(t, output.PageLimitReached, "a document shorter than the limit")
+	pages = 4
+	_, output, err = handler(ctx, nil, params)
+	require.NoError(t, err)
+	assert.True(t, output.PageLimitReached)
+	_, output, err = handler(ctx, nil, &AnalysisParams{ModelID: "prebuilt-read", DocumentURL: "http://example.com/doc.pdf", Pages: "1-4"})
+	require.NoError(t, err)
+	assert.False(t, output.PageLimitReached, "the range was given by the caller")
+
+	require.Len(t, sent, 3)
+	assert.Equal(t, "1-4", sent[0].Pages)
+	assert.Equal(t, 4, sent[0].PageLimit, "the range made up by the server is marked")
+	assert.Zero(t, sent[2].PageLimit)
+}
+
+func TestNewAnalysisHandler_LocalResultsAreNotRecorded(t *testing.T) {
+	mockRepo := &MockAnalysisRepository{
+		AnalyzeDocumentFunc: func(ctx context.Context, modelID string, options analysis.AnalyzeDocumentOptions) (*analysis.AnalyzeOperationResult, error) {
+			result := pagesResult(2)
+			result.Source = analysis.SourceLocal
+			return result, nil
+		},
+	}
+	ledger := &memoryLedger{}
+	handler := NewAnalysisHandler(mockRepo, WithUsageMeter(newTestMeter(ledger, usage.Budget{}, time.Now())))
+
+	_, output, err := handler(context.Background(), nil, &AnalysisParams{ModelID: "prebuilt-read", DocumentContent: "JVBERi0=", ContentType: "application/pdf"})
+	require.NoError(t, err)
+	assert.Equal(t, analysis.SourceLocal, output.Source)
+	assert.Empty(t, ledger.entries)
+}
+
+func TestNewUsageHandler(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 5, 2, 10, 0, 0, 0, time.UTC)
+	ledger := &memoryLedger{entries: []usage.Entry{
+		{Time: now.AddDate(0, -1, 0), ModelID: "prebuilt-read", Pages: 50},
+		{Time: now.Add(-24 * time.Hour), ModelID: "prebuilt-read", Client: "claude", Pages: 2},
+		{Time: now, ModelID: "prebuilt-invoice", Client: "claude", Session: "s1", Pages: 1},
+	}}
+	handler := NewUsageHandler(newTestMeter(ledger, usage.Budget{Daily: 10, Monthly: 100}, now))
+
+	_, output, err := handler(ctx, nil, &UsageParams{})
+	require.NoError(t, err)
+	assert.Equal(t, "2024-05-01", output.From)
+	assert.Equal(t, "2024-05-02", output.To)
+	assert.Equal(t, 3, output.Summary.Pages)
+	assert.Equal(t, map[string]int{"claude": 3}, output.Summary.ByClient)
+	assert.Equal(t, map[string]int{"s1": 1}, output.Summary.BySession)
+	assert.Equal(t, &BudgetStatus{Daily: 10, Monthly: 100, UsedToday: 1, UsedThisMonth: 3}, output.Budget)
+
+	_, output, err = handler(ctx, nil, &UsageParams{From: "2024-04-01", To: "2024-04-30"})
+	require.NoError(t, err)
+	assert.Equal(t, 50, output.Summary.Pages)
+
+	_, _, err = handler(ctx, nil, &UsageParams{From: "May 1"})
+	assert.Error(t, err)
+}
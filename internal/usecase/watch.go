@@ -0,0 +1,102 @@
+package usecase
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"path/filepath"
+
+	"github.com/linzhengen/azure-document-intelligence-mcp/internal/domain/analysis"
+	"github.com/linzhengen/azure-document-intelligence-mcp/internal/logging"
+)
+
+// ModelRule selects the model analyzing the files matching a glob pattern,
+// such as invoices-*.pdf. Patterns match the file name or its full path.
+type ModelRule struct {
+	Pattern string
+	ModelID string
+}
+
+// ResultWriter stores the results of analyzed files, named after the file
+// and the hash of its content.
+type ResultWriter interface {
+	// Exists reports whether the results of the file are stored.
+	Exists(name, hash string) bool
+	Write(name, hash string, result *analysis.AnalyzeOperationResult) error
+}
+
+// FileAnalyzer analyzes files of watched directories as they arrive,
+// without a client in the loop.
+type FileAnalyzer struct {
+	analyzer *analyzer
+	rules    []ModelRule
+	output   ResultWriter
+}
+
+// NewFileAnalyzer returns an analyzer of the files matching rules, tried in
+// order, with the model of the first match. Files are read with the reader of
+// WithDocumentReader, which is required, and their results written to output.
+func NewFileAnalyzer(analyzerRepo analysis.Repository, rules []ModelRule, output ResultWriter, opts ...HandlerOption) (*FileAnalyzer, error) {
+	a := newAnalyzer(analyzerRepo, opts)
+	if a.files == nil {
+		return nil, errors.New("no document reader")
+	}
+	for _, r := range rules {
+		if _, err := filepath.Match(r.Pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", r.Pattern, err)
+		}
+		if err := a.checkModel(r.ModelID); err != nil {
+			return nil, fmt.Errorf("pattern %q: %w", r.Pattern, err)
+		}
+	}
+	return &FileAnalyzer{analyzer: a, rules: rules, output: output}, nil
+}
+
+// Model returns the ID of the model analyzing the file at path, or "" if it
+// matches no rule.
+func (f *FileAnalyzer) Model(path string) string {
+	for _, r := range f.rules {
+		if ok, _ := filepath.Match(r.Pattern, filepath.Base(path)); ok {
+			return r.ModelID
+		}
+		if ok, _ := filepath.Match(r.Pattern, path); ok {
+			return r.ModelID
+		}
+	}
+	return ""
+}
+
+// Analyze analyzes the file at path and writes its results, unless it
+// matches no rule or the results of the same content are already written.
+func (f *FileAnalyzer) Analyze(ctx context.Context, path string) error {
+	ctx = logging.NewContext(ctx, sessionLogger(f.analyzer.logger, f.analyzer.redactor, nil).With("file", path))
+	logger := logging.FromContext(ctx)
+	modelID := f.Model(path)
+	if modelID == "" {
+		logger.DebugContext(ctx, "no model for file")
+		return nil
+	}
+	content, contentType, err := f.analyzer.files.Read(ctx, path)
+	if err != nil {
+		return err
+	}
+	sum := sha256.Sum256(content)
+	hash := hex.EncodeToString(sum[:8])
+	if f.output.Exists(path, hash) {
+		logger.DebugContext(ctx, "file already analyzed", "hash", hash)
+		return nil
+	}
+
+	params := &AnalysisParams{ModelID: modelID}
+	output, _, err := f.analyzer.analyze(ctx, nil, params, analysis.AnalyzeDocumentOptions{Content: content, ContentType: contentType})
+	if err != nil {
+		return err
+	}
+	if err := f.output.Write(path, hash, output.AnalyzeOperationResult); err != nil {
+		return err
+	}
+	logger.InfoContext(ctx, "analyzed file", "modelId", modelID, "hash", hash)
+	return nil
+}
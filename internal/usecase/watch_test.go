@@ -0,0 +1,75 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/linzhengen/azure-document-intelligence-mcp/internal/domain/analysis"
+)
+
+// memoryWriter keeps the results written, by name and hash.
+type memoryWriter map[string]*analysis.AnalyzeOperationResult
+
+func (w memoryWriter) Exists(name, hash string) bool {
+	_, ok := w[name+"@"+hash]
+	return ok
+}
+
+func (w memoryWriter) Write(name, hash string, result *analysis.AnalyzeOperationResult) error {
+	w[name+"@"+hash] = result
+	return nil
+}
+
+func TestFileAnalyzer(t *testing.T) {
+	ctx := context.Background()
+	var models []string
+	mockRepo := &MockAnalysisRepository{
+		AnalyzeDocumentFunc: func(ctx context.Context, modelID string, options analysis.AnalyzeDocumentOptions) (*analysis.AnalyzeOperationResult, error) {
+			models = append(models, modelID)
+			assert.Equal(t, "application/pdf", options.ContentType)
+			return &analysis.AnalyzeOperationResult{Status: "succeeded", AnalyzeResult: &analysis.AnalyzeResult{ModelID: modelID, Content: "Contact jane@contoso.com"}}, nil
+		},
+	}
+	reader := stubReader{
+		"/srv/scans/invoice-7.pdf": []byte("%PDF-1.7 invoice"),
+		"/srv/scans/letter.pdf":    []byte("%PDF-1.7 letter"),
+		"/srv/scans/notes.txt":     []byte("notes"),
+	}
+	output := memoryWriter{}
+	analyzer, err := NewFileAnalyzer(mockRepo, []ModelRule{
+		{Pattern: "invoice-*.pdf", ModelID: "prebuilt-invoice"},
+		{Pattern: "/srv/scans/*.pdf", ModelID: "prebuilt-read"},
+	}, output, WithDocumentReader(reader), WithPIIRedaction(nil, true))
+	require.NoError(t, err)
+
+	for _, path := range []string{"/srv/scans/invoice-7.pdf", "/srv/scans/letter.pdf", "/srv/scans/notes.txt"} {
+		require.NoError(t, analyzer.Analyze(ctx, path))
+	}
+	assert.Equal(t, []string{"prebuilt-invoice", "prebuilt-read"}, models, "files matching no rule are ignored")
+	require.Len(t, output, 2)
+	for _, result := range output {
+		assert.Equal(t, "Contact [EMAIL_1]", result.AnalyzeResult.Content, "results are redacted as configured")
+	}
+
+	require.NoError(t, analyzer.Analyze(ctx, "/srv/scans/letter.pdf"))
+	assert.Len(t, models, 2, "content already analyzed is skipped")
+	reader["/srv/scans/letter.pdf"] = []byte("%PDF-1.7 letter, second version")
+	require.NoError(t, analyzer.Analyze(ctx, "/srv/scans/letter.pdf"))
+	assert.Len(t, models, 3)
+	assert.Len(t, output, 3)
+
+	assert.Error(t, analyzer.Analyze(ctx, "/srv/scans/missing.pdf"))
+}
+
+func TestNewFileAnalyzer_Errors(t *testing.T) {
+	reader := WithDocumentReader(stubReader{})
+	_, err := NewFileAnalyzer(&MockAnalysisRepository{}, []ModelRule{{Pattern: "[a-", ModelID: "prebuilt-read"}}, memoryWriter{}, reader)
+	assert.ErrorContains(t, err, `invalid pattern "[a-"`)
+	_, err = NewFileAnalyzer(&MockAnalysisRepository{}, []ModelRule{{Pattern: "*.pdf", ModelID: "prebuilt-invoice"}}, memoryWriter{}, reader, WithAllowedModels([]string{"prebuilt-read"}))
+	assert.ErrorContains(t, err, "modelId prebuilt-invoice is not enabled on this server")
+	_, err = NewFileAnalyzer(&MockAnalysisRepository{}, nil, memoryWriter{})
+	assert.Error(t, err)
+}
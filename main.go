@@ -2,47 +2,539 @@ package main
 
 import (
 	"context"
-	"log"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"maps"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 
 	"github.com/linzhengen/azure-document-intelligence-mcp/config"
 	"github.com/linzhengen/azure-document-intelligence-mcp/internal/domain/analysis"
+	"github.com/linzhengen/azure-document-intelligence-mcp/internal/domain/history"
+	"github.com/linzhengen/azure-document-intelligence-mcp/internal/domain/pii"
+	"github.com/linzhengen/azure-document-intelligence-mcp/internal/domain/usage"
 	analysisinfra "github.com/linzhengen/azure-document-intelligence-mcp/internal/infrastructure/analysis"
+	"github.com/linzhengen/azure-document-intelligence-mcp/internal/infrastructure/analysis/fake"
+	"github.com/linzhengen/azure-document-intelligence-mcp/internal/infrastructure/fetch"
+	historyinfra "github.com/linzhengen/azure-document-intelligence-mcp/internal/infrastructure/history"
+	"github.com/linzhengen/azure-document-intelligence-mcp/internal/infrastructure/limit"
+	"github.com/linzhengen/azure-document-intelligence-mcp/internal/infrastructure/metrics"
+	"github.com/linzhengen/azure-document-intelligence-mcp/internal/infrastructure/pdf"
+	"github.com/linzhengen/azure-document-intelligence-mcp/internal/infrastructure/tracing"
+	"github.com/linzhengen/azure-document-intelligence-mcp/internal/infrastructure/urlpolicy"
+	usageinfra "github.com/linzhengen/azure-document-intelligence-mcp/internal/infrastructure/usage"
+	"github.com/linzhengen/azure-document-intelligence-mcp/internal/logging"
+	"github.com/linzhengen/azure-document-intelligence-mcp/internal/redact"
 	"github.com/linzhengen/azure-document-intelligence-mcp/internal/usecase"
 )
 
+const (
+	serverName    = "azure-document-intelligence-mcp"
+	serverVersion = "1.0.0"
+)
+
+// Tool names, as listed in the disabled_tools setting.
+const (
+	toolAnalyzeDocument  = "analyze_document"
+	toolAnalyzeDocuments = "analyze_documents"
+	toolGetUsage         = "get_usage"
+	toolListAnalyses     = "list_analyses"
+	toolGetAnalysis      = "get_analysis"
+	toolSearchAnalyses   = "search_analyses"
+)
+
+var toolNames = []string{toolAnalyzeDocument, toolAnalyzeDocuments, toolGetUsage, toolListAnalyses, toolGetAnalysis, toolSearchAnalyses}
+
 func main() {
 	ctx := context.Background()
 
-	// 1. Load configuration
-	cfg, err := config.Load()
-	if err != nil {
-		log.Fatalf("Failed to load config: %v", err)
+	configFile := flag.String("config", "", "YAML or TOML configuration file (overrides CONFIG_FILE)")
+	profile := flag.String("profile", "", "profile of the configuration file to apply, such as dev or prod (overrides CONFIG_PROFILE)")
+	fakeBackend := flag.Bool("fake-backend", false, "serve analyses from an in-process fake service instead of Azure")
+	transport := flag.String("transport", "", "how clients connect: stdio or http (overrides MCP_TRANSPORT)")
+	var logLevel slog.Level
+	flag.TextVar(&logLevel, "log-level", slog.LevelInfo, "minimum level of log records: DEBUG, INFO, WARN or ERROR (overrides LOG_LEVEL)")
+	flag.Usage = printUsage
+	flag.Parse()
+
+	// Flags override the configuration file and environment variables.
+	loadConfig := func() (*config.Config, error) {
+		cfg, err := config.Load(*configFile, *profile)
+		if err != nil {
+			return nil, err
+		}
+		if *fakeBackend {
+			cfg.FakeBackend = true
+		}
+		if *transport != "" {
+			cfg.Transport = *transport
+		}
+		flag.Visit(func(f *flag.Flag) {
+			if f.Name == "log-level" {
+				cfg.LogLevel = logLevel
+			}
+		})
+		return cfg, nil
+	}
+
+	if args := flag.Args(); len(args) > 0 {
+		switch {
+		case len(args) >= 2 && args[0] == "config" && args[1] == "validate":
+			parseCommandFlags(args[2:])
+			os.Exit(validateConfig(loadConfig))
+		case args[0] == "watch":
+			parseCommandFlags(args[1:])
+			cfg, redactor, logger := setUp(loadConfig)
+			os.Exit(runWatch(ctx, cfg, redactor, logger))
+		case len(args) >= 2 && args[0] == "models" && args[1] == "list":
+			os.Exit(runModels(args[2:], loadConfig))
+		case args[0] == "analyze" || args[0] == "classify" || args[0] == "tables":
+			os.Exit(runDocumentCommand(ctx, args[0], args[1:], loadConfig))
+		case args[0] == "serve":
+			parseCommandFlags(args[1:])
+		default:
+			fmt.Fprintf(os.Stderr, "unknown command %q\n", strings.Join(args, " "))
+			flag.Usage()
+			os.Exit(2)
+		}
 	}
 
+	// 1. Load configuration. Logs go to stderr: stdout carries the MCP stdio framing.
+	cfg, redactor, logger := setUp(loadConfig)
+
 	// 2. Initialize infrastructure layer
-	analysisRepo := analysisinfra.NewRepository(cfg.AzureEndpoint, cfg.AzureAPIKey, cfg.HTTPClientTimeout)
+	stop := startBackend(ctx, cfg, logger)
+	defer stop()
+	serverMetrics := metrics.New()
+	analysisRepo, err := newAnalysisRepository(cfg, redactor, serverMetrics)
+	if err != nil {
+		fatal(logger, "Invalid config", err)
+	}
+	analysisQueue := limit.NewFairQueue(cfg.MaxConcurrentAnalyses)
+	serverMetrics.WatchQueue(analysisQueue.Depth)
 
 	// 3. Create MCP server
 	server := mcp.NewServer(&mcp.Implementation{
-		Name:    "azure-document-intelligence-mcp",
-		Version: "1.0.0",
+		Name:    serverName,
+		Version: serverVersion,
 	}, nil)
+	server.AddReceivingMiddleware(usecase.RedactionMiddleware(redactor), usecase.TracingMiddleware(redactor))
 
-	// 4. Create the tool handler
-	analysisHandler := usecase.NewAnalysisHandler(analysisRepo)
+	// 4. Expose completed analyses as resources and create the tool handler
+	resultResources := usecase.NewResultResources(server, cfg.ResultCacheSize)
+	usageMeter, err := newUsageMeter(cfg)
+	if err != nil {
+		fatal(logger, "Failed to load usage ledger", err)
+	}
+	urlPolicy, fetchPolicy := newURLPolicy(cfg), newFetchPolicy(cfg)
+	piiDetector, err := newPIIDetector(cfg.PIIPatterns)
+	if err != nil {
+		fatal(logger, "Invalid config", err)
+	}
+	handlerOptions := []usecase.HandlerOption{
+		usecase.WithResultPublisher(resultResources),
+		usecase.WithLogger(logger),
+		usecase.WithRedactor(redactor),
+		usecase.WithPIIRedaction(piiDetector, cfg.PIIRedactAlways),
+		usecase.WithUsageMeter(usageMeter),
+		usecase.WithAnalysisQueue(analysisQueue),
+		usecase.WithURLValidator(urlPolicy),
+		usecase.WithFetchURLValidator(fetchPolicy),
+		usecase.WithDocumentFetcher(fetch.New(fetch.Options{
+			MaxBytes:     cfg.FetchMaxBytes,
+			Timeout:      time.Duration(cfg.FetchTimeout) * time.Second,
+			MaxRedirects: cfg.FetchMaxRedirects,
+			Headers:      cfg.FetchHostHeaders,
+			Policy:       fetchPolicy,
+		})),
+	}
+	if len(cfg.AllowedModels) > 0 {
+		handlerOptions = append(handlerOptions, usecase.WithAllowedModels(cfg.AllowedModels))
+	}
+	if len(cfg.DocumentDirs) > 0 {
+		files, err := fetch.NewFiles(cfg.DocumentDirs, cfg.FetchMaxBytes)
+		if err != nil {
+			fatal(logger, "Invalid config", err)
+		}
+		handlerOptions = append(handlerOptions, usecase.WithDocumentReader(files))
+	}
+	var resultStore history.Store
+	if cfg.HistoryEnabled {
+		resultStore, err = historyinfra.NewFileStore(historyDir(cfg), historyRetention(cfg))
+		if err != nil {
+			fatal(logger, "Failed to load analysis history", err)
+		}
+		handlerOptions = append(handlerOptions, usecase.WithResultStore(resultStore))
+	}
+	analysisHandler := usecase.NewAnalysisHandler(analysisRepo, handlerOptions...)
 
-	// 5. Register the analysis tool
+	// 5. Register the enabled tools
 	analyzeToolDef := &mcp.Tool{
-		Name:        "analyze_document",
-		Description: "Analyzes a document using Azure Document Intelligence. Pass 'prebuilt-read' or 'prebuilt-layout' in the modelId parameter. Provide the document either via 'documentUrl' or by passing base64 encoded data in 'documentContent' with its 'contentType'.",
+		Name:        toolAnalyzeDocument,
+		Description: "Analyzes a document using Azure Document Intelligence. Pass 'prebuilt-read', 'prebuilt-layout', 'prebuilt-invoice' or 'prebuilt-contract' in the modelId parameter. Provide the document either via 'documentUrl' or by passing base64 encoded data in 'documentContent' with its 'contentType'. Set 'simplify' to return extracted document fields as flattened plain values. Set per-element-type thresholds in 'confidence' to get a review report of uncertain extractions, optionally removing them from the result. Set 'pages' (for example '1-3,5') to analyze only some pages; requests that would exceed the page budget are rejected, and whole documents are analyzed up to the maximum pages per request, marked 'pageLimitReached' when they reach it. Set 'fetchMode' to 'server' to download 'documentUrl' on this server, for URLs the service can't reach. Set 'region' to keep the document on endpoints of that region. Set 'redactPii' to replace personal data such as email addresses and phone numbers by placeholders, listed in 'piiReport'.",
+	}
+	outputSchema, err := usecase.AnalysisOutputSchema()
+	if err != nil {
+		fatal(logger, "Failed to build output schema", err)
 	}
-	mcp.AddTool[*usecase.AnalysisParams, *analysis.AnalyzeOperationResult](server, analyzeToolDef, analysisHandler)
+	analyzeToolDef.OutputSchema = outputSchema
+	if !slices.Contains(cfg.DisabledTools, toolAnalyzeDocument) {
+		mcp.AddTool[*usecase.AnalysisParams, *usecase.AnalysisOutput](server, analyzeToolDef, analysisHandler)
+	}
+	if !slices.Contains(cfg.DisabledTools, toolAnalyzeDocuments) {
+		batchSchema, err := usecase.BatchAnalysisOutputSchema()
+		if err != nil {
+			fatal(logger, "Failed to build output schema", err)
+		}
+		mcp.AddTool(server, &mcp.Tool{
+			Name:         toolAnalyzeDocuments,
+			Description:  "Analyzes several documents with the same model and options, in parallel. Give each document in 'documents' by 'documentUrl', 'documentPath' (a file in a document directory of this server) or base64 encoded 'documentContent' with its 'contentType'. Returns the result or error of each document: a document that fails doesn't fail the others. Reports progress as documents complete.",
+			OutputSchema: batchSchema,
+		}, usecase.NewBatchAnalysisHandler(analysisRepo, usecase.BatchLimits{
+			Concurrency:  cfg.BatchConcurrency,
+			MaxDocuments: cfg.BatchMaxDocuments,
+		}, handlerOptions...))
+	}
+	if !slices.Contains(cfg.DisabledTools, toolGetUsage) {
+		mcp.AddTool(server, &mcp.Tool{
+			Name:        toolGetUsage,
+			Description: "Reports the pages analyzed per model, client, session and day between 'from' and 'to' (YYYY-MM-DD, defaulting to the current month), and the page budgets with their use today and this month. Azure bills per analyzed page.",
+		}, usecase.NewUsageHandler(usageMeter))
+	}
+	if resultStore != nil {
+		registerHistoryTools(server, resultStore, cfg.DisabledTools, logger)
+	}
+
+	// 6. Register the document workflow prompts
+	usecase.RegisterPrompts(server)
 
-	// 6. Run the server with StdioTransport
-	log.Println("Starting MCP server over stdio")
+	// 7. Run the server over the configured transport
+	if cfg.Transport == config.TransportHTTP {
+		mux := http.NewServeMux()
+		mux.Handle("/mcp", mcp.NewStreamableHTTPHandler(func(*http.Request) *mcp.Server { return server }, nil))
+		mux.Handle("/metrics", serverMetrics.Handler())
+		addr := fmt.Sprintf(":%d", cfg.Port)
+		logger.Info("Starting MCP server over HTTP", "addr", addr, "mcpPath", "/mcp", "metricsPath", "/metrics")
+		if err := http.ListenAndServe(addr, mux); err != nil {
+			fatal(logger, "Server failed", err)
+		}
+		return
+	}
+	logger.Info("Starting MCP server over stdio")
 	if err := server.Run(ctx, &mcp.StdioTransport{}); err != nil {
-		log.Fatalf("Server failed: %v", err)
+		fatal(logger, "Server failed", err)
+	}
+}
+
+// parseCommandFlags parses the flags following a command.
+func parseCommandFlags(args []string) {
+	if err := flag.CommandLine.Parse(args); err != nil {
+		os.Exit(2)
+	}
+	if flag.NArg() > 0 {
+		fmt.Fprintf(os.Stderr, "unexpected arguments %q\n", strings.Join(flag.Args(), " "))
+		os.Exit(2)
+	}
+}
+
+// setUp loads and checks the configuration, and sets up logging to stderr,
+// exiting on failure.
+func setUp(loadConfig func() (*config.Config, error)) (*config.Config, *redact.Redactor, *slog.Logger) {
+	cfg, err := loadConfig()
+	if err != nil {
+		fatal(logging.New(os.Stderr, slog.LevelInfo), "Failed to load config", err)
+	}
+	redactor, err := redact.New(cfg.Secrets(), cfg.RedactPatterns)
+	if err != nil {
+		fatal(logging.New(os.Stderr, slog.LevelInfo), "Invalid config", err)
+	}
+	logger := slog.New(redactor.Handler(logging.New(os.Stderr, cfg.LogLevel).Handler()))
+	slog.SetDefault(logger)
+	if err := checkConfig(cfg); err != nil {
+		fatal(logger, "Invalid config", err)
+	}
+	return cfg, redactor, logger
+}
+
+// startBackend sets up tracing and, if configured, starts the fake service,
+// exiting on failure. The returned function stops them.
+func startBackend(ctx context.Context, cfg *config.Config, logger *slog.Logger) func() {
+	var stops []func()
+	if cfg.OTLPEndpoint != "" {
+		shutdown, err := tracing.Setup(ctx, cfg.OTLPEndpoint, serverName, serverVersion)
+		if err != nil {
+			fatal(logger, "Failed to set up tracing", err)
+		}
+		stops = append(stops, func() { _ = shutdown(context.Background()) })
+		logger.Info("Exporting traces", "endpoint", cfg.OTLPEndpoint)
+	}
+	if cfg.FakeBackend {
+		fakeServer, err := startFakeBackend(cfg, logger)
+		if err != nil {
+			fatal(logger, "Failed to start fake backend", err)
+		}
+		stops = append(stops, fakeServer.Close)
+	}
+	return func() {
+		for _, stop := range slices.Backward(stops) {
+			stop()
+		}
+	}
+}
+
+// newAnalysisRepository returns the repository analyses go through, from the
+// local text layer of PDFs to the configured endpoints. Errors are redacted
+// before spans record them.
+func newAnalysisRepository(cfg *config.Config, redactor *redact.Redactor, serverMetrics *metrics.Metrics) (analysis.Repository, error) {
+	httpClient := &http.Client{Timeout: time.Duration(cfg.HTTPClientTimeout) * time.Second}
+	azureClient := limit.RateLimitClient(serverMetrics.WrapClient(tracing.WrapClient(httpClient, redactor)), limit.Rates{
+		Initiate: float64(cfg.InitiateRateLimit),
+		Poll:     float64(cfg.PollRateLimit),
+	})
+	azureRepo, err := newAzureRepository(cfg, azureClient)
+	if err != nil {
+		return nil, err
+	}
+	// Documents over the limits of the service are analyzed in parts, each
+	// counted in the metrics. Identical analyses in progress are shared, and
+	// counted as shared analyses; PDFs read locally as local analyses.
+	splitRepo := pdf.Split(serverMetrics.WrapRepository(azureRepo), pdf.SplitLimits{
+		MaxPages:    cfg.SplitMaxPages,
+		MaxBytes:    cfg.SplitMaxBytes,
+		Concurrency: cfg.SplitConcurrency,
+	})
+	analysisRepo := analysisinfra.Coalesce(splitRepo, serverMetrics.SharedAnalysis)
+	if cfg.LocalTextExtraction {
+		analysisRepo = pdf.TextLayer(analysisRepo, cfg.LocalTextMinCoverage, serverMetrics.LocalAnalysis)
+	}
+	return analysisRepo, nil
+}
+
+// newURLPolicy returns the policy of the configured document URLs.
+func newURLPolicy(cfg *config.Config) *urlpolicy.Policy {
+	return &urlpolicy.Policy{
+		AllowedSchemes:       cfg.URLAllowedSchemes,
+		AllowedHosts:         cfg.URLAllowedHosts,
+		DeniedHosts:          cfg.URLDeniedHosts,
+		AllowPrivateNetworks: cfg.URLAllowPrivateNetworks,
+	}
+}
+
+// newFetchPolicy returns the policy of the document URLs downloaded by this
+// server, which also accepts the configured private hosts.
+func newFetchPolicy(cfg *config.Config) *urlpolicy.Policy {
+	policy := newURLPolicy(cfg)
+	policy.PrivateHosts = cfg.FetchPrivateHosts
+	return policy
+}
+
+// newUsageMeter returns the meter of the configured ledger and budgets.
+func newUsageMeter(cfg *config.Config) (*usecase.UsageMeter, error) {
+	ledger, err := usageinfra.NewFileLedger(usageLedgerPath(cfg))
+	if err != nil {
+		return nil, err
+	}
+	return usecase.NewUsageMeter(ledger, usage.Budget{
+		Daily:              cfg.DailyPageBudget,
+		Monthly:            cfg.MonthlyPageBudget,
+		MaxPagesPerRequest: cfg.MaxPagesPerRequest,
+	}), nil
+}
+
+// registerHistoryTools registers the enabled tools recalling stored analyses.
+func registerHistoryTools(server *mcp.Server, store history.Store, disabled []string, logger *slog.Logger) {
+	if !slices.Contains(disabled, toolListAnalyses) {
+		mcp.AddTool(server, &mcp.Tool{
+			Name:        toolListAnalyses,
+			Description: "Lists past document analyses, newest first, with their source URL, model, content hash, client and page count. Filter by 'modelId', 'client' or 'since' (YYYY-MM-DD). Use get_analysis to retrieve a result without analyzing the document again.",
+		}, usecase.NewListAnalysesHandler(store))
+	}
+	if !slices.Contains(disabled, toolGetAnalysis) {
+		outputSchema, err := usecase.GetAnalysisOutputSchema()
+		if err != nil {
+			fatal(logger, "Failed to build output schema", err)
+		}
+		mcp.AddTool(server, &mcp.Tool{
+			Name:         toolGetAnalysis,
+			Description:  "Returns a past document analysis by 'id', as listed by list_analyses or search_analyses, with its full result.",
+			OutputSchema: outputSchema,
+		}, usecase.NewGetAnalysisHandler(store))
+	}
+	if !slices.Contains(disabled, toolSearchAnalyses) {
+		mcp.AddTool(server, &mcp.Tool{
+			Name:        toolSearchAnalyses,
+			Description: "Searches past document analyses for words in their extracted content and field values, such as a vendor name or invoice number. Every word of 'query' must occur. Returns the best matches with excerpts; use get_analysis for a full result.",
+		}, usecase.NewSearchAnalysesHandler(store))
+	}
+}
+
+// printUsage prints the command line help.
+func printUsage() {
+	out := flag.CommandLine.Output()
+	fmt.Fprintf(out, `Usage:
+  %[1]s [flags] [serve]                      run the MCP server
+  %[1]s [flags] config validate              check the configuration and exit
+  %[1]s [flags] watch                        analyze the files arriving in watch_dirs
+  %[1]s [flags] models list                  list the models and whether they're enabled
+  %[1]s [flags] analyze [flags] <document>   analyze a document, as JSON or markdown
+  %[1]s [flags] classify [flags] <document>  list the documents found by type, as JSON or CSV
+  %[1]s [flags] tables [flags] <document>    extract the tables, as CSV, markdown or JSON
+
+A document is a file path, a URL, or - for the standard input. Run a
+command with -h for its flags.
+
+Flags:
+`, serverName)
+	flag.PrintDefaults()
+}
+
+// validateConfig loads and checks the configuration, reporting the result
+// for the config validate command, and returns the exit code.
+func validateConfig(load func() (*config.Config, error)) int {
+	cfg, err := load()
+	if err == nil {
+		err = checkConfig(cfg)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Invalid configuration:")
+		for _, line := range strings.Split(err.Error(), "\n") {
+			fmt.Fprintln(os.Stderr, "  "+line)
+		}
+		return 1
+	}
+	fmt.Println("Configuration is valid.")
+	return 0
+}
+
+// checkConfig validates the configuration, including the settings that
+// depend on what the server offers: models, tools and patterns.
+func checkConfig(cfg *config.Config) error {
+	errs := []error{cfg.Validate()}
+	supported := usecase.SupportedModels()
+	for _, m := range cfg.AllowedModels {
+		if !slices.Contains(supported, m) {
+			errs = append(errs, fmt.Errorf("unsupported model %q in allowed_models: must be one of %s", m, strings.Join(supported, ", ")))
+		}
+	}
+	for _, r := range cfg.WatchModels {
+		if !slices.Contains(supported, r.Model) {
+			errs = append(errs, fmt.Errorf("unsupported model %q in watch_models: must be one of %s", r.Model, strings.Join(supported, ", ")))
+		}
+	}
+	for _, t := range cfg.DisabledTools {
+		if !slices.Contains(toolNames, t) {
+			errs = append(errs, fmt.Errorf("unknown tool %q in disabled_tools: must be one of %s", t, strings.Join(toolNames, ", ")))
+		}
+	}
+	if _, err := redact.New(nil, cfg.RedactPatterns); err != nil {
+		errs = append(errs, fmt.Errorf("redact_patterns: %w", err))
+	}
+	if _, err := newPIIDetector(cfg.PIIPatterns); err != nil {
+		errs = append(errs, fmt.Errorf("pii_patterns: %w", err))
+	}
+	return errors.Join(errs...)
+}
+
+// fatal logs err and exits.
+func fatal(logger *slog.Logger, msg string, err error) {
+	logger.Error(msg, "error", err)
+	os.Exit(1)
+}
+
+// historyDir returns the configured history directory, defaulting to the user
+// configuration directory. It returns "" to keep results in memory only if
+// there is no such directory.
+func historyDir(cfg *config.Config) string {
+	if cfg.HistoryDir != "" {
+		return cfg.HistoryDir
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, serverName, "history")
+}
+
+// historyRetention returns the configured bounds of the history.
+func historyRetention(cfg *config.Config) historyinfra.Retention {
+	return historyinfra.Retention{
+		MaxRecords: cfg.HistoryMaxRecords,
+		MaxAge:     time.Duration(cfg.HistoryMaxAgeDays) * 24 * time.Hour,
+	}
+}
+
+// usageLedgerPath returns the configured ledger path, defaulting to the user
+// configuration directory. It returns "" to keep usage in memory only if
+// there is no such directory.
+func usageLedgerPath(cfg *config.Config) string {
+	if cfg.UsageLedgerPath != "" {
+		return cfg.UsageLedgerPath
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, serverName, "usage.jsonl")
+}
+
+// newPIIDetector returns the built-in personal data detectors, followed by
+// the configured patterns in the order of their categories.
+func newPIIDetector(patterns config.NamedPatterns) (pii.Detector, error) {
+	detectors := []pii.Detector{pii.Default()}
+	for _, category := range slices.Sorted(maps.Keys(patterns)) {
+		d, err := pii.NewPattern(category, patterns[category])
+		if err != nil {
+			return nil, err
+		}
+		detectors = append(detectors, d)
+	}
+	return pii.Combine(detectors...), nil
+}
+
+// newAzureRepository returns the repository of the configured endpoint, or a
+// router over the configured endpoints. A single endpoint is also routed when
+// its concurrency is limited.
+func newAzureRepository(cfg *config.Config, client analysisinfra.HTTPClient) (analysis.Repository, error) {
+	if len(cfg.AzureEndpoints) == 0 && cfg.MaxConcurrentPerEndpoint == 0 {
+		return analysisinfra.NewRepositoryWithClient(cfg.AzureEndpoint, cfg.AzureAPIKey, client), nil
+	}
+	endpoints := make([]analysisinfra.Endpoint, len(cfg.AzureEndpoints))
+	for i, e := range cfg.AzureEndpoints {
+		endpoints[i] = analysisinfra.Endpoint{URL: e.Endpoint, APIKey: e.APIKey, Weight: e.Weight, Region: e.Region, Models: e.Models, MaxConcurrent: e.MaxConcurrent}
+	}
+	if len(endpoints) == 0 {
+		endpoints = []analysisinfra.Endpoint{{URL: cfg.AzureEndpoint, APIKey: cfg.AzureAPIKey}}
+	}
+	return analysisinfra.NewRouter(endpoints, client, analysisinfra.RouterOptions{
+		Strategy:                 cfg.RoutingStrategy,
+		FailureThreshold:         cfg.CircuitFailureThreshold,
+		Cooldown:                 time.Duration(cfg.CircuitCooldown) * time.Second,
+		MaxConcurrentPerEndpoint: cfg.MaxConcurrentPerEndpoint,
+	})
+}
+
+// startFakeBackend starts the fake service and points the configuration at it.
+func startFakeBackend(cfg *config.Config, logger *slog.Logger) (*fake.Server, error) {
+	if cfg.AzureAPIKey == "" {
+		cfg.AzureAPIKey = "fake-key"
+	}
+	server := fake.NewServer(cfg.AzureAPIKey)
+	if cfg.FakeFixturesDir != "" {
+		if err := server.LoadFixtures(cfg.FakeFixturesDir); err != nil {
+			server.Close()
+			return nil, err
+		}
 	}
-}
\ No newline at end of file
+	cfg.AzureEndpoint = server.URL
+	cfg.AzureEndpoints = nil
+	logger.Info("Using fake Document Intelligence backend", "url", server.URL)
+	return server, nil
+}
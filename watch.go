@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/linzhengen/azure-document-intelligence-mcp/config"
+	"github.com/linzhengen/azure-document-intelligence-mcp/internal/infrastructure/fetch"
+	historyinfra "github.com/linzhengen/azure-document-intelligence-mcp/internal/infrastructure/history"
+	"github.com/linzhengen/azure-document-intelligence-mcp/internal/infrastructure/metrics"
+	"github.com/linzhengen/azure-document-intelligence-mcp/internal/infrastructure/watch"
+	"github.com/linzhengen/azure-document-intelligence-mcp/internal/redact"
+	"github.com/linzhengen/azure-document-intelligence-mcp/internal/usecase"
+)
+
+// runWatch analyzes the files of the watched directories as they arrive,
+// until interrupted, and returns the exit code.
+func runWatch(ctx context.Context, cfg *config.Config, redactor *redact.Redactor, logger *slog.Logger) int {
+	if err := checkWatchConfig(cfg); err != nil {
+		logger.Error("Invalid config", "error", err)
+		return 1
+	}
+	ctx, cancel := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	stop := startBackend(ctx, cfg, logger)
+	defer stop()
+	analysisRepo, err := newAnalysisRepository(cfg, redactor, metrics.New())
+	if err != nil {
+		logger.Error("Invalid config", "error", err)
+		return 1
+	}
+	usageMeter, err := newUsageMeter(cfg)
+	if err != nil {
+		logger.Error("Failed to load usage ledger", "error", err)
+		return 1
+	}
+	piiDetector, err := newPIIDetector(cfg.PIIPatterns)
+	if err != nil {
+		logger.Error("Invalid config", "error", err)
+		return 1
+	}
+	files, err := fetch.NewFiles(cfg.WatchDirs, cfg.FetchMaxBytes)
+	if err != nil {
+		logger.Error("Invalid config", "error", err)
+		return 1
+	}
+	output, err := watch.NewOutput(cfg.WatchOutputDir)
+	if err != nil {
+		logger.Error("Invalid config", "error", err)
+		return 1
+	}
+
+	options := []usecase.HandlerOption{
+		usecase.WithLogger(logger),
+		usecase.WithRedactor(redactor),
+		usecase.WithPIIRedaction(piiDetector, cfg.PIIRedactAlways),
+		usecase.WithUsageMeter(usageMeter),
+		usecase.WithDocumentReader(files),
+	}
+	if len(cfg.AllowedModels) > 0 {
+		options = append(options, usecase.WithAllowedModels(cfg.AllowedModels))
+	}
+	if cfg.HistoryEnabled {
+		store, err := historyinfra.NewFileStore(historyDir(cfg), historyRetention(cfg))
+		if err != nil {
+			logger.Error("Failed to load analysis history", "error", err)
+			return 1
+		}
+		options = append(options, usecase.WithResultStore(store))
+	}
+	rules := make([]usecase.ModelRule, len(cfg.WatchModels))
+	for i, r := range cfg.WatchModels {
+		rules[i] = usecase.ModelRule{Pattern: r.Pattern, ModelID: r.Model}
+	}
+	analyzer, err := usecase.NewFileAnalyzer(analysisRepo, rules, output, options...)
+	if err != nil {
+		logger.Error("Invalid config", "error", err)
+		return 1
+	}
+
+	// A file that fails is analyzed again when it changes, or on restart.
+	err = watch.Run(ctx, cfg.WatchDirs, watch.Options{PollInterval: time.Duration(cfg.WatchPollInterval) * time.Second}, func(path string) {
+		if err := analyzer.Analyze(ctx, path); err != nil && ctx.Err() == nil {
+			logger.Error("Failed to analyze file", "file", path, "error", err)
+		}
+	})
+	if err != nil {
+		logger.Error("Failed to watch directories", "error", err)
+		return 1
+	}
+	logger.Info("Stopped watching directories")
+	return 0
+}
+
+// checkWatchConfig checks the settings the watch command requires.
+func checkWatchConfig(cfg *config.Config) error {
+	var errs []error
+	if len(cfg.WatchDirs) == 0 {
+		errs = append(errs, errors.New("watch_dirs must list the directories to watch"))
+	}
+	if len(cfg.WatchModels) == 0 {
+		errs = append(errs, errors.New("watch_models must map file patterns to models"))
+	}
+	if cfg.WatchOutputDir == "" {
+		errs = append(errs, errors.New("watch_output_dir must be set"))
+	}
+	// Results written to a watched directory could be analyzed in turn.
+	output, _ := filepath.Abs(cfg.WatchOutputDir)
+	for _, dir := range cfg.WatchDirs {
+		if abs, _ := filepath.Abs(dir); cfg.WatchOutputDir != "" && abs == output {
+			errs = append(errs, fmt.Errorf("watch_output_dir must not be one of watch_dirs: %s", dir))
+		}
+	}
+	return errors.Join(errs...)
+}